@@ -0,0 +1,467 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/Cheemx/chirpy/internal/auth"
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/entitlements"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/notifications"
+	"github.com/Cheemx/chirpy/internal/outbox"
+	"github.com/Cheemx/chirpy/internal/pagination"
+	"github.com/Cheemx/chirpy/internal/validation"
+	"github.com/google/uuid"
+)
+
+// chirpResponse is the JSON shape of a chirp: the stored row plus the counts
+// and other data derived from related tables.
+type chirpResponse struct {
+	database.Chirp
+	ReplyCount   int64 `json:"reply_count"`
+	LikeCount    int64 `json:"like_count"`
+	LikedByMe    bool  `json:"liked_by_me"`
+	RechirpCount int64 `json:"rechirp_count"`
+
+	// Edited is whether the body has changed since the chirp was posted.
+	// GET /api/chirps/{chirpID}/history has what it said before.
+	Edited bool `json:"edited"`
+
+	// Author is the public profile of user_id, so clients don't have to look
+	// every user up.
+	Author authorResponse `json:"author"`
+
+	// Mentions are the @mentions that resolved to a user when the chirp was
+	// posted, in order of appearance.
+	Mentions []mentionEntity `json:"mentions"`
+
+	// Poll is the poll the chirp was posted with, if any.
+	Poll *pollResponse `json:"poll,omitempty"`
+
+	// Media are the images attached to the chirp, in order.
+	Media []mediaResponse `json:"media"`
+
+	// The reposted and quoted chirps, inlined one level deep. QuotedChirp is
+	// nil when quote_of_id points at a chirp that has since been deleted.
+	RechirpedChirp *chirpResponse `json:"rechirped_chirp,omitempty"`
+	QuotedChirp    *chirpResponse `json:"quoted_chirp"`
+}
+
+// presentChirps loads everything chirpResponse needs for a batch of chirps
+// with one query per related table, keeping the order of chirps. viewerID is
+// the authenticated user, or uuid.Nil for anonymous requests.
+func (cfg *apiConfig) presentChirps(ctx context.Context, viewerID uuid.UUID, chirps []database.Chirp) ([]chirpResponse, error) {
+	res, err := cfg.countChirps(ctx, viewerID, chirps)
+	if err != nil {
+		return nil, err
+	}
+
+	// inline the chirps that rechirps and quotes point at
+	var refIDs []uuid.UUID
+	for _, chirp := range chirps {
+		if chirp.RechirpOfID.Valid {
+			refIDs = append(refIDs, chirp.RechirpOfID.UUID)
+		}
+		if chirp.QuoteOfID.Valid {
+			refIDs = append(refIDs, chirp.QuoteOfID.UUID)
+		}
+	}
+	if len(refIDs) == 0 {
+		return res, nil
+	}
+	refChirps, err := cfg.db.GetChirpsByIDs(ctx, refIDs)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := cfg.countChirps(ctx, viewerID, refChirps)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[uuid.UUID]*chirpResponse{}
+	for i := range refs {
+		byID[refs[i].ID] = &refs[i]
+	}
+	for i := range res {
+		res[i].RechirpedChirp = byID[res[i].RechirpOfID.UUID]
+		res[i].QuotedChirp = byID[res[i].QuoteOfID.UUID]
+	}
+	return res, nil
+}
+
+// countChirps is presentChirps without inlining referenced chirps.
+func (cfg *apiConfig) countChirps(ctx context.Context, viewerID uuid.UUID, chirps []database.Chirp) ([]chirpResponse, error) {
+	res := make([]chirpResponse, len(chirps))
+	if len(chirps) == 0 {
+		return res, nil
+	}
+
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+	}
+
+	replyCounts, err := cfg.db.CountChirpReplies(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	replies := map[uuid.UUID]int64{}
+	for _, row := range replyCounts {
+		replies[row.ParentID.UUID] = row.ReplyCount
+	}
+
+	likeCounts, err := cfg.db.CountChirpLikes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	likes := map[uuid.UUID]int64{}
+	for _, row := range likeCounts {
+		likes[row.ChirpID] = row.LikeCount
+	}
+
+	liked := map[uuid.UUID]bool{}
+	if viewerID != uuid.Nil {
+		likedIDs, err := cfg.db.ListLikedChirpIDs(ctx, database.ListLikedChirpIDsParams{
+			UserID:   viewerID,
+			ChirpIds: ids,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range likedIDs {
+			liked[id] = true
+		}
+	}
+
+	rechirpCounts, err := cfg.db.CountRechirps(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	rechirps := map[uuid.UUID]int64{}
+	for _, row := range rechirpCounts {
+		rechirps[row.RechirpOfID.UUID] = row.RechirpCount
+	}
+
+	mentionRows, err := cfg.db.ListChirpMentions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	mentions := map[uuid.UUID][]mentionEntity{}
+	for _, row := range mentionRows {
+		mentions[row.ChirpID] = append(mentions[row.ChirpID], mentionEntity{
+			Start:    row.StartIndex,
+			End:      row.EndIndex,
+			UserID:   row.UserID,
+			Username: row.Username.String,
+		})
+	}
+
+	polls, err := cfg.chirpPolls(ctx, viewerID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	attached, err := cfg.chirpMedia(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	authors, err := cfg.chirpAuthors(ctx, chirps)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, chirp := range chirps {
+		res[i] = chirpResponse{
+			Chirp:        chirp,
+			Author:       authors[chirp.UserID],
+			ReplyCount:   replies[chirp.ID],
+			LikeCount:    likes[chirp.ID],
+			LikedByMe:    liked[chirp.ID],
+			RechirpCount: rechirps[chirp.ID],
+			Edited:       chirp.UpdatedAt.After(chirp.CreatedAt),
+			Mentions:     mentions[chirp.ID],
+			Poll:         polls[chirp.ID],
+			Media:        attached[chirp.ID],
+		}
+		if res[i].Mentions == nil {
+			res[i].Mentions = []mentionEntity{}
+		}
+		if res[i].Media == nil {
+			res[i].Media = []mediaResponse{}
+		}
+	}
+	return res, nil
+}
+
+// presentChirp is presentChirps for a single chirp.
+func (cfg *apiConfig) presentChirp(ctx context.Context, viewerID uuid.UUID, chirp database.Chirp) (chirpResponse, error) {
+	res, err := cfg.presentChirps(ctx, viewerID, []database.Chirp{chirp})
+	if err != nil {
+		return chirpResponse{}, err
+	}
+	return res[0], nil
+}
+
+// chirpRequest is what a new chirp is made of, as sent to POST /api/chirps
+// or kept in a draft.
+type chirpRequest struct {
+	Body      string `json:"body"`
+	InReplyTo string `json:"in_reply_to,omitempty"`
+	RechirpOf string `json:"rechirp_of,omitempty"`
+	QuoteOf   string `json:"quote_of,omitempty"`
+
+	Poll     *pollRequest `json:"poll,omitempty"`
+	MediaIDs []string     `json:"media_ids,omitempty"`
+}
+
+// preparedChirp is a chirp that passed every check, ready to be inserted.
+type preparedChirp struct {
+	params database.CreateChirpParams
+	// flags are the reasons moderation flagged the body for review.
+	flags []string
+	// parentUserID is the author of the chirp replied to, if any.
+	parentUserID uuid.UUID
+	// poll is the poll to post with the chirp, if any.
+	poll *pollRequest
+	// media are the uploads to attach, in order.
+	media []uuid.UUID
+}
+
+// prepareChirp runs every check a new chirp by user goes through:
+// validation and moderation of the body, and the chirps it replies to,
+// quotes or rechirps. Problems with the request come back as *httpapi.Error.
+// It reads through db, which is the transaction the chirp is inserted in
+// when there is one already.
+func (cfg *apiConfig) prepareChirp(ctx context.Context, db database.Store, user database.User, request chirpRequest) (preparedChirp, error) {
+	// a rechirp reposts another chirp as is, it can't carry anything else
+	if request.RechirpOf != "" && (request.Body != "" || request.InReplyTo != "" || request.QuoteOf != "" || request.Poll != nil || len(request.MediaIDs) > 0) {
+		return preparedChirp{}, httpapi.BadRequest(httpapi.CodeInvalidParameter, "A rechirp can't have a body, in_reply_to, quote_of, poll or media_ids")
+	}
+
+	// Sending field errors for an empty, too long or malformed Chirp; a
+	// rechirp has no body of its own to check
+	if request.RechirpOf == "" {
+		err := cfg.chirpRules(user).Chirp(request.Body)
+		var fieldErrs validation.Errors
+		if errors.As(err, &fieldErrs) {
+			return preparedChirp{}, httpapi.ValidationFailed(fieldErrs)
+		}
+	}
+
+	// masking, rejecting or flagging whatever the moderation rules object to
+	verdict := cfg.moderator.Moderate(request.Body)
+	if verdict.Rejected {
+		return preparedChirp{}, httpapi.BadRequest(httpapi.CodeContentRejected, "Chirp rejected: "+verdict.Reason)
+	}
+
+	prepared := preparedChirp{
+		params: database.CreateChirpParams{
+			Body:   verdict.Body,
+			UserID: user.ID,
+		},
+		flags: verdict.Flags,
+	}
+
+	// poll options are moderated like the body
+	if request.Poll != nil {
+		err := checkPoll(request.Poll)
+		if err != nil {
+			return preparedChirp{}, err
+		}
+		for i, option := range request.Poll.Options {
+			verdict := cfg.moderator.Moderate(option)
+			if verdict.Rejected {
+				return preparedChirp{}, httpapi.BadRequest(httpapi.CodeContentRejected, "Poll option rejected: "+verdict.Reason)
+			}
+			request.Poll.Options[i] = verdict.Body
+			for _, flag := range verdict.Flags {
+				if !slices.Contains(prepared.flags, flag) {
+					prepared.flags = append(prepared.flags, flag)
+				}
+			}
+		}
+		prepared.poll = request.Poll
+	}
+
+	if len(request.MediaIDs) > 0 {
+		mediaIDs, err := cfg.checkChirpMedia(ctx, db, user.ID, request.MediaIDs)
+		if err != nil {
+			return preparedChirp{}, err
+		}
+		prepared.media = mediaIDs
+	}
+
+	// attach the reply to its parent and the parent's thread
+	if request.InReplyTo != "" {
+		parent, err := cfg.referencedChirp(ctx, db, "in_reply_to", request.InReplyTo)
+		if err != nil {
+			return preparedChirp{}, err
+		}
+		prepared.parentUserID = parent.UserID
+		prepared.params.ParentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		prepared.params.RootID = parent.RootID
+		if !prepared.params.RootID.Valid {
+			prepared.params.RootID = prepared.params.ParentID
+		}
+	}
+
+	// quoting a rechirp quotes the chirp it reposts
+	if request.QuoteOf != "" {
+		quoted, err := cfg.referencedChirp(ctx, db, "quote_of", request.QuoteOf)
+		if err != nil {
+			return preparedChirp{}, err
+		}
+		prepared.params.QuoteOfID = originalChirpID(quoted)
+	}
+
+	// rechirping a rechirp reposts the original, and only once per user
+	if request.RechirpOf != "" {
+		original, err := cfg.referencedChirp(ctx, db, "rechirp_of", request.RechirpOf)
+		if err != nil {
+			return preparedChirp{}, err
+		}
+		prepared.params.RechirpOfID = originalChirpID(original)
+
+		_, err = db.GetUserRechirp(ctx, database.GetUserRechirpParams{
+			UserID:      user.ID,
+			RechirpOfID: prepared.params.RechirpOfID,
+		})
+		if err == nil {
+			return preparedChirp{}, httpapi.Conflict("Chirp already rechirped")
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return preparedChirp{}, err
+		}
+	}
+	return prepared, nil
+}
+
+// insertChirp creates a prepared chirp with its poll and media, along with
+// the event announcing it. tx should be a transaction.
+func insertChirp(ctx context.Context, tx database.Store, prepared preparedChirp) (database.Chirp, error) {
+	chirp, err := tx.CreateChirp(ctx, prepared.params)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if prepared.poll != nil {
+		_, err = tx.CreatePoll(ctx, database.CreatePollParams{
+			ChirpID:  chirp.ID,
+			Options:  prepared.poll.Options,
+			ClosesAt: prepared.poll.ClosesAt,
+		})
+		if err != nil {
+			return database.Chirp{}, err
+		}
+	}
+	err = attachMedia(ctx, tx, chirp, prepared.media)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	return chirp, outbox.Record(ctx, tx, outbox.ChirpCreated, chirp)
+}
+
+// announceChirp does everything that follows posting a chirp: tagging it,
+// recording its mentions, notifying and flagging, and publishing it to the
+// live streams. It returns the chirp as its author sees it.
+func (cfg *apiConfig) announceChirp(ctx context.Context, chirp database.Chirp, prepared preparedChirp) (chirpResponse, error) {
+	// the chirp is already posted, a lost flag, tag or mention shouldn't fail
+	// the request
+	err := cfg.tagChirp(ctx, chirp)
+	if err != nil {
+		log.Printf("Error tagging Chirp %s: %v", chirp.ID, err)
+	}
+	err = cfg.mentionChirp(ctx, chirp, nil)
+	if err != nil {
+		log.Printf("Error recording mentions in Chirp %s: %v", chirp.ID, err)
+	}
+	if chirp.ParentID.Valid {
+		cfg.notify(ctx, notifications.Event{
+			Type:      notifications.Reply,
+			Recipient: prepared.parentUserID,
+			Actor:     chirp.UserID,
+			ChirpID:   chirp.ParentID.UUID,
+		})
+	}
+	cfg.flagChirp(ctx, chirp.ID, prepared.flags)
+
+	res, err := cfg.presentChirp(ctx, chirp.UserID, chirp)
+	if err != nil {
+		return chirpResponse{}, err
+	}
+	cfg.publishChirp(ctx, eventChirpCreated, chirp)
+	return res, nil
+}
+
+// chirpRules are the limits user's chirp bodies are checked against. Some
+// plans get longer chirps.
+func (cfg *apiConfig) chirpRules(user database.User) validation.ChirpRules {
+	if cfg.entitlements.Has(user, entitlements.LongChirps) {
+		return validation.RedChirpRules
+	}
+	return validation.DefaultChirpRules
+}
+
+// flagChirp records the reasons moderation flagged a chirp for review. The
+// chirp is already saved, so a lost flag is logged rather than failing the
+// request.
+func (cfg *apiConfig) flagChirp(ctx context.Context, chirpID uuid.UUID, reasons []string) {
+	for _, reason := range reasons {
+		_, err := cfg.db.CreateModerationFlag(ctx, database.CreateModerationFlagParams{
+			ChirpID: chirpID,
+			Reason:  reason,
+		})
+		if err != nil {
+			log.Printf("Error flagging Chirp %s: %v", chirpID, err)
+		}
+	}
+}
+
+// chirpCursor is the keyset position of a chirp in created_at, id order.
+func chirpCursor(chirp database.Chirp) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: chirp.CreatedAt, ID: chirp.ID}
+}
+
+// referencedChirp looks up a chirp ID given in the request body field named
+// field. A malformed or unknown ID is the client's mistake, so both are 400s.
+func (cfg *apiConfig) referencedChirp(ctx context.Context, db database.Store, field, id string) (database.Chirp, error) {
+	chirpID, err := uuid.Parse(id)
+	if err != nil {
+		return database.Chirp{}, httpapi.BadRequest(httpapi.CodeInvalidParameter, field+" is not a valid ID")
+	}
+	chirp, err := db.GetChirpByID(ctx, chirpID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.Chirp{}, httpapi.BadRequest(httpapi.CodeInvalidParameter, field+" refers to a Chirp that doesn't exist")
+		}
+		return database.Chirp{}, err
+	}
+	return chirp, nil
+}
+
+// originalChirpID is the chirp a rechirp reposts, or the chirp itself when it
+// isn't a rechirp.
+func originalChirpID(chirp database.Chirp) uuid.NullUUID {
+	if chirp.RechirpOfID.Valid {
+		return chirp.RechirpOfID
+	}
+	return uuid.NullUUID{UUID: chirp.ID, Valid: true}
+}
+
+// viewerID identifies the caller of a public endpoint. Anonymous callers, and
+// callers whose token doesn't validate, get uuid.Nil instead of an error.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.UUID {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil
+	}
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		return uuid.Nil
+	}
+	return userID
+}
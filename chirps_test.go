@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRechirpsAndQuotes(t *testing.T) {
+	srv := newTestServer(t)
+	cheems := createTestUser(t, srv, "cheems@example.com")
+	doge := createTestUser(t, srv, "doge@example.com")
+
+	original := postChirp(t, srv, cheems.Token, map[string]string{"body": "original"}, 201)
+	rechirp := postChirp(t, srv, doge.Token, map[string]string{"rechirp_of": original.ID.String()}, 201)
+	if rechirp.RechirpedChirp == nil || rechirp.RechirpedChirp.Body != "original" {
+		t.Fatalf("rechirp not hydrated: %+v", rechirp.RechirpedChirp)
+	}
+	postChirp(t, srv, doge.Token, map[string]string{"rechirp_of": original.ID.String()}, 409)
+	postChirp(t, srv, doge.Token, map[string]string{"rechirp_of": rechirp.ID.String()}, 409)
+	postChirp(t, srv, cheems.Token, map[string]string{"rechirp_of": original.ID.String(), "body": "nope"}, 400)
+
+	quote := postChirp(t, srv, doge.Token, map[string]string{"body": "look at this", "quote_of": rechirp.ID.String()}, 201)
+	if quote.QuotedChirp == nil || quote.QuotedChirp.ID != original.ID {
+		t.Fatalf("quote should embed the original: %+v", quote.QuotedChirp)
+	}
+
+	res := doJSON(t, srv, "GET", "/api/chirps/"+original.ID.String(), "", nil)
+	var got chirpResponse
+	decodeBody(t, res, &got)
+	if got.RechirpCount != 1 {
+		t.Fatalf("rechirp_count = %d want 1", got.RechirpCount)
+	}
+
+	if res := doJSON(t, srv, "DELETE", "/api/chirps/"+original.ID.String(), cheems.Token, nil); res.StatusCode != 204 {
+		t.Fatalf("delete original: got %d want 204", res.StatusCode)
+	}
+	if res := doJSON(t, srv, "GET", "/api/chirps/"+rechirp.ID.String(), "", nil); res.StatusCode != 404 {
+		t.Fatalf("rechirp should go with the original: got %d", res.StatusCode)
+	}
+	res = doJSON(t, srv, "GET", "/api/chirps/"+quote.ID.String(), "", nil)
+	got = chirpResponse{}
+	decodeBody(t, res, &got)
+	if got.QuotedChirp != nil || got.QuoteOfID.UUID != original.ID {
+		t.Fatalf("quote of deleted chirp: quoted %+v quote_of_id %v", got.QuotedChirp, got.QuoteOfID)
+	}
+}
+
+func TestChirpValidation(t *testing.T) {
+	srv := newTestServer(t)
+	user := createTestUser(t, srv, "cheems@example.com")
+
+	// 140 characters but 420 bytes
+	postChirp(t, srv, user.Token, map[string]string{"body": strings.Repeat("न", 140)}, 201)
+
+	res := doJSON(t, srv, "POST", "/api/chirps", user.Token, map[string]string{"body": "   "})
+	if res.StatusCode != 400 {
+		t.Fatalf("blank chirp: got %d want 400", res.StatusCode)
+	}
+	var errRes struct {
+		Error  string `json:"error"`
+		Errors []struct {
+			Field string `json:"field"`
+			Code  string `json:"code"`
+		} `json:"errors"`
+	}
+	decodeBody(t, res, &errRes)
+	if len(errRes.Errors) != 1 || errRes.Errors[0].Field != "body" || errRes.Errors[0].Code != "required" {
+		t.Fatalf("field errors = %+v", errRes.Errors)
+	}
+
+	long := map[string]string{"body": strings.Repeat("a", 200)}
+	postChirp(t, srv, user.Token, long, 400)
+
+	// Chirpy Red users get the longer limit
+	res = doPolka(t, srv, "polka", map[string]any{
+		"id":    "evt_1",
+		"event": "user.upgraded",
+		"data":  map[string]string{"user_id": user.ID.String()},
+	})
+	if res.StatusCode != 204 {
+		t.Fatalf("upgrade user: got %d want 204", res.StatusCode)
+	}
+	postChirp(t, srv, user.Token, long, 201)
+}
@@ -2,37 +2,46 @@ package main
 
 import (
 	"database/sql"
-	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
-	"sort"
-	"strings"
 	"time"
 
 	"github.com/Cheemx/chirpy/internal/auth"
 	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/outbox"
+	"github.com/Cheemx/chirpy/internal/pagination"
+	"github.com/Cheemx/chirpy/internal/validation"
 	"github.com/google/uuid"
 )
 
 func (cfg *apiConfig) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	// Request Validation
 	req := struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email    string  `json:"email"`
+		Password string  `json:"password"`
+		Username *string `json:"username,omitempty"`
 	}{}
 
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err := httpapi.DecodeJSON(w, r, &req)
 	if err != nil {
-		log.Printf("Error decoding request: %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	var fieldErrs validation.Errors
+	fieldErrs.Require("email", req.Email)
+	fieldErrs.Require("password", req.Password)
+	checkUsername(&fieldErrs, req.Username)
+	if len(fieldErrs) > 0 {
+		httpapi.RespondWithError(w, httpapi.ValidationFailed(fieldErrs))
 		return
 	}
 
 	// Hashing the normal text password from r.Body
 	hashedPass, err := auth.HashPassword(req.Password)
 	if err != nil {
-		log.Printf("Error hashing the password: %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
@@ -40,40 +49,21 @@ func (cfg *apiConfig) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	user, err := cfg.db.CreateUser(r.Context(), database.CreateUserParams{
 		Email:          req.Email,
 		HashedPassword: hashedPass,
+		Username:       nullString(req.Username),
 	})
 	if err != nil {
-		log.Printf("Error creating User: %v", err)
-		w.WriteHeader(500)
+		if isUniqueViolation(err) {
+			httpapi.RespondWithError(w, httpapi.Conflict("Email or username is already taken"))
+			return
+		}
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
 	cfg.user = &user
 
 	// Creating response and responding
-	res := struct {
-		ID          uuid.UUID `json:"id"`
-		CreatedAt   time.Time `json:"created_at"`
-		UpdatedAt   time.Time `json:"updated_at"`
-		Email       string    `json:"email"`
-		IsChirpyRed bool      `json:"is_chirpy_red"`
-	}{
-		ID:          user.ID,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		Email:       user.Email,
-		IsChirpyRed: user.IsChirpyRed,
-	}
-	data, err := json.Marshal(res)
-	if err != nil {
-		log.Printf("Error marshalling JSON: %v", err)
-		w.WriteHeader(500)
-		return
-	}
-
-	// Responding!
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(201)
-	w.Write(data)
+	httpapi.RespondWithJSON(w, 201, cfg.newUserResponse(user))
 }
 
 func (cfg *apiConfig) handleLoginUser(w http.ResponseWriter, r *http.Request) {
@@ -84,29 +74,39 @@ func (cfg *apiConfig) handleLoginUser(w http.ResponseWriter, r *http.Request) {
 		ExpireIn int    `json:"expires_in_seconds,omitempty"`
 	}{}
 
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err := httpapi.DecodeJSON(w, r, &req)
 	if err != nil {
-		log.Printf("Error decoding request: %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
 	// Get User by Email
 	user, err := cfg.db.GetUserAndHashPassByEmail(r.Context(), req.Email)
 	if err != nil {
-		log.Printf("Incorrect email or password: %v", err)
-		w.WriteHeader(401)
+		if errors.Is(err, sql.ErrNoRows) {
+			httpapi.RespondWithError(w, httpapi.Unauthorized(errors.New("incorrect email or password")))
+			return
+		}
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
 	// validating password
 	err = auth.CheckPasswordHash(req.Password, user.HashedPassword)
 	if err != nil {
-		log.Print(err)
-		w.WriteHeader(401)
+		httpapi.RespondWithError(w, httpapi.Unauthorized(err))
 		return
 	}
 
+	// logging in to a deleted account takes it out of the trash
+	if user.DeletedAt.Valid {
+		user, err = cfg.restoreUser(r, user)
+		if err != nil {
+			httpapi.RespondWithError(w, err)
+			return
+		}
+	}
+
 	// Create the Access Token
 	if req.ExpireIn == 0 || req.ExpireIn > 3600 {
 		req.ExpireIn = 3600
@@ -114,16 +114,14 @@ func (cfg *apiConfig) handleLoginUser(w http.ResponseWriter, r *http.Request) {
 
 	token, err := auth.MakeJWT(user.ID, cfg.jwtSecret, time.Duration(req.ExpireIn)*time.Second)
 	if err != nil {
-		log.Printf("Error making JWT %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
 	// Create the Refresh Token
 	refreshToken, err := auth.MakeRefreshToken()
 	if err != nil {
-		log.Printf("Error making Refresh Token %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
@@ -132,90 +130,68 @@ func (cfg *apiConfig) handleLoginUser(w http.ResponseWriter, r *http.Request) {
 		UserID: user.ID,
 	})
 	if err != nil {
-		log.Printf("Error storing refresh token %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
 	// Creating response and responding
 	res := struct {
-		ID           uuid.UUID `json:"id"`
-		CreatedAt    time.Time `json:"created_at"`
-		UpdatedAt    time.Time `json:"updated_at"`
-		Email        string    `json:"email"`
-		Token        string    `json:"token"`
-		RefreshToken string    `json:"refresh_token"`
-		IsChirpyRed  bool      `json:"is_chirpy_red"`
+		userResponse
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}{
-		ID:           user.ID,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-		Email:        user.Email,
+		userResponse: cfg.newUserResponse(user),
 		Token:        token,
 		RefreshToken: refTok.Token,
-		IsChirpyRed:  user.IsChirpyRed,
 	}
-	data, err := json.Marshal(res)
-	if err != nil {
-		log.Printf("Error marshalling JSON: %v", err)
-		w.WriteHeader(500)
-		return
-	}
-
-	// Responding!
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(200)
-	w.Write(data)
+	httpapi.RespondWithJSON(w, 200, res)
 }
 
 func (cfg *apiConfig) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
 	// get token for header
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		log.Printf("Header token error %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, httpapi.Unauthorized(err))
 		return
 	}
 
 	// Look up token in db
 	refToken, err := cfg.db.GetTokenByTokenValue(r.Context(), token)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Print("Error No Rows with specified token")
-			w.WriteHeader(401)
+		if errors.Is(err, sql.ErrNoRows) {
+			httpapi.RespondWithError(w, httpapi.Unauthorized(errors.New("unknown refresh token")))
 			return
 		}
-		log.Printf("Error getting refToken %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 	// if expired return 401
 	if time.Now().Compare(refToken.ExpiresAt) > 0 {
-		log.Print("token expired")
-		w.WriteHeader(401)
+		httpapi.RespondWithError(w, httpapi.Unauthorized(errors.New("refresh token expired")))
 		return
 	}
 
 	// check if revoked
 	if refToken.RevokedAt.Valid {
-		log.Print("token revoked")
-		w.WriteHeader(401)
+		httpapi.RespondWithError(w, httpapi.Unauthorized(errors.New("refresh token revoked")))
 		return
 	}
 
-	// Get the user of that token
+	// Get the user of that token, unless they deleted their account
 	user, err := cfg.db.GetUserFromRefreshToken(r.Context(), refToken.Token)
 	if err != nil {
-		log.Printf("Error getting user from token value %v", err)
-		w.WriteHeader(500)
+		if errors.Is(err, sql.ErrNoRows) {
+			httpapi.RespondWithError(w, httpapi.Unauthorized(errors.New("account deleted")))
+			return
+		}
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
 	// Create new access token for the user
 	accessToken, err := auth.MakeJWT(user.ID, cfg.jwtSecret, 3600*time.Second)
 	if err != nil {
-		log.Printf("Error making JWT %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
@@ -225,24 +201,13 @@ func (cfg *apiConfig) handleRefreshToken(w http.ResponseWriter, r *http.Request)
 	}{
 		Token: accessToken,
 	}
-	data, err := json.Marshal(res)
-	if err != nil {
-		log.Printf("Error marshalling JSON: %v", err)
-		w.WriteHeader(500)
-		return
-	}
-
-	// Responding!
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(200)
-	w.Write(data)
+	httpapi.RespondWithJSON(w, 200, res)
 }
 
 func (cfg *apiConfig) handleRevokeRefreshToken(w http.ResponseWriter, r *http.Request) {
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		log.Printf("Error making Refresh Token %v", err)
-		w.WriteHeader(401)
+		httpapi.RespondWithError(w, httpapi.Unauthorized(err))
 		return
 	}
 
@@ -253,14 +218,12 @@ func (cfg *apiConfig) handleRevokeRefreshToken(w http.ResponseWriter, r *http.Re
 		Token:     token,
 	})
 	if err != nil {
-		log.Printf("Error setting Refresh Token timestamps %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
 	if rowsAffected < 1 {
-		log.Printf("Error setting Refresh Token timestamps %v", err)
-		w.WriteHeader(401)
+		httpapi.RespondWithError(w, httpapi.Unauthorized(errors.New("unknown or revoked refresh token")))
 		return
 	}
 
@@ -269,228 +232,178 @@ func (cfg *apiConfig) handleRevokeRefreshToken(w http.ResponseWriter, r *http.Re
 
 func (cfg *apiConfig) handleCreateChirp(w http.ResponseWriter, r *http.Request) {
 	// Authorization checkpoint
-	token, err := auth.GetBearerToken(r.Header)
+	userId, err := cfg.authenticate(r)
 	if err != nil {
-		log.Printf("Auth Header error: %v", err)
-		w.WriteHeader(401)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
-	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
-	if err != nil {
-		log.Printf("JWT validation error: %v", err)
-		w.WriteHeader(401)
-		return
-	}
 	// Request validation
-	decoder := json.NewDecoder(r.Body)
-	request := database.CreateChirpParams{}
-	err = decoder.Decode(&request)
+	var request chirpRequest
+	err = httpapi.DecodeJSON(w, r, &request)
 	if err != nil {
-		log.Printf("Error decoding request parameters: %s", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
-	// Response handling
-	type errorResponse struct {
-		Error string `json:"error"`
-	}
-
-	var errRes errorResponse
-
-	// Sending error for Chirp length out of range
-	if len(request.Body) > 140 {
-		errRes.Error = "Chirp is too long"
-		data, err := json.Marshal(errRes)
-		if err != nil {
-			log.Printf("Error marshalling JSON: %s", err)
-			w.WriteHeader(500)
-			return
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(400)
-		w.Write(data)
+	// some plans get longer chirps
+	user, err := cfg.db.GetUserByID(r.Context(), userId)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "User"))
 		return
 	}
-
-	// replacing "profane" words with "****" in request.Body
-	words := strings.Fields(request.Body)
-	clean := []string{}
-	for _, word := range words {
-		lowerWord := strings.ToLower(word)
-		if lowerWord == "kerfuffle" || lowerWord == "sharbert" || lowerWord == "fornax" {
-			clean = append(clean, "****")
-		} else {
-			clean = append(clean, word)
-		}
+	prepared, err := cfg.prepareChirp(r.Context(), cfg.db, user, request)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
 	}
-	chirp := strings.Join(clean, " ")
 
-	// Creating the Chirp in database
-	request.Body = chirp
-	request.UserID = userId
-	Chirp, err := cfg.db.CreateChirp(r.Context(), request)
+	// Creating the Chirp in database, along with the event announcing it
+	var Chirp database.Chirp
+	err = cfg.db.InTx(r.Context(), func(tx database.Store) error {
+		Chirp, err = insertChirp(r.Context(), tx, prepared)
+		return err
+	})
 	if err != nil {
-		log.Printf("Error Creating Chirp: %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
+	cfg.outbox.Wake()
 
-	// Creating response Body
-	data, err := json.Marshal(Chirp)
+	res, err := cfg.announceChirp(r.Context(), Chirp, prepared)
 	if err != nil {
-		log.Printf("Error marshalling JSON: %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
 	// Responding!
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(201)
-	w.Write(data)
+	httpapi.RespondWithJSON(w, 201, res)
 }
 
 func (cfg *apiConfig) handleGetChirps(w http.ResponseWriter, r *http.Request) {
-	chirps, err := cfg.db.GetAllChirps(r.Context())
+	query := r.URL.Query()
+
+	// page size and position
+	page, err := parsePage(r)
 	if err != nil {
-		log.Printf("Error getting Chirps: %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
 	// get order of chirps param
-	order := r.URL.Query().Get("sort")
+	order := query.Get("sort")
+	if order != "" && order != "asc" && order != "desc" {
+		httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, "sort must be asc or desc"))
+		return
+	}
+
+	params := database.ListChirpsAscParams{PageSize: page.FetchSize()}
+	params.AfterCreatedAt, params.AfterID = page.After()
 
 	// get author_id from request params if provided
-	id := r.URL.Query().Get("author_id")
-	if id != "" {
-		// get chirps of that id only
-		var c []database.Chirp
+	if id := query.Get("author_id"); id != "" {
 		uid, err := uuid.Parse(id)
 		if err != nil {
-			log.Printf("Error parsing the key: %v", err)
-			w.WriteHeader(500)
-			return
-		}
-		for _, chirp := range chirps {
-			if chirp.UserID == uid {
-				c = append(c, chirp)
-			}
-		}
-		if order == "desc" {
-			sort.Slice(c, func(i, j int) bool {
-				return c[j].CreatedAt.Compare(c[i].CreatedAt) < 0
-			})
-		}
-		// Creating response Body
-		data, err := json.Marshal(c)
-		if err != nil {
-			log.Printf("Error marshalling JSON: %v", err)
-			w.WriteHeader(500)
+			httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, "author_id is not a valid ID"))
 			return
 		}
-
-		// Responding!
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(200)
-		w.Write(data)
-		return
+		params.AuthorID = uuid.NullUUID{UUID: uid, Valid: true}
 	}
-
-	// descending chirps
+	var chirps []database.Chirp
 	if order == "desc" {
-		sort.Slice(chirps, func(i, j int) bool {
-			return chirps[j].CreatedAt.Compare(chirps[i].CreatedAt) < 0
-		})
+		chirps, err = cfg.db.ListChirpsDesc(r.Context(), database.ListChirpsDescParams(params))
+	} else {
+		chirps, err = cfg.db.ListChirpsAsc(r.Context(), params)
+	}
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
 	}
 
 	// Creating response Body
-	data, err := json.Marshal(chirps)
+	res := struct {
+		Chirps     []chirpResponse `json:"chirps"`
+		NextCursor string          `json:"next_cursor,omitempty"`
+	}{}
+	chirps, res.NextCursor = pagination.Trim(page, chirps, chirpCursor)
+	if res.NextCursor != "" {
+		w.Header().Set("Link", pagination.NextLink(r.URL, res.NextCursor))
+	}
+	res.Chirps, err = cfg.presentChirps(r.Context(), cfg.viewerID(r), chirps)
 	if err != nil {
-		log.Printf("Error marshalling JSON: %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
 	// Responding!
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(200)
-	w.Write(data)
+	httpapi.RespondWithJSON(w, 200, res)
 }
 
 func (cfg *apiConfig) handleGetChirpByID(w http.ResponseWriter, r *http.Request) {
-	ChirpID := r.PathValue("chirpID")
+	// parse the chirpID to uuid format
+	id, err := pathUUID(r, "chirpID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
 
-	chirp, err := cfg.db.GetChirpByID(r.Context(), uuid.MustParse(ChirpID))
+	chirp, err := cfg.db.GetChirpByID(r.Context(), id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Print("Chirp not found")
-			w.WriteHeader(404)
-			return
-		}
-		log.Printf("Error getting the Chirp: %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, lookupError(err, "Chirp"))
 		return
 	}
 
-	// Creating response Body
-	data, err := json.Marshal(chirp)
+	res, err := cfg.presentChirp(r.Context(), cfg.viewerID(r), chirp)
 	if err != nil {
-		log.Printf("Error marshalling JSON: %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
 	// Responding!
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(200)
-	w.Write(data)
+	httpapi.RespondWithJSON(w, 200, res)
 }
 
 func (cfg *apiConfig) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
-	// get the authorization header
-	accessToken, err := auth.GetBearerToken(r.Header)
+	// get the user from the access token
+	userID, err := cfg.authenticate(r)
 	if err != nil {
-		log.Printf("Error getting auth Header %v", err)
-		w.WriteHeader(401)
-		return
-	}
-
-	// get the user from the token
-	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
-	if err != nil {
-		log.Printf("Error getting user from token value %v", err)
-		w.WriteHeader(401)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
 	// /request body to parse the http request
 	req := struct {
-		Password string `json:"password"`
-		Email    string `json:"email"`
+		Password    string  `json:"password"`
+		Email       string  `json:"email"`
+		Username    *string `json:"username,omitempty"`
+		DisplayName *string `json:"display_name,omitempty"`
+		Bio         *string `json:"bio,omitempty"`
+		AvatarURL   *string `json:"avatar_url,omitempty"`
 	}{}
 
 	// Decode the request
-	err = json.NewDecoder(r.Body).Decode(&req)
+	err = httpapi.DecodeJSON(w, r, &req)
 	if err != nil {
-		log.Print("Error unmarshalling the request")
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
-	// validate email and password for emptiness
-	if req.Email == "" || req.Password == "" {
-		log.Print("Empty request parameters")
-		w.WriteHeader(401)
+	// validate email and password for emptiness, and whatever profile fields
+	// are being changed
+	var fieldErrs validation.Errors
+	fieldErrs.Require("email", req.Email)
+	fieldErrs.Require("password", req.Password)
+	checkUsername(&fieldErrs, req.Username)
+	checkProfile(&fieldErrs, req.DisplayName, req.Bio, req.AvatarURL)
+	if len(fieldErrs) > 0 {
+		httpapi.RespondWithError(w, httpapi.ValidationFailed(fieldErrs))
 		return
 	}
 
 	// hash the text password
 	hashedPass, err := auth.HashPassword(req.Password)
 	if err != nil {
-		log.Print("Error unmarshalling the request")
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
@@ -498,168 +411,77 @@ func (cfg *apiConfig) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 	updateduser, err := cfg.db.UpdateUser(r.Context(), database.UpdateUserParams{
 		Email:          req.Email,
 		HashedPassword: hashedPass,
+		Username:       nullString(req.Username),
+		DisplayName:    nullString(req.DisplayName),
+		Bio:            nullString(req.Bio),
+		AvatarUrl:      nullString(req.AvatarURL),
 		ID:             userID,
 	})
 	if err != nil {
-		log.Printf("Error updating the user %v", err)
-		w.WriteHeader(500)
+		if isUniqueViolation(err) {
+			httpapi.RespondWithError(w, httpapi.Conflict("Email or username is already taken"))
+			return
+		}
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
 	// Creating response and responding
-	res := struct {
-		ID          uuid.UUID `json:"id"`
-		CreatedAt   time.Time `json:"created_at"`
-		UpdatedAt   time.Time `json:"updated_at"`
-		Email       string    `json:"email"`
-		IsChirpyRed bool      `json:"is_chirpy_red"`
-	}{
-		ID:          updateduser.ID,
-		CreatedAt:   updateduser.CreatedAt,
-		UpdatedAt:   updateduser.UpdatedAt,
-		Email:       updateduser.Email,
-		IsChirpyRed: updateduser.IsChirpyRed,
-	}
-	data, err := json.Marshal(res)
-	if err != nil {
-		log.Printf("Error marshalling JSON: %v", err)
-		w.WriteHeader(500)
-		return
-	}
-
-	// Responding!
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(200)
-	w.Write(data)
+	httpapi.RespondWithJSON(w, 200, cfg.newUserResponse(updateduser))
 }
 
 func (cfg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request) {
-	// get the authorization header
-	accessToken, err := auth.GetBearerToken(r.Header)
+	// get the user from the access token
+	userID, err := cfg.authenticate(r)
 	if err != nil {
-		log.Printf("Error getting auth Header %v", err)
-		w.WriteHeader(401)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
-	// get the user from the token
-	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
-	if err != nil {
-		log.Printf("Error getting user from token value %v", err)
-		w.WriteHeader(401)
-		return
-	}
-
-	// Get the chitpID from request parameters
-	ChirpID := r.PathValue("chirpID")
-
 	// parse the chirpID to uuid format
-	id, err := uuid.Parse(ChirpID)
+	id, err := pathUUID(r, "chirpID")
 	if err != nil {
-		w.WriteHeader(400)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 
 	// Get Chirp from Database by ID
 	chirp, err := cfg.db.GetChirpByID(r.Context(), id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Print("Chirp not found")
-			w.WriteHeader(404)
-			return
-		}
-		log.Printf("Error getting the Chirp: %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, lookupError(err, "Chirp"))
 		return
 	}
 
 	// Check if Authenticated user and Chirp's author are same or not
 	if chirp.UserID != userID {
-		log.Print("Chirp Author and current user are not same")
-		w.WriteHeader(403)
-		return
-	}
-
-	// Delete the chirp since user and chirpID are confirmed
-	err = cfg.db.DeleteChirpByID(r.Context(), uuid.MustParse(ChirpID))
-	if err != nil {
-		log.Printf("Error deleting the Chirp: %v", err)
-		w.WriteHeader(500)
-		return
-	}
-
-	// chirp deleted successfully
-	w.WriteHeader(204)
-}
-
-func (cfg *apiConfig) handleUpdateUserToRed(w http.ResponseWriter, r *http.Request) {
-	// Validate the request for pola key header
-	apiKey, err := auth.GetAPIKey(r.Header)
-	if err != nil {
-		log.Printf("Error getting the apiKey, %v", err)
-		w.WriteHeader(401)
-		return
-	}
-
-	// check if both keys are same or not
-	if apiKey != cfg.polkaKey {
-		log.Print("Wrong API KEY ")
-		w.WriteHeader(401)
-		return
-	}
-
-	// request struct to parse
-	req := struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserID string `json:"user_id"`
-		} `json:"data"`
-	}{}
-
-	// Unmarshall the request
-	err = json.NewDecoder(r.Body).Decode(&req)
-	if err != nil {
-		log.Print("Error Unmarshalling request body")
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, httpapi.Forbidden("You can only delete your own Chirps"))
 		return
 	}
 
-	// check if event is correct
-	if req.Event != "user.upgraded" {
-		log.Print("Unknown Event encountered")
-		w.WriteHeader(204)
-		return
-	}
-
-	// check if id is provided
-	if req.Data.UserID == "" {
-		log.Print("Bad Request")
-		w.WriteHeader(401)
-		return
+	deleted := struct {
+		ID     uuid.UUID `json:"id"`
+		UserID uuid.UUID `json:"user_id"`
+	}{
+		ID:     chirp.ID,
+		UserID: chirp.UserID,
 	}
 
-	// get userID from req
-	id, err := uuid.Parse(req.Data.UserID)
+	// Delete the chirp since user and chirpID are confirmed, along with the
+	// event announcing it
+	err = cfg.db.InTx(r.Context(), func(tx database.Store) error {
+		if err := tx.DeleteChirpByID(r.Context(), id); err != nil {
+			return err
+		}
+		return outbox.Record(r.Context(), tx, outbox.ChirpDeleted, deleted)
+	})
 	if err != nil {
-		log.Print("Error decoding user ID")
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
+	cfg.outbox.Wake()
 
-	// update user to red
-	_, err = cfg.db.UpdateUserToRedByID(r.Context(), id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Printf("Error finding the user: %v", err)
-			w.WriteHeader(404)
-			return
-		}
-		log.Printf("Error Updating the User to Red: %v", err)
-		w.WriteHeader(500)
-		return
-	}
+	cfg.publishChirp(r.Context(), eventChirpDeleted, chirp)
 
-	// return with 204 code
+	// chirp deleted successfully
 	w.WriteHeader(204)
-
 }
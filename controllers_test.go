@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/eventbus"
+	"github.com/Cheemx/chirpy/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+func TestChirpLifecycle(t *testing.T) {
+	srv := newTestServer(t)
+
+	user := createTestUser(t, srv, "cheems@example.com")
+
+	chirp := postChirp(t, srv, user.Token, map[string]string{"body": "I had a kerfuffle today"}, 201)
+	if chirp.Body != "I had a **** today" {
+		t.Fatalf("got body %q", chirp.Body)
+	}
+
+	res := doJSON(t, srv, "GET", "/api/chirps/"+chirp.ID.String(), "", nil)
+	if res.StatusCode != 200 {
+		t.Fatalf("get chirp: got %d want 200", res.StatusCode)
+	}
+
+	other := createTestUser(t, srv, "doge@example.com")
+	res = doJSON(t, srv, "DELETE", "/api/chirps/"+chirp.ID.String(), other.Token, nil)
+	if res.StatusCode != 403 {
+		t.Fatalf("delete by other user: got %d want 403", res.StatusCode)
+	}
+
+	res = doJSON(t, srv, "DELETE", "/api/chirps/"+chirp.ID.String(), user.Token, nil)
+	if res.StatusCode != 204 {
+		t.Fatalf("delete chirp: got %d want 204", res.StatusCode)
+	}
+
+	res = doJSON(t, srv, "GET", "/api/chirps/"+chirp.ID.String(), "", nil)
+	if res.StatusCode != 404 {
+		t.Fatalf("get deleted chirp: got %d want 404", res.StatusCode)
+	}
+}
+
+func TestRefreshAndRevoke(t *testing.T) {
+	srv := newTestServer(t)
+	user := createTestUser(t, srv, "cheems@example.com")
+
+	res := doJSON(t, srv, "POST", "/api/refresh", user.RefreshToken, nil)
+	if res.StatusCode != 200 {
+		t.Fatalf("refresh: got %d want 200", res.StatusCode)
+	}
+
+	res = doJSON(t, srv, "POST", "/api/revoke", user.RefreshToken, nil)
+	if res.StatusCode != 204 {
+		t.Fatalf("revoke: got %d want 204", res.StatusCode)
+	}
+
+	res = doJSON(t, srv, "POST", "/api/refresh", user.RefreshToken, nil)
+	if res.StatusCode != 401 {
+		t.Fatalf("refresh after revoke: got %d want 401", res.StatusCode)
+	}
+}
+
+type testUser struct {
+	ID           uuid.UUID `json:"id"`
+	Email        string    `json:"email"`
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+}
+
+// newTestServer serves the full API from an in-memory store, configured
+// like main would be from the environment.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return newTestInstance(t, database.NewMemory(), eventbus.NewLocal())
+}
+
+// newTestInstance starts a server on store and events, which other
+// instances can share.
+func newTestInstance(t *testing.T, store database.Store, events eventbus.Bus) *httptest.Server {
+	t.Helper()
+	t.Setenv("SECRET", "Cheems")
+	t.Setenv("POLKA_KEY", "polka")
+	t.Setenv("POLKA_SIGNING_SECRET", "polka-secret")
+	t.Setenv("ADMIN_KEY", "admin")
+	t.Setenv("MEDIA_DIR", t.TempDir())
+	t.Setenv("MEDIA_SIGNING_SECRET", "media-secret")
+	cfg, err := newAPIConfig(context.Background(), store, events)
+	if err != nil {
+		t.Fatalf("newAPIConfig error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg.start(ctx)
+	t.Cleanup(cancel)
+	srv := httptest.NewServer(cfg.routes())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// createTestUser signs a user up and logs them in.
+func createTestUser(t *testing.T, srv *httptest.Server, email string) testUser {
+	t.Helper()
+	creds := map[string]string{"email": email, "password": "password"}
+
+	res := doJSON(t, srv, "POST", "/api/users", "", creds)
+	if res.StatusCode != 201 {
+		t.Fatalf("create user: got %d want 201", res.StatusCode)
+	}
+	res.Body.Close()
+
+	res = doJSON(t, srv, "POST", "/api/login", "", creds)
+	if res.StatusCode != 200 {
+		t.Fatalf("login: got %d want 200", res.StatusCode)
+	}
+	var user testUser
+	decodeBody(t, res, &user)
+	return user
+}
+
+// postChirp creates a chirp from req, which must get the status want. The
+// chirp is only returned when it was created.
+func postChirp(t *testing.T, srv *httptest.Server, token string, req any, want int) chirpResponse {
+	t.Helper()
+	res := doJSON(t, srv, "POST", "/api/chirps", token, req)
+	defer res.Body.Close()
+	if res.StatusCode != want {
+		t.Fatalf("create chirp %v: got %d want %d", req, res.StatusCode, want)
+	}
+	var chirp chirpResponse
+	if want == 201 {
+		decodeBody(t, res, &chirp)
+	}
+	return chirp
+}
+
+func doJSON(t *testing.T, srv *httptest.Server, method, path, token string, body any) *http.Response {
+	t.Helper()
+	authHeader := ""
+	if token != "" {
+		authHeader = "Bearer " + token
+	}
+	return doRequest(t, srv, method, path, authHeader, body)
+}
+
+// doAdmin calls an admin endpoint with the test server's ADMIN_KEY.
+func doAdmin(t *testing.T, srv *httptest.Server, method, path string, body any) *http.Response {
+	t.Helper()
+	return doRequest(t, srv, method, path, "ApiKey admin", body)
+}
+
+// doPolka sends a Polka webhook with the given API key, signed now with the
+// test server's POLKA_SIGNING_SECRET.
+func doPolka(t *testing.T, srv *httptest.Server, apiKey string, body any) *http.Response {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("encoding body: %v", err)
+	}
+	req, err := http.NewRequest("POST", srv.URL+"/api/polka/webhooks", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewRequest error: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "ApiKey "+apiKey)
+	req.Header.Set(polkaSignatureHeader, webhooks.Sign("polka-secret", time.Now(), data))
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("POST /api/polka/webhooks: %v", err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+func doRequest(t *testing.T, srv *httptest.Server, method, path, authHeader string, body any) *http.Response {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("encoding body: %v", err)
+		}
+	}
+	req, err := http.NewRequest(method, srv.URL+path, &buf)
+	if err != nil {
+		t.Fatalf("NewRequest error: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+func decodeBody(t *testing.T, res *http.Response, v any) {
+	t.Helper()
+	defer res.Body.Close()
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/entitlements"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/pagination"
+	"github.com/Cheemx/chirpy/internal/scheduler"
+	"github.com/Cheemx/chirpy/internal/validation"
+	"github.com/google/uuid"
+)
+
+// maxDraftLength caps the body of a draft that isn't scheduled. It only has
+// to pass the chirp length limit once it's published.
+const maxDraftLength = 1000
+
+// draftRequest is a draft as sent to POST and PUT /api/drafts. A publish_at
+// schedules it.
+type draftRequest struct {
+	Body      string     `json:"body"`
+	InReplyTo string     `json:"in_reply_to,omitempty"`
+	QuoteOf   string     `json:"quote_of,omitempty"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+
+	// a draft keeps only its text and what it refers to, these are here to
+	// be refused rather than lost
+	Poll     *pollRequest `json:"poll,omitempty"`
+	MediaIDs []string     `json:"media_ids,omitempty"`
+}
+
+// draftResponse is a draft. LastError says why the scheduler couldn't
+// publish it, which left it unscheduled.
+type draftResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	Body      string     `json:"body"`
+	InReplyTo *uuid.UUID `json:"in_reply_to"`
+	QuoteOf   *uuid.UUID `json:"quote_of"`
+	PublishAt *time.Time `json:"publish_at"`
+	LastError string     `json:"last_error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func newDraftResponse(draft database.Draft) draftResponse {
+	res := draftResponse{
+		ID:        draft.ID,
+		Body:      draft.Body,
+		LastError: draft.LastError.String,
+		CreatedAt: draft.CreatedAt,
+		UpdatedAt: draft.UpdatedAt,
+	}
+	if draft.ParentID.Valid {
+		res.InReplyTo = &draft.ParentID.UUID
+	}
+	if draft.QuoteOfID.Valid {
+		res.QuoteOf = &draft.QuoteOfID.UUID
+	}
+	if draft.PublishAt.Valid {
+		res.PublishAt = &draft.PublishAt.Time
+	}
+	return res
+}
+
+func draftCursor(draft database.Draft) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: draft.CreatedAt, ID: draft.ID}
+}
+
+// draftChirp is the chirp a draft would publish.
+func draftChirp(draft database.Draft) chirpRequest {
+	request := chirpRequest{Body: draft.Body}
+	if draft.ParentID.Valid {
+		request.InReplyTo = draft.ParentID.UUID.String()
+	}
+	if draft.QuoteOfID.Valid {
+		request.QuoteOf = draft.QuoteOfID.UUID.String()
+	}
+	return request
+}
+
+// draftID parses the ID of a chirp a draft refers to.
+func draftID(field, id string) (uuid.NullUUID, error) {
+	if id == "" {
+		return uuid.NullUUID{}, nil
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.NullUUID{}, httpapi.BadRequest(httpapi.CodeInvalidParameter, field+" is not a valid ID")
+	}
+	return uuid.NullUUID{UUID: parsed, Valid: true}, nil
+}
+
+// checkDraft validates a draft of user's. A draft being written only has to
+// fit maxDraftLength; a scheduled one goes through every check of a new
+// chirp already, so mistakes show up now rather than at publish_at. Neither
+// can have a poll or media, which drafts don't keep.
+func (cfg *apiConfig) checkDraft(ctx context.Context, user database.User, req draftRequest) (database.CreateDraftParams, error) {
+	var fieldErrs validation.Errors
+	if req.Poll != nil {
+		fieldErrs = append(fieldErrs, validation.FieldError{Field: "poll", Code: validation.CodeInvalid, Message: "A draft can't have a poll"})
+	}
+	if len(req.MediaIDs) > 0 {
+		fieldErrs = append(fieldErrs, validation.FieldError{Field: "media_ids", Code: validation.CodeInvalid, Message: "A draft can't have media"})
+	}
+	if req.PublishAt == nil {
+		fieldErrs.MaxLength("body", req.Body, maxDraftLength)
+	}
+	if len(fieldErrs) > 0 {
+		return database.CreateDraftParams{}, httpapi.ValidationFailed(fieldErrs)
+	}
+
+	params := database.CreateDraftParams{
+		UserID: user.ID,
+		Body:   req.Body,
+	}
+	var err error
+	params.ParentID, err = draftID("in_reply_to", req.InReplyTo)
+	if err != nil {
+		return database.CreateDraftParams{}, err
+	}
+	params.QuoteOfID, err = draftID("quote_of", req.QuoteOf)
+	if err != nil {
+		return database.CreateDraftParams{}, err
+	}
+
+	if req.PublishAt == nil {
+		return params, nil
+	}
+
+	// scheduling is a plan feature
+	if !cfg.entitlements.Has(user, entitlements.ScheduledChirps) {
+		return database.CreateDraftParams{}, httpapi.Forbidden("Your plan doesn't include scheduling Chirps")
+	}
+	if !req.PublishAt.After(time.Now()) {
+		return database.CreateDraftParams{}, httpapi.BadRequest(httpapi.CodeInvalidParameter, "publish_at must be in the future")
+	}
+	_, err = cfg.prepareChirp(ctx, cfg.db, user, chirpRequest{
+		Body:      req.Body,
+		InReplyTo: req.InReplyTo,
+		QuoteOf:   req.QuoteOf,
+	})
+	if err != nil {
+		return database.CreateDraftParams{}, err
+	}
+	params.PublishAt = sql.NullTime{Time: req.PublishAt.UTC(), Valid: true}
+	return params, nil
+}
+
+// publishDraft creates the chirp draft would publish, through tx, after the
+// same checks as any new chirp. Deleting the draft is up to the caller.
+func (cfg *apiConfig) publishDraft(ctx context.Context, tx database.Store, draft database.Draft) (database.Chirp, preparedChirp, error) {
+	user, err := tx.GetUserByID(ctx, draft.UserID)
+	if err != nil {
+		return database.Chirp{}, preparedChirp{}, lookupError(err, "User")
+	}
+	prepared, err := cfg.prepareChirp(ctx, tx, user, draftChirp(draft))
+	if err != nil {
+		return database.Chirp{}, preparedChirp{}, err
+	}
+	chirp, err := insertChirp(ctx, tx, prepared)
+	if err != nil {
+		return database.Chirp{}, preparedChirp{}, err
+	}
+	return chirp, prepared, nil
+}
+
+// publishScheduled is the scheduler's Publisher. A draft the client would
+// have been told off for is rejected, anything else is retried.
+func (cfg *apiConfig) publishScheduled(ctx context.Context, tx database.Store, draft database.Draft) (func(context.Context), error) {
+	chirp, prepared, err := cfg.publishDraft(ctx, tx, draft)
+	var apiErr *httpapi.Error
+	if errors.As(err, &apiErr) && apiErr.Status < 500 {
+		return nil, &scheduler.Rejection{Reason: apiErr.Message}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) {
+		cfg.outbox.Wake()
+		_, err := cfg.announceChirp(ctx, chirp, prepared)
+		if err != nil {
+			log.Printf("Error announcing scheduled Chirp %s: %v", chirp.ID, err)
+		}
+	}, nil
+}
+
+func (cfg *apiConfig) handleCreateDraft(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	req := draftRequest{}
+	err = httpapi.DecodeJSON(w, r, &req)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "User"))
+		return
+	}
+	params, err := cfg.checkDraft(r.Context(), user, req)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	draft, err := cfg.db.CreateDraft(r.Context(), params)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 201, newDraftResponse(draft))
+}
+
+func (cfg *apiConfig) handleGetDrafts(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// most recently started first
+	params := database.ListDraftsParams{
+		UserID:   userID,
+		PageSize: page.FetchSize(),
+	}
+	params.AfterCreatedAt, params.AfterID = page.After()
+	drafts, err := cfg.db.ListDrafts(r.Context(), params)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Creating response Body
+	res := struct {
+		Drafts     []draftResponse `json:"drafts"`
+		NextCursor string          `json:"next_cursor,omitempty"`
+	}{}
+	drafts, res.NextCursor = pagination.Trim(page, drafts, draftCursor)
+	if res.NextCursor != "" {
+		w.Header().Set("Link", pagination.NextLink(r.URL, res.NextCursor))
+	}
+	res.Drafts = make([]draftResponse, len(drafts))
+	for i, draft := range drafts {
+		res.Drafts[i] = newDraftResponse(draft)
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
+
+func (cfg *apiConfig) handleGetDraft(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	id, err := pathUUID(r, "draftID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// other users' drafts are as good as missing
+	draft, err := cfg.db.GetDraft(r.Context(), database.GetDraftParams{ID: id, UserID: userID})
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "Draft"))
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, newDraftResponse(draft))
+}
+
+func (cfg *apiConfig) handleUpdateDraft(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	id, err := pathUUID(r, "draftID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// the draft is replaced as a whole, leaving out publish_at unschedules it
+	req := draftRequest{}
+	err = httpapi.DecodeJSON(w, r, &req)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "User"))
+		return
+	}
+	params, err := cfg.checkDraft(r.Context(), user, req)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	draft, err := cfg.db.UpdateDraft(r.Context(), database.UpdateDraftParams{
+		ID:        id,
+		UserID:    userID,
+		Body:      params.Body,
+		ParentID:  params.ParentID,
+		QuoteOfID: params.QuoteOfID,
+		PublishAt: params.PublishAt,
+	})
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "Draft"))
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, newDraftResponse(draft))
+}
+
+func (cfg *apiConfig) handleDeleteDraft(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	id, err := pathUUID(r, "draftID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	n, err := cfg.db.DeleteDraft(r.Context(), database.DeleteDraftParams{ID: id, UserID: userID})
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	if n == 0 {
+		httpapi.RespondWithError(w, httpapi.NotFound("Draft not found"))
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handlePublishDraft(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	id, err := pathUUID(r, "draftID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Publishing the draft now, whenever it was scheduled for. A draft the
+	// scheduler is publishing at this moment is locked, and skipped as if
+	// it were already gone.
+	var chirp database.Chirp
+	var prepared preparedChirp
+	err = cfg.db.InTx(r.Context(), func(tx database.Store) error {
+		draft, err := tx.LockDraft(r.Context(), database.LockDraftParams{ID: id, UserID: userID})
+		if err != nil {
+			return lookupError(err, "Draft")
+		}
+		chirp, prepared, err = cfg.publishDraft(r.Context(), tx, draft)
+		if err != nil {
+			return err
+		}
+		_, err = tx.DeleteDraft(r.Context(), database.DeleteDraftParams{ID: id, UserID: userID})
+		return err
+	})
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	cfg.outbox.Wake()
+
+	res, err := cfg.announceChirp(r.Context(), chirp, prepared)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 201, res)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/validation"
+	"github.com/google/uuid"
+)
+
+func TestDrafts(t *testing.T) {
+	srv := newTestServer(t)
+	cheems := createTestUser(t, srv, "cheems@example.com")
+	doge := createTestUser(t, srv, "doge@example.com")
+
+	save := func(method, path string, user testUser, body map[string]any, want int) draftResponse {
+		t.Helper()
+		res := doJSON(t, srv, method, path, user.Token, body)
+		var draft draftResponse
+		if res.StatusCode != want {
+			res.Body.Close()
+			t.Fatalf("%s %s: got %d want %d", method, path, res.StatusCode, want)
+		}
+		if want < 300 {
+			decodeBody(t, res, &draft)
+		} else {
+			res.Body.Close()
+		}
+		return draft
+	}
+	status := func(method, path, token string, want int) {
+		t.Helper()
+		res := doJSON(t, srv, method, path, token, nil)
+		res.Body.Close()
+		if res.StatusCode != want {
+			t.Fatalf("%s %s: got %d want %d", method, path, res.StatusCode, want)
+		}
+	}
+
+	// a draft being written doesn't have to be a valid chirp yet
+	draft := save("POST", "/api/drafts", cheems, map[string]any{"body": ""}, 201)
+	path := "/api/drafts/" + draft.ID.String()
+	draft = save("PUT", path, cheems, map[string]any{"body": strings.Repeat("a", 300)}, 200)
+	if draft.PublishAt != nil || len(draft.Body) != 300 {
+		t.Fatalf("updated draft = %+v", draft)
+	}
+	save("PUT", path, cheems, map[string]any{"body": strings.Repeat("a", maxDraftLength+1)}, 400)
+	save("POST", "/api/drafts", cheems, map[string]any{"body": "hi", "in_reply_to": "nope"}, 400)
+	// drafts don't keep polls or media, so they're refused rather than lost
+	for field, value := range map[string]any{
+		"poll":      map[string]any{"options": []string{"yes", "no"}},
+		"media_ids": []string{uuid.NewString()},
+	} {
+		res := doJSON(t, srv, "POST", "/api/drafts", cheems.Token, map[string]any{"body": "hi", field: value})
+		var body struct {
+			Code   string                  `json:"code"`
+			Fields []validation.FieldError `json:"errors"`
+		}
+		decodeBody(t, res, &body)
+		if res.StatusCode != 400 || body.Code != httpapi.CodeValidationFailed || len(body.Fields) != 1 || body.Fields[0].Field != field {
+			t.Fatalf("draft with %s: got %d %+v, want a validation error on it", field, res.StatusCode, body)
+		}
+	}
+
+	// only their author sees them
+	status("GET", path, doge.Token, 404)
+	save("PUT", path, doge, map[string]any{"body": "mine now"}, 404)
+	status("DELETE", path, doge.Token, 404)
+	res := doJSON(t, srv, "GET", "/api/drafts", cheems.Token, nil)
+	var page struct {
+		Drafts []draftResponse `json:"drafts"`
+	}
+	decodeBody(t, res, &page)
+	if len(page.Drafts) != 1 || page.Drafts[0].ID != draft.ID {
+		t.Fatalf("drafts = %+v, want the one draft", page.Drafts)
+	}
+
+	// publishing now checks it like any new chirp
+	status("POST", path+"/publish", cheems.Token, 400)
+	save("PUT", path, cheems, map[string]any{"body": "fresh off the drafts"}, 200)
+	status("POST", path+"/publish", doge.Token, 404)
+	res = doJSON(t, srv, "POST", path+"/publish", cheems.Token, nil)
+	if res.StatusCode != 201 {
+		t.Fatalf("publish: got %d want 201", res.StatusCode)
+	}
+	var chirp chirpResponse
+	decodeBody(t, res, &chirp)
+	if chirp.Body != "fresh off the drafts" || chirp.UserID != cheems.ID {
+		t.Fatalf("published chirp = %+v", chirp)
+	}
+	status("GET", path, cheems.Token, 404)
+
+	// scheduling comes with Red, and only for the future
+	soon := time.Now().Add(1500 * time.Millisecond)
+	save("POST", "/api/drafts", cheems, map[string]any{"body": "later", "publish_at": soon}, 403)
+	res = doPolka(t, srv, "polka", map[string]any{
+		"id":    "evt_1",
+		"event": "user.upgraded",
+		"data":  map[string]string{"user_id": cheems.ID.String()},
+	})
+	if res.StatusCode != 204 {
+		t.Fatalf("upgrade: got %d want 204", res.StatusCode)
+	}
+	save("POST", "/api/drafts", cheems, map[string]any{"body": "later", "publish_at": time.Now().Add(-time.Minute)}, 400)
+	save("POST", "/api/drafts", cheems, map[string]any{"body": "", "publish_at": soon}, 400)
+
+	scheduled := save("POST", "/api/drafts", cheems, map[string]any{"body": "right on time", "publish_at": soon}, 201)
+	if scheduled.PublishAt == nil || !scheduled.PublishAt.Equal(soon) {
+		t.Fatalf("scheduled draft = %+v, want publish_at %v", scheduled, soon)
+	}
+	// a reply whose parent is gone by then can't be published
+	orphan := save("POST", "/api/drafts", cheems, map[string]any{
+		"body":        "replying to nothing",
+		"in_reply_to": chirp.ID.String(),
+		"publish_at":  soon,
+	}, 201)
+	status("DELETE", "/api/chirps/"+chirp.ID.String(), cheems.Token, 204)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		page, _ := getChirpsPage(t, srv, "/api/chirps")
+		if len(page.Chirps) == 1 {
+			if page.Chirps[0].Body != "right on time" {
+				t.Fatalf("scheduled chirp = %+v", page.Chirps[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("chirps = %+v, want the scheduled chirp", page.Chirps)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	status("GET", "/api/drafts/"+scheduled.ID.String(), cheems.Token, 404)
+
+	for {
+		orphan = save("GET", "/api/drafts/"+orphan.ID.String(), cheems, nil, 200)
+		if orphan.LastError != "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("orphan draft = %+v, want a last_error", orphan)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if orphan.PublishAt != nil {
+		t.Fatalf("rejected draft = %+v, want it unscheduled", orphan)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/entities"
+	"github.com/Cheemx/chirpy/internal/entitlements"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/outbox"
+	"github.com/Cheemx/chirpy/internal/validation"
+	"github.com/google/uuid"
+)
+
+// defaultEditWindow is how long after posting a chirp can be edited when
+// CHIRP_EDIT_WINDOW isn't set.
+const defaultEditWindow = 30 * time.Minute
+
+// revisionResponse is one version of a chirp's body. ReplacedAt is nil for
+// the current one.
+type revisionResponse struct {
+	Body       string     `json:"body"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReplacedAt *time.Time `json:"replaced_at"`
+}
+
+// chirpEditWindow reads how long chirps stay editable from CHIRP_EDIT_WINDOW,
+// a duration such as "30m" or "1h".
+func chirpEditWindow() (time.Duration, error) {
+	s := os.Getenv("CHIRP_EDIT_WINDOW")
+	if s == "" {
+		return defaultEditWindow, nil
+	}
+	window, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("CHIRP_EDIT_WINDOW: %w", err)
+	}
+	if window <= 0 {
+		return 0, fmt.Errorf("CHIRP_EDIT_WINDOW: %s is not positive", s)
+	}
+	return window, nil
+}
+
+// reindexChirp brings the hashtags and @mentions recorded for an edited chirp
+// in line with its new body. Tags it keeps keep their place in trending, and
+// users it mentioned before aren't notified again.
+func (cfg *apiConfig) reindexChirp(ctx context.Context, chirp database.Chirp) error {
+	err := cfg.db.UntagChirp(ctx, database.UntagChirpParams{
+		ChirpID: chirp.ID,
+		Keep:    entities.UniqueTags(entities.Hashtags(chirp.Body)),
+	})
+	if err != nil {
+		return err
+	}
+	err = cfg.tagChirp(ctx, chirp)
+	if err != nil {
+		return err
+	}
+
+	mentioned, err := cfg.db.ListChirpMentions(ctx, []uuid.UUID{chirp.ID})
+	if err != nil {
+		return err
+	}
+	notified := map[uuid.UUID]bool{}
+	for _, row := range mentioned {
+		notified[row.UserID] = true
+	}
+	err = cfg.db.DeleteChirpMentions(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	return cfg.mentionChirp(ctx, chirp, notified)
+}
+
+func (cfg *apiConfig) handleEditChirp(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// parse the chirpID to uuid format
+	id, err := pathUUID(r, "chirpID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Request validation
+	request := struct {
+		Body string `json:"body"`
+	}{}
+	err = httpapi.DecodeJSON(w, r, &request)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirpByID(r.Context(), id)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "Chirp"))
+		return
+	}
+
+	// only the author may edit, and only a body of their own
+	if chirp.UserID != userID {
+		httpapi.RespondWithError(w, httpapi.Forbidden("You can only edit your own Chirps"))
+		return
+	}
+	if chirp.RechirpOfID.Valid {
+		httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, "A rechirp has no body to edit"))
+		return
+	}
+
+	// editing is a plan feature, and only for a while after posting
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "User"))
+		return
+	}
+	if !cfg.entitlements.Has(user, entitlements.EditChirps) {
+		httpapi.RespondWithError(w, httpapi.Forbidden("Your plan doesn't include editing Chirps"))
+		return
+	}
+	if time.Since(chirp.CreatedAt) > cfg.editWindow {
+		httpapi.RespondWithError(w, httpapi.Forbidden(fmt.Sprintf("Chirps can only be edited within %v of posting", cfg.editWindow)))
+		return
+	}
+
+	// the new body passes the same checks as a new Chirp
+	err = cfg.chirpRules(user).Chirp(request.Body)
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) {
+		httpapi.RespondWithError(w, httpapi.ValidationFailed(fieldErrs))
+		return
+	}
+	verdict := cfg.moderator.Moderate(request.Body)
+	if verdict.Rejected {
+		httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeContentRejected, "Chirp rejected: "+verdict.Reason))
+		return
+	}
+
+	// an edit that changes nothing isn't recorded
+	changed := verdict.Body != chirp.Body
+	if changed {
+		// Updating the Chirp only if nobody edited it since we read it,
+		// keeping what it said before, along with the event announcing it
+		updated := chirp
+		err = cfg.db.InTx(r.Context(), func(tx database.Store) error {
+			updated, err = tx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+				ID:        chirp.ID,
+				Body:      verdict.Body,
+				UpdatedAt: chirp.UpdatedAt,
+			})
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return httpapi.Conflict("Chirp was edited at the same time, try again")
+				}
+				return err
+			}
+			_, err = tx.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+				ChirpID:   chirp.ID,
+				Body:      chirp.Body,
+				CreatedAt: chirp.UpdatedAt,
+			})
+			if err != nil {
+				return err
+			}
+			return outbox.Record(r.Context(), tx, outbox.ChirpUpdated, updated)
+		})
+		if err != nil {
+			httpapi.RespondWithError(w, err)
+			return
+		}
+		cfg.outbox.Wake()
+		chirp = updated
+
+		// the edit is already saved, a lost flag, tag or mention shouldn't
+		// fail the request
+		err = cfg.reindexChirp(r.Context(), chirp)
+		if err != nil {
+			log.Printf("Error reindexing Chirp %s: %v", chirp.ID, err)
+		}
+		cfg.flagChirp(r.Context(), chirp.ID, verdict.Flags)
+	}
+
+	res, err := cfg.presentChirp(r.Context(), userID, chirp)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	if changed {
+		cfg.publishChirp(r.Context(), eventChirpUpdated, chirp)
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
+
+func (cfg *apiConfig) handleGetChirpHistory(w http.ResponseWriter, r *http.Request) {
+	// parse the chirpID to uuid format
+	id, err := pathUUID(r, "chirpID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirpByID(r.Context(), id)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "Chirp"))
+		return
+	}
+
+	// newest first
+	revisions, err := cfg.db.ListChirpRevisions(r.Context(), id)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Creating response Body, starting with what the Chirp says now
+	res := struct {
+		ChirpID   uuid.UUID          `json:"chirp_id"`
+		Revisions []revisionResponse `json:"revisions"`
+	}{
+		ChirpID: chirp.ID,
+		Revisions: []revisionResponse{{
+			Body:      chirp.Body,
+			CreatedAt: chirp.UpdatedAt,
+		}},
+	}
+	for _, rev := range revisions {
+		res.Revisions = append(res.Revisions, revisionResponse{
+			Body:       rev.Body,
+			CreatedAt:  rev.CreatedAt,
+			ReplacedAt: &rev.ReplacedAt,
+		})
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestChirpEditing(t *testing.T) {
+	srv := newTestServer(t)
+	cheems := createTestUser(t, srv, "cheems@example.com")
+	doge := createTestUser(t, srv, "doge@example.com")
+
+	chirp := postChirp(t, srv, cheems.Token, map[string]string{"body": "learning #go today"}, 201)
+	path := "/api/chirps/" + chirp.ID.String()
+	if chirp.Edited {
+		t.Fatal("new chirp is marked edited")
+	}
+
+	edit := func(user testUser, path, body string, want int) chirpResponse {
+		t.Helper()
+		res := doJSON(t, srv, "PATCH", path, user.Token, map[string]string{"body": body})
+		if res.StatusCode != want {
+			t.Fatalf("edit %q: got %d want %d", body, res.StatusCode, want)
+		}
+		var chirp chirpResponse
+		if want == 200 {
+			decodeBody(t, res, &chirp)
+		}
+		return chirp
+	}
+
+	// editing comes with Red
+	edit(cheems, path, "learning #rust today", 403)
+	res := doPolka(t, srv, "polka", map[string]any{
+		"id":    "evt_1",
+		"event": "user.upgraded",
+		"data":  map[string]string{"user_id": cheems.ID.String()},
+	})
+	if res.StatusCode != 204 {
+		t.Fatalf("upgrade: got %d want 204", res.StatusCode)
+	}
+
+	edit(doge, path, "learning #rust today", 403)
+	edit(cheems, path, strings.Repeat("a", 281), 400)
+	edit(cheems, path, "", 400)
+
+	edited := edit(cheems, path, "learning #rust today, what a kerfuffle", 200)
+	if !edited.Edited || edited.Body != "learning #rust today, what a ****" {
+		t.Fatalf("edited chirp = %+v", edited)
+	}
+	// the same body again changes nothing
+	edit(cheems, path, "learning #rust today, what a kerfuffle", 200)
+
+	// the chirp moves from #go to #rust
+	if page, _ := getChirpsPage(t, srv, "/api/tags/go/chirps"); len(page.Chirps) != 0 {
+		t.Fatalf("#go chirps = %+v, want none", page.Chirps)
+	}
+	if page, _ := getChirpsPage(t, srv, "/api/tags/rust/chirps"); len(page.Chirps) != 1 || !page.Chirps[0].Edited {
+		t.Fatalf("#rust chirps = %+v, want the edited chirp", page.Chirps)
+	}
+
+	var history struct {
+		ChirpID   uuid.UUID          `json:"chirp_id"`
+		Revisions []revisionResponse `json:"revisions"`
+	}
+	res = doJSON(t, srv, "GET", path+"/history", "", nil)
+	if res.StatusCode != 200 {
+		t.Fatalf("history: got %d want 200", res.StatusCode)
+	}
+	decodeBody(t, res, &history)
+	if len(history.Revisions) != 2 {
+		t.Fatalf("history = %+v, want 2 revisions", history.Revisions)
+	}
+	current, previous := history.Revisions[0], history.Revisions[1]
+	if current.Body != edited.Body || current.ReplacedAt != nil {
+		t.Errorf("current revision = %+v", current)
+	}
+	if previous.Body != "learning #go today" || !previous.CreatedAt.Equal(chirp.CreatedAt) || previous.ReplacedAt == nil {
+		t.Errorf("previous revision = %+v", previous)
+	}
+
+	// a rechirp has nothing to edit
+	rechirp := postChirp(t, srv, cheems.Token, map[string]string{"rechirp_of": chirp.ID.String()}, 201)
+	edit(cheems, "/api/chirps/"+rechirp.ID.String(), "hello", 400)
+
+	// nor does anything once the window has passed
+	t.Setenv("CHIRP_EDIT_WINDOW", "1ns")
+	srv = newTestServer(t)
+	cheems = createTestUser(t, srv, "cheems@example.com")
+	doPolka(t, srv, "polka", map[string]any{
+		"id":    "evt_2",
+		"event": "user.upgraded",
+		"data":  map[string]string{"user_id": cheems.ID.String()},
+	}).Body.Close()
+	late := postChirp(t, srv, cheems.Token, map[string]string{"body": "too late"}, 201)
+	edit(cheems, "/api/chirps/"+late.ID.String(), "too late to fix", 403)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/entitlements"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+)
+
+// newEntitlements reads the plan matrix from the environment. Each plan's
+// features come from PLAN_<PLAN>_FEATURES, e.g. PLAN_RED_FEATURES, when it's
+// set; set but empty gives the plan no features.
+func newEntitlements() (*entitlements.Entitlements, error) {
+	matrix := entitlements.DefaultMatrix()
+	for _, plan := range entitlements.Plans {
+		key := "PLAN_" + strings.ToUpper(string(plan)) + "_FEATURES"
+		s, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		features, err := entitlements.ParseFeatures(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		matrix[plan] = features
+	}
+	return entitlements.New(matrix), nil
+}
+
+func (cfg *apiConfig) handleGetEntitlements(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "User"))
+		return
+	}
+
+	res := struct {
+		Plan         entitlements.Plan      `json:"plan"`
+		Features     []entitlements.Feature `json:"features"`
+		RedExpiresAt *time.Time             `json:"red_expires_at"`
+	}{
+		Plan:     cfg.entitlements.Plan(user),
+		Features: cfg.entitlements.Features(user),
+	}
+	if res.Plan == entitlements.Red && user.RedExpiresAt.Valid {
+		res.RedExpiresAt = &user.RedExpiresAt.Time
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
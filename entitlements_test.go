@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEntitlements(t *testing.T) {
+	srv := newTestServer(t)
+	cheems := createTestUser(t, srv, "cheems@example.com")
+	doge := createTestUser(t, srv, "doge@example.com")
+
+	type entitlementsResponse struct {
+		Plan         string     `json:"plan"`
+		Features     []string   `json:"features"`
+		RedExpiresAt *time.Time `json:"red_expires_at"`
+	}
+	get := func(user testUser) entitlementsResponse {
+		t.Helper()
+		res := doJSON(t, srv, "GET", "/api/entitlements", user.Token, nil)
+		if res.StatusCode != 200 {
+			t.Fatalf("GET entitlements: got %d want 200", res.StatusCode)
+		}
+		var e entitlementsResponse
+		decodeBody(t, res, &e)
+		return e
+	}
+	upgrade := func(user testUser, id string, effectiveAt, expiresAt time.Time, want int) {
+		t.Helper()
+		res := doPolka(t, srv, "polka", map[string]any{
+			"id":    id,
+			"event": "user.upgraded",
+			"data": map[string]string{
+				"user_id":      user.ID.String(),
+				"effective_at": effectiveAt.Format(time.RFC3339),
+				"expires_at":   expiresAt.Format(time.RFC3339),
+			},
+		})
+		if res.StatusCode != want {
+			t.Fatalf("upgrade: got %d want %d", res.StatusCode, want)
+		}
+	}
+	long := map[string]string{"body": strings.Repeat("a", 200)}
+
+	if e := get(cheems); e.Plan != "free" || len(e.Features) != 0 || e.RedExpiresAt != nil {
+		t.Fatalf("free entitlements = %+v", e)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	upgrade(cheems, "evt_1", now, now.Add(time.Hour), 204)
+	e := get(cheems)
+	if e.Plan != "red" || fmt.Sprint(e.Features) != "[long_chirps edit_chirps higher_rate_limits scheduled_chirps]" {
+		t.Fatalf("red entitlements = %+v", e)
+	}
+	if e.RedExpiresAt == nil || !e.RedExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("red_expires_at = %v, want %v", e.RedExpiresAt, now.Add(time.Hour))
+	}
+	postChirp(t, srv, cheems.Token, long, 201)
+
+	// doge is still on free. When Red runs out is covered in internal/entitlements
+	postChirp(t, srv, doge.Token, long, 400)
+
+	// writes are rate limited, less so on a plan with higher rate limits
+	limited := func(user testUser) bool {
+		t.Helper()
+		for range 2 * writeRate.Requests {
+			res := doJSON(t, srv, "POST", "/api/notifications/read", user.Token, map[string]any{})
+			res.Body.Close()
+			if res.StatusCode == 429 {
+				if res.Header.Get("Retry-After") == "" {
+					t.Fatal("429 without Retry-After")
+				}
+				return true
+			}
+		}
+		return false
+	}
+	if !limited(doge) {
+		t.Fatal("free user wasn't rate limited")
+	}
+	if limited(cheems) {
+		t.Fatal("red user was rate limited like a free one")
+	}
+
+	upgrade(doge, "evt_2", now, now.Add(-time.Minute), 400)
+	if res := doJSON(t, srv, "GET", "/api/entitlements", "", nil); res.StatusCode != 401 {
+		t.Fatalf("entitlements without token: got %d want 401", res.StatusCode)
+	}
+}
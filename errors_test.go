@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestErrorResponses(t *testing.T) {
+	srv := newTestServer(t)
+	user := createTestUser(t, srv, "cheems@example.com")
+
+	tests := []struct {
+		name   string
+		res    func() *http.Response
+		status int
+		code   string
+	}{
+		{
+			name:   "Bad chirp ID",
+			res:    func() *http.Response { return doJSON(t, srv, "GET", "/api/chirps/nope", "", nil) },
+			status: 400,
+			code:   "invalid_parameter",
+		},
+		{
+			name:   "Unknown chirp",
+			res:    func() *http.Response { return doJSON(t, srv, "GET", "/api/chirps/"+uuid.NewString(), "", nil) },
+			status: 404,
+			code:   "not_found",
+		},
+		{
+			name:   "Missing token",
+			res:    func() *http.Response { return doJSON(t, srv, "GET", "/api/timeline", "", nil) },
+			status: 401,
+			code:   "unauthorized",
+		},
+		{
+			name: "Duplicate email",
+			res: func() *http.Response {
+				return doJSON(t, srv, "POST", "/api/users", "", map[string]string{"email": user.Email, "password": "x"})
+			},
+			status: 409,
+			code:   "conflict",
+		},
+		{
+			name: "Update with missing fields",
+			res: func() *http.Response {
+				return doJSON(t, srv, "PUT", "/api/users", user.Token, map[string]string{"email": ""})
+			},
+			status: 400,
+			code:   "validation_failed",
+		},
+		{
+			name: "Polka with bad user ID",
+			res: func() *http.Response {
+				return doPolka(t, srv, "polka", map[string]any{
+					"id":    "evt_1",
+					"event": "user.upgraded",
+					"data":  map[string]string{"user_id": "nope"},
+				})
+			},
+			status: 400,
+			code:   "invalid_parameter",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := tt.res()
+			if res.StatusCode != tt.status {
+				t.Fatalf("got %d want %d", res.StatusCode, tt.status)
+			}
+			if ct := res.Header.Get("Content-Type"); ct != "application/json" {
+				t.Fatalf("Content-Type = %q", ct)
+			}
+			var body struct {
+				Error string `json:"error"`
+				Code  string `json:"code"`
+			}
+			decodeBody(t, res, &body)
+			if body.Code != tt.code || body.Error == "" {
+				t.Fatalf("error body = %+v, want code %s", body, tt.code)
+			}
+		})
+	}
+}
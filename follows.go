@@ -0,0 +1,216 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/notifications"
+	"github.com/Cheemx/chirpy/internal/pagination"
+	"github.com/google/uuid"
+)
+
+// followResponse is one entry in a followers or following list.
+type followResponse struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+func (cfg *apiConfig) handleFollowUser(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// parse the userID to uuid format
+	followeeID, err := pathUUID(r, "userID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// users can't follow themselves
+	if followeeID == userID {
+		httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, "You can't follow yourself"))
+		return
+	}
+
+	// make sure the user being followed exists
+	_, err = cfg.db.GetUserByID(r.Context(), followeeID)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "User"))
+		return
+	}
+
+	// following twice is a no-op
+	followed, err := cfg.db.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	if followed > 0 {
+		cfg.notify(r.Context(), notifications.Event{
+			Type:      notifications.Follow,
+			Recipient: followeeID,
+			Actor:     userID,
+		})
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handleUnfollowUser(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// parse the userID to uuid format
+	followeeID, err := pathUUID(r, "userID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// unfollowing someone you don't follow is a no-op
+	_, err = cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handleGetFollowers(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, func(userID uuid.UUID, page pagination.Page) ([]followResponse, error) {
+		params := database.ListFollowersParams{UserID: userID, PageSize: page.FetchSize()}
+		params.AfterCreatedAt, params.AfterID = page.After()
+		rows, err := cfg.db.ListFollowers(r.Context(), params)
+		if err != nil {
+			return nil, err
+		}
+		res := make([]followResponse, len(rows))
+		for i, row := range rows {
+			res[i] = followResponse{UserID: row.UserID, FollowedAt: row.CreatedAt}
+		}
+		return res, nil
+	})
+}
+
+func (cfg *apiConfig) handleGetFollowing(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, func(userID uuid.UUID, page pagination.Page) ([]followResponse, error) {
+		params := database.ListFollowingParams{UserID: userID, PageSize: page.FetchSize()}
+		params.AfterCreatedAt, params.AfterID = page.After()
+		rows, err := cfg.db.ListFollowing(r.Context(), params)
+		if err != nil {
+			return nil, err
+		}
+		res := make([]followResponse, len(rows))
+		for i, row := range rows {
+			res[i] = followResponse{UserID: row.UserID, FollowedAt: row.CreatedAt}
+		}
+		return res, nil
+	})
+}
+
+// listFollows serves one page of a user's followers or followings, newest
+// first. fetch runs the actual query.
+func (cfg *apiConfig) listFollows(w http.ResponseWriter, r *http.Request, fetch func(uuid.UUID, pagination.Page) ([]followResponse, error)) {
+	// parse the userID to uuid format
+	userID, err := pathUUID(r, "userID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// make sure the user exists
+	_, err = cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "User"))
+		return
+	}
+
+	follows, err := fetch(userID, page)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Creating response Body
+	res := struct {
+		Users      []followResponse `json:"users"`
+		NextCursor string           `json:"next_cursor,omitempty"`
+	}{
+		Users: []followResponse{},
+	}
+	follows, res.NextCursor = pagination.Trim(page, follows, func(f followResponse) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: f.FollowedAt, ID: f.UserID}
+	})
+	if res.NextCursor != "" {
+		w.Header().Set("Link", pagination.NextLink(r.URL, res.NextCursor))
+	}
+	res.Users = append(res.Users, follows...)
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
+
+func (cfg *apiConfig) handleGetTimeline(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// the user's own chirps merged with everyone they follow, newest first
+	params := database.ListTimelineParams{UserID: userID, PageSize: page.FetchSize()}
+	params.AfterCreatedAt, params.AfterID = page.After()
+	chirps, err := cfg.db.ListTimeline(r.Context(), params)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Creating response Body
+	res := struct {
+		Chirps     []chirpResponse `json:"chirps"`
+		NextCursor string          `json:"next_cursor,omitempty"`
+	}{}
+	chirps, res.NextCursor = pagination.Trim(page, chirps, chirpCursor)
+	if res.NextCursor != "" {
+		w.Header().Set("Link", pagination.NextLink(r.URL, res.NextCursor))
+	}
+	res.Chirps, err = cfg.presentChirps(r.Context(), userID, chirps)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
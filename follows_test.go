@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFollowsAndTimeline(t *testing.T) {
+	srv := newTestServer(t)
+	cheems := createTestUser(t, srv, "cheems@example.com")
+	doge := createTestUser(t, srv, "doge@example.com")
+	walter := createTestUser(t, srv, "walter@example.com")
+
+	postChirp(t, srv, cheems.Token, map[string]string{"body": "mine"}, 201)
+	postChirp(t, srv, doge.Token, map[string]string{"body": "followed"}, 201)
+	postChirp(t, srv, walter.Token, map[string]string{"body": "stranger"}, 201)
+
+	follow := "/api/users/" + doge.ID.String() + "/follow"
+	for i := 0; i < 2; i++ {
+		if res := doJSON(t, srv, "POST", follow, cheems.Token, nil); res.StatusCode != 204 {
+			t.Fatalf("follow: got %d want 204", res.StatusCode)
+		}
+	}
+	if res := doJSON(t, srv, "POST", "/api/users/"+cheems.ID.String()+"/follow", cheems.Token, nil); res.StatusCode != 400 {
+		t.Fatalf("self follow: got %d want 400", res.StatusCode)
+	}
+	if res := doJSON(t, srv, "POST", "/api/users/"+uuid.NewString()+"/follow", cheems.Token, nil); res.StatusCode != 404 {
+		t.Fatalf("follow missing user: got %d want 404", res.StatusCode)
+	}
+	if res := doJSON(t, srv, "POST", follow, "", nil); res.StatusCode != 401 {
+		t.Fatalf("anonymous follow: got %d want 401", res.StatusCode)
+	}
+
+	res := doJSON(t, srv, "GET", "/api/users/"+doge.ID.String()+"/followers", "", nil)
+	var followers struct {
+		Users []followResponse `json:"users"`
+	}
+	decodeBody(t, res, &followers)
+	if len(followers.Users) != 1 || followers.Users[0].UserID != cheems.ID {
+		t.Fatalf("followers: %+v", followers.Users)
+	}
+
+	res = doJSON(t, srv, "GET", "/api/timeline", cheems.Token, nil)
+	if res.StatusCode != 200 {
+		t.Fatalf("timeline: got %d want 200", res.StatusCode)
+	}
+	var timeline chirpsPage
+	decodeBody(t, res, &timeline)
+	if len(timeline.Chirps) != 2 || timeline.Chirps[0].Body != "followed" || timeline.Chirps[1].Body != "mine" {
+		t.Fatalf("timeline: %+v", timeline.Chirps)
+	}
+
+	if res := doJSON(t, srv, "DELETE", follow, cheems.Token, nil); res.StatusCode != 204 {
+		t.Fatalf("unfollow: got %d want 204", res.StatusCode)
+	}
+	res = doJSON(t, srv, "GET", "/api/users/"+cheems.ID.String()+"/following", "", nil)
+	var following struct {
+		Users []followResponse `json:"users"`
+	}
+	decodeBody(t, res, &following)
+	if len(following.Users) != 0 {
+		t.Fatalf("following after unfollow: %+v", following.Users)
+	}
+}
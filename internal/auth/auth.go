@@ -36,6 +36,14 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	userID, _, err := ParseJWT(tokenString, tokenSecret)
+	return userID, err
+}
+
+// ParseJWT validates a token like ValidateJWT and also returns when it
+// expires, for connections that outlive a single request. The time is zero
+// for a token without an expiry.
+func ParseJWT(tokenString, tokenSecret string) (uuid.UUID, time.Time, error) {
 	var claims jwt.RegisteredClaims
 	_, err := jwt.ParseWithClaims(tokenString, &claims, func(t *jwt.Token) (any, error) {
 		if t.Method != jwt.SigningMethodHS256 {
@@ -44,10 +52,18 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 		return []byte(tokenSecret), nil
 	})
 	if err != nil {
-		return uuid.UUID{}, err
+		return uuid.UUID{}, time.Time{}, err
 	}
 
-	return uuid.Parse(claims.Subject)
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.UUID{}, time.Time{}, err
+	}
+	var expiresAt time.Time
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	return userID, expiresAt, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
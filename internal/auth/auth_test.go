@@ -87,6 +87,19 @@ func TestJWT(t *testing.T) {
 		}
 	})
 
+	t.Run("expiry returned", func(t *testing.T) {
+		id := uuid.New()
+		tok := mustMakeJWT(t, id, secret, time.Hour)
+
+		gotID, expiresAt, err := ParseJWT(tok, secret)
+		if err != nil {
+			t.Fatalf("ParseJWT error: %v", err)
+		}
+		if gotID != id || time.Until(expiresAt).Round(time.Minute) != time.Hour {
+			t.Fatalf("got %s expiring at %s", gotID, expiresAt)
+		}
+	})
+
 	t.Run("expired token rejected", func(t *testing.T) {
 		id := uuid.New()
 		tok := mustMakeJWT(t, id, secret, -1*time.Second)
@@ -0,0 +1,170 @@
+// Package broker fans published values out to any number of subscribers in
+// the same process, keeping the most recent ones so a subscriber that
+// reconnects can catch up on what it missed.
+//
+// Publishing never waits for subscribers. Each subscriber has a small
+// buffer, and one that falls so far behind that its buffer fills up is
+// dropped: its channel is closed and it is expected to subscribe again from
+// the last ID it saw, which the replay buffer usually covers.
+package broker
+
+import (
+	"slices"
+	"sync"
+)
+
+// Message is a published value and its position in the stream. IDs start at
+// 1 and increase by one per Publish.
+type Message[T any] struct {
+	ID    uint64
+	Value T
+}
+
+// Broker distributes values of type T. The zero value isn't usable, create
+// one with New.
+type Broker[T any] struct {
+	mu         sync.Mutex
+	lastID     uint64
+	replay     []Message[T]
+	replaySize int
+	bufferSize int
+	subs       map[*Subscription[T]]struct{}
+}
+
+// New returns a Broker keeping the last replaySize messages for catching up
+// and buffering up to bufferSize messages per subscriber.
+func New[T any](replaySize, bufferSize int) *Broker[T] {
+	return &Broker[T]{
+		replaySize: replaySize,
+		bufferSize: bufferSize,
+		subs:       map[*Subscription[T]]struct{}{},
+	}
+}
+
+// Subscription receives the messages its filter accepts.
+type Subscription[T any] struct {
+	broker *Broker[T]
+	filter func(T) bool
+	ch     chan Message[T]
+	start  uint64
+	last   *Message[T]
+}
+
+// Start is the ID of the last message published before the subscription
+// started. Messages delivers only messages after it.
+func (s *Subscription[T]) Start() uint64 {
+	return s.start
+}
+
+// Last is the message with the Start ID, whatever the filter thinks of it,
+// if the replay buffer had it when the subscription started.
+func (s *Subscription[T]) Last() (Message[T], bool) {
+	if s.last == nil {
+		return Message[T]{}, false
+	}
+	return *s.last, true
+}
+
+// Messages delivers new messages. It is closed when the subscription is
+// closed or dropped for falling behind.
+func (s *Subscription[T]) Messages() <-chan Message[T] {
+	return s.ch
+}
+
+// Close stops the subscription. It is safe to call more than once, and after
+// the broker dropped it.
+func (s *Subscription[T]) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+// Publish assigns v the next ID and hands it to every subscriber whose
+// filter accepts it.
+func (b *Broker[T]) Publish(v T) Message[T] {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	msg := Message[T]{ID: b.lastID, Value: v}
+	b.replay = append(b.replay, msg)
+	if len(b.replay) > b.replaySize {
+		b.replay = b.replay[len(b.replay)-b.replaySize:]
+	}
+
+	for sub := range b.subs {
+		if !sub.filter(v) {
+			continue
+		}
+		select {
+		case sub.ch <- msg:
+		default:
+			// too slow to keep up, it has to catch up from the replay buffer
+			b.remove(sub)
+		}
+	}
+	return msg
+}
+
+// Subscribe starts a subscription to the messages filter accepts; a nil
+// filter accepts everything. With a lastID other than 0 it also returns the
+// accepted messages published after lastID that are still in the replay
+// buffer, and reports whether those are all of them. Messages that aged out
+// of the buffer, or were published before the process started, are lost.
+func (b *Broker[T]) Subscribe(lastID uint64, filter func(T) bool) (*Subscription[T], []Message[T], bool) {
+	if filter == nil {
+		filter = func(T) bool { return true }
+	}
+	sub := &Subscription[T]{
+		broker: b,
+		filter: filter,
+		ch:     make(chan Message[T], b.bufferSize),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = struct{}{}
+	sub.start = b.lastID
+	if len(b.replay) > 0 {
+		last := b.replay[len(b.replay)-1]
+		sub.last = &last
+	}
+
+	if lastID == 0 {
+		return sub, nil, true
+	}
+	complete := lastID <= b.lastID
+	if len(b.replay) > 0 && b.replay[0].ID > lastID+1 {
+		complete = false
+	}
+	var missed []Message[T]
+	for _, msg := range b.replay {
+		if msg.ID > lastID && filter(msg.Value) {
+			missed = append(missed, msg)
+		}
+	}
+	return sub, missed, complete
+}
+
+// Find returns the ID of the newest message in the replay buffer that match
+// accepts, for subscribing after a message known by something other than
+// its ID.
+func (b *Broker[T]) Find(match func(T) bool) (uint64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, msg := range slices.Backward(b.replay) {
+		if match(msg.Value) {
+			return msg.ID, true
+		}
+	}
+	return 0, false
+}
+
+// remove closes sub if it is still subscribed. Callers hold b.mu.
+func (b *Broker[T]) remove(sub *Subscription[T]) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.ch)
+}
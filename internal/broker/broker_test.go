@@ -0,0 +1,125 @@
+package broker
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPublishFilters(t *testing.T) {
+	b := New[int](10, 10)
+	even, _, _ := b.Subscribe(0, func(v int) bool { return v%2 == 0 })
+	all, _, _ := b.Subscribe(0, nil)
+	for v := 1; v <= 4; v++ {
+		b.Publish(v)
+	}
+	even.Close()
+	all.Close()
+
+	if got := drain(even); got != "[2:2 4:4]" {
+		t.Errorf("even got %s", got)
+	}
+	if got := drain(all); got != "[1:1 2:2 3:3 4:4]" {
+		t.Errorf("all got %s", got)
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	b := New[int](10, 2)
+	slow, _, _ := b.Subscribe(0, nil)
+	fast, _, _ := b.Subscribe(0, nil)
+	// publishing must not block on the slow subscriber
+	for v := 1; v <= 3; v++ {
+		b.Publish(v)
+		if v < 3 {
+			<-fast.Messages()
+		}
+	}
+
+	if got := drain(slow); got != "[1:1 2:2]" {
+		t.Errorf("slow got %s before being dropped", got)
+	}
+	// the fast one is still subscribed
+	select {
+	case msg := <-fast.Messages():
+		if msg.ID != 3 {
+			t.Errorf("fast got %v, want ID 3", msg)
+		}
+	default:
+		t.Error("fast subscriber was dropped")
+	}
+	slow.Close() // closing a dropped subscription is a no-op
+}
+
+func TestReplay(t *testing.T) {
+	b := New[string](3, 10)
+	for _, v := range []string{"a", "b", "c", "d", "e"} {
+		b.Publish(v)
+	}
+
+	tests := []struct {
+		lastID       uint64
+		want         string
+		wantComplete bool
+	}{
+		{lastID: 0, want: "[]", wantComplete: true},
+		{lastID: 3, want: "[4:d 5:e]", wantComplete: true},
+		{lastID: 2, want: "[3:c 4:d 5:e]", wantComplete: true},
+		{lastID: 1, want: "[3:c 4:d 5:e]", wantComplete: false},
+		{lastID: 5, want: "[]", wantComplete: true},
+		// an ID from before a restart
+		{lastID: 9, want: "[]", wantComplete: false},
+	}
+	for _, tt := range tests {
+		sub, missed, complete := b.Subscribe(tt.lastID, nil)
+		sub.Close()
+		if sub.Start() != 5 {
+			t.Errorf("Start() = %d, want 5", sub.Start())
+		}
+		if got := format(missed); got != tt.want || complete != tt.wantComplete {
+			t.Errorf("Subscribe(%d) = %s, %v; want %s, %v", tt.lastID, got, complete, tt.want, tt.wantComplete)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	b := New[string](3, 10)
+	empty, _, _ := b.Subscribe(0, nil)
+	empty.Close()
+	if last, ok := empty.Last(); ok {
+		t.Errorf("Last() before anything was published = %v", last)
+	}
+	for _, v := range []string{"a", "b", "c", "b", "d"} {
+		b.Publish(v)
+	}
+
+	is := func(want string) func(string) bool { return func(v string) bool { return v == want } }
+	if id, ok := b.Find(is("b")); !ok || id != 4 {
+		t.Errorf("Find(b) = %d, %v; want the newest, 4", id, ok)
+	}
+	// a has aged out of the replay buffer
+	if id, ok := b.Find(is("a")); ok {
+		t.Errorf("Find(a) = %d, want not found", id)
+	}
+
+	sub, _, _ := b.Subscribe(0, is("nothing"))
+	sub.Close()
+	if last, ok := sub.Last(); !ok || last.ID != 5 || last.Value != "d" {
+		t.Errorf("Last() = %v, %v; want 5:d whatever the filter", last, ok)
+	}
+}
+
+func drain[T any](sub *Subscription[T]) string {
+	var msgs []Message[T]
+	for msg := range sub.Messages() {
+		msgs = append(msgs, msg)
+	}
+	return format(msgs)
+}
+
+func format[T any](msgs []Message[T]) string {
+	s := make([]string, len(msgs))
+	for i, msg := range msgs {
+		s[i] = fmt.Sprintf("%d:%v", msg.ID, msg.Value)
+	}
+	return fmt.Sprint(s)
+}
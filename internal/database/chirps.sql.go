@@ -7,29 +7,125 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countChirpReplies = `-- name: CountChirpReplies :many
+SELECT parent_id, COUNT(*) AS reply_count
+FROM chirps
+WHERE parent_id = ANY($1::uuid[])
+AND deleted_at IS NULL
+GROUP BY parent_id
+`
+
+type CountChirpRepliesRow struct {
+	ParentID   uuid.NullUUID `json:"parent_id"`
+	ReplyCount int64         `json:"reply_count"`
+}
+
+func (q *Queries) CountChirpReplies(ctx context.Context, parentIds []uuid.UUID) ([]CountChirpRepliesRow, error) {
+	rows, err := q.db.QueryContext(ctx, countChirpReplies, pq.Array(parentIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountChirpRepliesRow
+	for rows.Next() {
+		var i CountChirpRepliesRow
+		if err := rows.Scan(
+			&i.ParentID,
+			&i.ReplyCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countRechirps = `-- name: CountRechirps :many
+SELECT rechirp_of_id, COUNT(*) AS rechirp_count
+FROM chirps
+WHERE rechirp_of_id = ANY($1::uuid[])
+AND deleted_at IS NULL
+GROUP BY rechirp_of_id
+`
+
+type CountRechirpsRow struct {
+	RechirpOfID  uuid.NullUUID `json:"rechirp_of_id"`
+	RechirpCount int64         `json:"rechirp_count"`
+}
+
+func (q *Queries) CountRechirps(ctx context.Context, chirpIds []uuid.UUID) ([]CountRechirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, countRechirps, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountRechirpsRow
+	for rows.Next() {
+		var i CountRechirpsRow
+		if err := rows.Scan(
+			&i.RechirpOfID,
+			&i.RechirpCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps(id, created_at, updated_at, body, user_id)
+INSERT INTO chirps(id, created_at, updated_at, body, user_id, parent_id, root_id, rechirp_of_id, quote_of_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, rechirp_of_id, quote_of_id, deleted_at
 `
 
 type CreateChirpParams struct {
-	Body   string    `json:"body"`
-	UserID uuid.UUID `json:"user_id"`
+	Body        string        `json:"body"`
+	UserID      uuid.UUID     `json:"user_id"`
+	ParentID    uuid.NullUUID `json:"parent_id"`
+	RootID      uuid.NullUUID `json:"root_id"`
+	RechirpOfID uuid.NullUUID `json:"rechirp_of_id"`
+	QuoteOfID   uuid.NullUUID `json:"quote_of_id"`
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.ParentID,
+		arg.RootID,
+		arg.RechirpOfID,
+		arg.QuoteOfID,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,21 +133,18 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const deleteAllChirps = `-- name: DeleteAllChirps :exec
-TRUNCATE TABLE chirps
-`
-
-func (q *Queries) DeleteAllChirps(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, deleteAllChirps)
-	return err
-}
-
 const deleteChirpByID = `-- name: DeleteChirpByID :exec
-DELETE FROM chirps WHERE id = $1
+UPDATE chirps SET deleted_at = NOW()
+WHERE (id = $1 OR rechirp_of_id = $1) AND deleted_at IS NULL
 `
 
 func (q *Queries) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
@@ -60,7 +153,7 @@ func (q *Queries) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
 }
 
 const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE deleted_at IS NULL ORDER BY created_at
 `
 
 func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
@@ -78,6 +171,11 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -93,7 +191,7 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE id = $1
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, rechirp_of_id, quote_of_id, deleted_at FROM chirps WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -105,6 +203,351 @@ func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getChirpThread = `-- name: GetChirpThread :many
+WITH RECURSIVE thread AS (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.rechirp_of_id, chirps.quote_of_id, chirps.deleted_at, 0 AS depth
+    FROM chirps
+    WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+    UNION ALL
+    SELECT replies.id, replies.created_at, replies.updated_at, replies.body, replies.user_id, replies.parent_id, replies.root_id, replies.rechirp_of_id, replies.quote_of_id, replies.deleted_at, thread.depth + 1
+    FROM chirps replies
+    JOIN thread ON replies.parent_id = thread.id
+    WHERE thread.depth < $2::int AND replies.deleted_at IS NULL
+)
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, rechirp_of_id, quote_of_id, deleted_at, depth FROM thread
+ORDER BY depth, created_at, id
+`
+
+type GetChirpThreadParams struct {
+	ID       uuid.UUID `json:"id"`
+	MaxDepth int32     `json:"max_depth"`
+}
+
+type GetChirpThreadRow struct {
+	ID          uuid.UUID     `json:"id"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	Body        string        `json:"body"`
+	UserID      uuid.UUID     `json:"user_id"`
+	ParentID    uuid.NullUUID `json:"parent_id"`
+	RootID      uuid.NullUUID `json:"root_id"`
+	RechirpOfID uuid.NullUUID `json:"rechirp_of_id"`
+	QuoteOfID   uuid.NullUUID `json:"quote_of_id"`
+	DeletedAt   *time.Time    `json:"deleted_at"`
+	Depth       int32         `json:"depth"`
+}
+
+func (q *Queries) GetChirpThread(ctx context.Context, arg GetChirpThreadParams) ([]GetChirpThreadRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpThread, arg.ID, arg.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpThreadRow
+	for rows.Next() {
+		var i GetChirpThreadRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+			&i.Depth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, rechirp_of_id, quote_of_id, deleted_at FROM chirps
+WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserRechirp = `-- name: GetUserRechirp :one
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, rechirp_of_id, quote_of_id, deleted_at FROM chirps
+WHERE user_id = $1 AND rechirp_of_id = $2 AND deleted_at IS NULL
+`
+
+type GetUserRechirpParams struct {
+	UserID      uuid.UUID     `json:"user_id"`
+	RechirpOfID uuid.NullUUID `json:"rechirp_of_id"`
+}
+
+func (q *Queries) GetUserRechirp(ctx context.Context, arg GetUserRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getUserRechirp, arg.UserID, arg.RechirpOfID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const listChirpReplies = `-- name: ListChirpReplies :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, rechirp_of_id, quote_of_id, deleted_at FROM chirps
+WHERE parent_id = $1::uuid
+AND deleted_at IS NULL
+AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) > ($2::timestamp, $3::uuid)
+)
+ORDER BY created_at, id
+LIMIT $4
+`
+
+type ListChirpRepliesParams struct {
+	ParentID       uuid.UUID     `json:"parent_id"`
+	AfterCreatedAt sql.NullTime  `json:"after_created_at"`
+	AfterID        uuid.NullUUID `json:"after_id"`
+	PageSize       int32         `json:"page_size"`
+}
+
+func (q *Queries) ListChirpReplies(ctx context.Context, arg ListChirpRepliesParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpReplies,
+		arg.ParentID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsAsc = `-- name: ListChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, rechirp_of_id, quote_of_id, deleted_at FROM chirps
+WHERE deleted_at IS NULL
+AND ($1::uuid IS NULL OR user_id = $1::uuid)
+AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) > ($2::timestamp, $3::uuid)
+)
+ORDER BY created_at, id
+LIMIT $4
+`
+
+type ListChirpsAscParams struct {
+	AuthorID       uuid.NullUUID `json:"author_id"`
+	AfterCreatedAt sql.NullTime  `json:"after_created_at"`
+	AfterID        uuid.NullUUID `json:"after_id"`
+	PageSize       int32         `json:"page_size"`
+}
+
+func (q *Queries) ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAsc,
+		arg.AuthorID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsDesc = `-- name: ListChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, rechirp_of_id, quote_of_id, deleted_at FROM chirps
+WHERE deleted_at IS NULL
+AND ($1::uuid IS NULL OR user_id = $1::uuid)
+AND (
+    $2::timestamp IS NULL
+    OR (created_at, id) < ($2::timestamp, $3::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListChirpsDescParams struct {
+	AuthorID       uuid.NullUUID `json:"author_id"`
+	AfterCreatedAt sql.NullTime  `json:"after_created_at"`
+	AfterID        uuid.NullUUID `json:"after_id"`
+	PageSize       int32         `json:"page_size"`
+}
+
+func (q *Queries) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsDesc,
+		arg.AuthorID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps SET body = $2, updated_at = NOW()
+WHERE id = $1 AND updated_at = $3 AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, rechirp_of_id, quote_of_id, deleted_at
+`
+
+type UpdateChirpBodyParams struct {
+	ID        uuid.UUID `json:"id"`
+	Body      string    `json:"body"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body, arg.UpdatedAt)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.DeletedAt,
 	)
 	return i, err
 }
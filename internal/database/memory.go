@@ -0,0 +1,875 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Errors returned by Memory where Postgres would raise a constraint violation.
+var (
+	ErrUniqueViolation     = errors.New("database: unique constraint violation")
+	ErrForeignKeyViolation = errors.New("database: foreign key violation")
+	ErrCheckViolation      = errors.New("database: check constraint violation")
+)
+
+// Memory is a thread-safe, in-process Store. It mirrors the schema in
+// sql/schema: emails and refresh tokens are unique, chirps and tokens must
+// reference an existing user and are removed with it, reads leave out
+// soft-deleted chirps and users, and lookups that find nothing return
+// sql.ErrNoRows.
+type Memory struct {
+	mu sync.RWMutex
+	// txMu lets one transaction run at a time, see InTx.
+	txMu sync.Mutex
+
+	users   map[uuid.UUID]User
+	chirps  map[uuid.UUID]Chirp
+	tokens  map[string]RefreshToken
+	follows map[followKey]Follow
+	likes   map[likeKey]Like
+	words   map[string]BannedWord
+	flags   map[uuid.UUID]ModerationFlag
+	tags    map[string]Tag
+	tagged  map[chirpTagKey]ChirpTag
+
+	mentions  map[mentionKey]Mention
+	revisions map[uuid.UUID]ChirpRevision
+	drafts    map[uuid.UUID]Draft
+	polls     map[uuid.UUID]Poll
+	pollVotes map[pollVoteKey]PollVote
+	media     map[uuid.UUID]Medium
+
+	notifications      map[uuid.UUID]Notification
+	notificationActors map[notificationActorKey]NotificationActor
+	notificationPrefs  map[notificationPrefKey]NotificationPreference
+
+	outbox map[uuid.UUID]OutboxEvent
+
+	webhookEvents    map[string]WebhookEvent
+	redStatusChanges map[uuid.UUID]RedStatusChange
+
+	webhooks   map[uuid.UUID]WebhookEndpoint
+	deliveries map[uuid.UUID]WebhookDelivery
+
+	// now is swapped out in tests that need deterministic timestamps.
+	now func() time.Time
+}
+
+func NewMemory() *Memory {
+	m := &Memory{
+		users:   map[uuid.UUID]User{},
+		chirps:  map[uuid.UUID]Chirp{},
+		tokens:  map[string]RefreshToken{},
+		follows: map[followKey]Follow{},
+		likes:   map[likeKey]Like{},
+		words:   map[string]BannedWord{},
+		flags:   map[uuid.UUID]ModerationFlag{},
+		tags:    map[string]Tag{},
+		tagged:  map[chirpTagKey]ChirpTag{},
+
+		mentions:  map[mentionKey]Mention{},
+		revisions: map[uuid.UUID]ChirpRevision{},
+		drafts:    map[uuid.UUID]Draft{},
+		polls:     map[uuid.UUID]Poll{},
+		pollVotes: map[pollVoteKey]PollVote{},
+		media:     map[uuid.UUID]Medium{},
+
+		notifications:      map[uuid.UUID]Notification{},
+		notificationActors: map[notificationActorKey]NotificationActor{},
+		notificationPrefs:  map[notificationPrefKey]NotificationPreference{},
+
+		outbox: map[uuid.UUID]OutboxEvent{},
+
+		webhookEvents:    map[string]WebhookEvent{},
+		redStatusChanges: map[uuid.UUID]RedStatusChange{},
+
+		webhooks:   map[uuid.UUID]WebhookEndpoint{},
+		deliveries: map[uuid.UUID]WebhookDelivery{},
+
+		now: func() time.Time { return time.Now().UTC() },
+	}
+	// seeded by 011_moderation.sql
+	for _, word := range []string{"kerfuffle", "sharbert", "fornax"} {
+		m.words[word] = BannedWord{Word: word, CreatedAt: m.now()}
+	}
+	return m
+}
+
+// InTx runs fn against a copy of m's tables and writes back what fn changed
+// only if it succeeds, so a failed transaction leaves nothing behind. Like
+// another connection in Postgres, queries against m itself don't see fn's
+// writes until then. Transactions run one at a time, which stands in for the
+// row locks they would take.
+func (m *Memory) InTx(ctx context.Context, fn func(Store) error) error {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	m.mu.RLock()
+	before := m.snapshot()
+	m.mu.RUnlock()
+	tx := before.snapshot()
+	err := fn(tx)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apply(before, tx)
+	return nil
+}
+
+// snapshot copies every table of m into a new Memory. The caller holds m.mu.
+func (m *Memory) snapshot() *Memory {
+	return &Memory{
+		users:              maps.Clone(m.users),
+		chirps:             maps.Clone(m.chirps),
+		tokens:             maps.Clone(m.tokens),
+		follows:            maps.Clone(m.follows),
+		likes:              maps.Clone(m.likes),
+		words:              maps.Clone(m.words),
+		flags:              maps.Clone(m.flags),
+		tags:               maps.Clone(m.tags),
+		tagged:             maps.Clone(m.tagged),
+		mentions:           maps.Clone(m.mentions),
+		revisions:          maps.Clone(m.revisions),
+		drafts:             maps.Clone(m.drafts),
+		polls:              maps.Clone(m.polls),
+		pollVotes:          maps.Clone(m.pollVotes),
+		media:              maps.Clone(m.media),
+		notifications:      maps.Clone(m.notifications),
+		notificationActors: maps.Clone(m.notificationActors),
+		notificationPrefs:  maps.Clone(m.notificationPrefs),
+		outbox:             maps.Clone(m.outbox),
+		webhookEvents:      maps.Clone(m.webhookEvents),
+		redStatusChanges:   maps.Clone(m.redStatusChanges),
+		webhooks:           maps.Clone(m.webhooks),
+		deliveries:         maps.Clone(m.deliveries),
+
+		now: m.now,
+	}
+}
+
+// apply writes the rows tx added, changed and removed since before to m,
+// leaving alone those changed outside the transaction meanwhile. The caller
+// holds m.mu.
+func (m *Memory) apply(before, tx *Memory) {
+	applyTable(m.users, before.users, tx.users)
+	applyTable(m.chirps, before.chirps, tx.chirps)
+	applyTable(m.tokens, before.tokens, tx.tokens)
+	applyTable(m.follows, before.follows, tx.follows)
+	applyTable(m.likes, before.likes, tx.likes)
+	applyTable(m.words, before.words, tx.words)
+	applyTable(m.flags, before.flags, tx.flags)
+	applyTable(m.tags, before.tags, tx.tags)
+	applyTable(m.tagged, before.tagged, tx.tagged)
+	applyTable(m.mentions, before.mentions, tx.mentions)
+	applyTable(m.revisions, before.revisions, tx.revisions)
+	applyTable(m.drafts, before.drafts, tx.drafts)
+	applyTable(m.polls, before.polls, tx.polls)
+	applyTable(m.pollVotes, before.pollVotes, tx.pollVotes)
+	applyTable(m.media, before.media, tx.media)
+	applyTable(m.notifications, before.notifications, tx.notifications)
+	applyTable(m.notificationActors, before.notificationActors, tx.notificationActors)
+	applyTable(m.notificationPrefs, before.notificationPrefs, tx.notificationPrefs)
+	applyTable(m.outbox, before.outbox, tx.outbox)
+	applyTable(m.webhookEvents, before.webhookEvents, tx.webhookEvents)
+	applyTable(m.redStatusChanges, before.redStatusChanges, tx.redStatusChanges)
+	applyTable(m.webhooks, before.webhooks, tx.webhooks)
+	applyTable(m.deliveries, before.deliveries, tx.deliveries)
+}
+
+func applyTable[K comparable, V any](live, before, after map[K]V) {
+	for k, v := range after {
+		old, ok := before[k]
+		if !ok || !reflect.DeepEqual(old, v) {
+			live[k] = v
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			delete(live, k)
+		}
+	}
+}
+
+// chirps.sql
+
+func (m *Memory) CountChirpReplies(ctx context.Context, parentIds []uuid.UUID) ([]CountChirpRepliesRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	wanted := map[uuid.UUID]bool{}
+	for _, id := range parentIds {
+		wanted[id] = true
+	}
+	counts := map[uuid.UUID]int64{}
+	for _, chirp := range m.chirps {
+		if chirp.DeletedAt == nil && chirp.ParentID.Valid && wanted[chirp.ParentID.UUID] {
+			counts[chirp.ParentID.UUID]++
+		}
+	}
+	var items []CountChirpRepliesRow
+	for id, n := range counts {
+		items = append(items, CountChirpRepliesRow{
+			ParentID:   uuid.NullUUID{UUID: id, Valid: true},
+			ReplyCount: n,
+		})
+	}
+	return items, nil
+}
+
+func (m *Memory) CountRechirps(ctx context.Context, chirpIds []uuid.UUID) ([]CountRechirpsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := map[uuid.UUID]int64{}
+	for _, chirp := range m.chirps {
+		if chirp.DeletedAt == nil && chirp.RechirpOfID.Valid && slices.Contains(chirpIds, chirp.RechirpOfID.UUID) {
+			counts[chirp.RechirpOfID.UUID]++
+		}
+	}
+	var items []CountRechirpsRow
+	for id, n := range counts {
+		items = append(items, CountRechirpsRow{
+			RechirpOfID:  uuid.NullUUID{UUID: id, Valid: true},
+			RechirpCount: n,
+		})
+	}
+	return items, nil
+}
+
+func (m *Memory) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return Chirp{}, ErrForeignKeyViolation
+	}
+	if !m.chirpExists(arg.ParentID) || !m.chirpExists(arg.RootID) || !m.chirpExists(arg.RechirpOfID) {
+		return Chirp{}, ErrForeignKeyViolation
+	}
+	if arg.RechirpOfID.Valid {
+		for _, chirp := range m.chirps {
+			if chirp.DeletedAt == nil && chirp.UserID == arg.UserID && chirp.RechirpOfID == arg.RechirpOfID {
+				return Chirp{}, ErrUniqueViolation
+			}
+		}
+	}
+	now := m.now()
+	chirp := Chirp{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Body:        arg.Body,
+		UserID:      arg.UserID,
+		ParentID:    arg.ParentID,
+		RootID:      arg.RootID,
+		RechirpOfID: arg.RechirpOfID,
+		QuoteOfID:   arg.QuoteOfID,
+	}
+	m.chirps[chirp.ID] = chirp
+	return chirp, nil
+}
+
+func (m *Memory) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the chirp goes to the trash along with its rechirps
+	now := m.now()
+	for _, chirp := range m.chirps {
+		if chirp.DeletedAt == nil && (chirp.ID == id || chirp.RechirpOfID.Valid && chirp.RechirpOfID.UUID == id) {
+			chirp.DeletedAt = &now
+			m.chirps[chirp.ID] = chirp
+		}
+	}
+	return nil
+}
+
+func (m *Memory) GetAllChirps(ctx context.Context) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []Chirp
+	for _, chirp := range m.chirps {
+		if chirp.DeletedAt == nil {
+			items = append(items, chirp)
+		}
+	}
+	sortChirps(items)
+	return items, nil
+}
+
+func (m *Memory) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	chirp, ok := m.liveChirp(id)
+	if !ok {
+		return Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}
+
+func (m *Memory) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []Chirp
+	for _, chirp := range m.chirps {
+		if chirp.DeletedAt == nil && slices.Contains(ids, chirp.ID) {
+			items = append(items, chirp)
+		}
+	}
+	return items, nil
+}
+
+func (m *Memory) GetChirpThread(ctx context.Context, arg GetChirpThreadParams) ([]GetChirpThreadRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	root, ok := m.liveChirp(arg.ID)
+	if !ok {
+		return nil, nil
+	}
+	items := []GetChirpThreadRow{threadRow(root, 0)}
+	level := []uuid.UUID{root.ID}
+	for depth := int32(1); depth <= arg.MaxDepth && len(level) > 0; depth++ {
+		var children []Chirp
+		for _, chirp := range m.chirps {
+			if chirp.DeletedAt == nil && chirp.ParentID.Valid && slices.Contains(level, chirp.ParentID.UUID) {
+				children = append(children, chirp)
+			}
+		}
+		sortChirps(children)
+		level = level[:0]
+		for _, chirp := range children {
+			items = append(items, threadRow(chirp, depth))
+			level = append(level, chirp.ID)
+		}
+	}
+	return items, nil
+}
+
+func (m *Memory) GetUserRechirp(ctx context.Context, arg GetUserRechirpParams) (Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, chirp := range m.chirps {
+		if chirp.DeletedAt == nil && chirp.UserID == arg.UserID && arg.RechirpOfID.Valid && chirp.RechirpOfID == arg.RechirpOfID {
+			return chirp, nil
+		}
+	}
+	return Chirp{}, sql.ErrNoRows
+}
+
+func (m *Memory) ListChirpReplies(ctx context.Context, arg ListChirpRepliesParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []Chirp
+	for _, chirp := range m.chirps {
+		if chirp.DeletedAt == nil && chirp.ParentID.Valid && chirp.ParentID.UUID == arg.ParentID {
+			items = append(items, chirp)
+		}
+	}
+	return pageChirps(items, arg.AfterCreatedAt, arg.AfterID, false, arg.PageSize), nil
+}
+
+func (m *Memory) ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error) {
+	return m.listChirps(ListChirpsDescParams(arg), false), nil
+}
+
+func (m *Memory) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error) {
+	return m.listChirps(arg, true), nil
+}
+
+// listChirps is the keyset scan behind ListChirpsAsc and ListChirpsDesc.
+func (m *Memory) listChirps(arg ListChirpsDescParams, desc bool) []Chirp {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []Chirp
+	for _, chirp := range m.chirps {
+		if chirp.DeletedAt != nil || arg.AuthorID.Valid && chirp.UserID != arg.AuthorID.UUID {
+			continue
+		}
+		items = append(items, chirp)
+	}
+	return pageChirps(items, arg.AfterCreatedAt, arg.AfterID, desc, arg.PageSize)
+}
+
+func (m *Memory) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	chirp, ok := m.liveChirp(arg.ID)
+	if !ok || !chirp.UpdatedAt.Equal(arg.UpdatedAt) {
+		return Chirp{}, sql.ErrNoRows
+	}
+	chirp.Body = arg.Body
+	chirp.UpdatedAt = m.now()
+	m.chirps[chirp.ID] = chirp
+	return chirp, nil
+}
+
+// tokens.sql
+
+func (m *Memory) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tokens[arg.Token]; ok {
+		return RefreshToken{}, ErrUniqueViolation
+	}
+	if _, ok := m.users[arg.UserID]; !ok {
+		return RefreshToken{}, ErrForeignKeyViolation
+	}
+	now := m.now()
+	token := RefreshToken{
+		Token:     arg.Token,
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    arg.UserID,
+		ExpiresAt: now.AddDate(0, 0, 60),
+	}
+	m.tokens[token.Token] = token
+	return token, nil
+}
+
+func (m *Memory) GetTokenByTokenValue(ctx context.Context, token string) (RefreshToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	refToken, ok := m.tokens[token]
+	if !ok {
+		return RefreshToken{}, sql.ErrNoRows
+	}
+	return refToken, nil
+}
+
+func (m *Memory) GetUserFromRefreshToken(ctx context.Context, token string) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	refToken, ok := m.tokens[token]
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	user, ok := m.liveUser(refToken.UserID)
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (m *Memory) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	for token, refToken := range m.tokens {
+		if refToken.UserID != userID || refToken.RevokedAt.Valid {
+			continue
+		}
+		refToken.RevokedAt = sql.NullTime{Time: now, Valid: true}
+		refToken.UpdatedAt = now
+		m.tokens[token] = refToken
+	}
+	return nil
+}
+
+func (m *Memory) SetTokenTimestamps(ctx context.Context, arg SetTokenTimestampsParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	refToken, ok := m.tokens[arg.Token]
+	if !ok || refToken.RevokedAt.Valid {
+		return 0, nil
+	}
+	refToken.RevokedAt = arg.RevokedAt
+	refToken.UpdatedAt = arg.UpdatedAt
+	m.tokens[arg.Token] = refToken
+	return 1, nil
+}
+
+// users.sql
+
+func (m *Memory) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.emailTaken(arg.Email, uuid.Nil) || m.usernameTaken(arg.Username, uuid.Nil) {
+		return User{}, ErrUniqueViolation
+	}
+	now := m.now()
+	user := User{
+		ID:             uuid.New(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+		Username:       arg.Username,
+	}
+	m.users[user.ID] = user
+	return user, nil
+}
+
+func (m *Memory) DeleteAllUsers(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// TRUNCATE ... CASCADE empties every table referencing users.
+	m.users = map[uuid.UUID]User{}
+	m.chirps = map[uuid.UUID]Chirp{}
+	m.tokens = map[string]RefreshToken{}
+	m.follows = map[followKey]Follow{}
+	m.likes = map[likeKey]Like{}
+	m.flags = map[uuid.UUID]ModerationFlag{}
+	m.tagged = map[chirpTagKey]ChirpTag{}
+	m.mentions = map[mentionKey]Mention{}
+	m.revisions = map[uuid.UUID]ChirpRevision{}
+	m.drafts = map[uuid.UUID]Draft{}
+	m.polls = map[uuid.UUID]Poll{}
+	m.pollVotes = map[pollVoteKey]PollVote{}
+	m.media = map[uuid.UUID]Medium{}
+	m.notifications = map[uuid.UUID]Notification{}
+	m.notificationActors = map[notificationActorKey]NotificationActor{}
+	m.notificationPrefs = map[notificationPrefKey]NotificationPreference{}
+	m.redStatusChanges = map[uuid.UUID]RedStatusChange{}
+	m.webhooks = map[uuid.UUID]WebhookEndpoint{}
+	m.deliveries = map[uuid.UUID]WebhookDelivery{}
+	return nil
+}
+
+func (m *Memory) DeleteUser(ctx context.Context, id uuid.UUID) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.liveUser(id)
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	user.DeletedAt = sql.NullTime{Time: m.now(), Valid: true}
+	m.users[user.ID] = user
+	return user, nil
+}
+
+func (m *Memory) GetUserAndHashPassByEmail(ctx context.Context, email string) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return User{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.liveUser(id)
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (m *Memory) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.users {
+		if !user.DeletedAt.Valid && user.Username.Valid && strings.EqualFold(user.Username.String, username) {
+			return user, nil
+		}
+	}
+	return User{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetUserCounts(ctx context.Context, userID uuid.UUID) (GetUserCountsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var row GetUserCountsRow
+	for _, chirp := range m.chirps {
+		if chirp.DeletedAt == nil && chirp.UserID == userID {
+			row.ChirpCount++
+		}
+	}
+	for key := range m.follows {
+		if _, ok := m.liveUser(key.follower); ok && key.followee == userID {
+			row.FollowerCount++
+		}
+		if _, ok := m.liveUser(key.followee); ok && key.follower == userID {
+			row.FollowingCount++
+		}
+	}
+	return row, nil
+}
+
+func (m *Memory) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []User
+	for _, id := range ids {
+		if user, ok := m.liveUser(id); ok && !slices.Contains(items, user) {
+			items = append(items, user)
+		}
+	}
+	return items, nil
+}
+
+func (m *Memory) GetUsersByUsernames(ctx context.Context, usernames []string) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []User
+	for _, user := range m.users {
+		if !user.DeletedAt.Valid && user.Username.Valid && slices.Contains(usernames, strings.ToLower(user.Username.String)) {
+			items = append(items, user)
+		}
+	}
+	return items, nil
+}
+
+func (m *Memory) RestoreUser(ctx context.Context, id uuid.UUID) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok || !user.DeletedAt.Valid {
+		return User{}, sql.ErrNoRows
+	}
+	user.DeletedAt = sql.NullTime{}
+	m.users[user.ID] = user
+	return user, nil
+}
+
+func (m *Memory) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.liveUser(arg.ID)
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	if m.emailTaken(arg.Email, arg.ID) || m.usernameTaken(arg.Username, arg.ID) {
+		return User{}, ErrUniqueViolation
+	}
+	user.Email = arg.Email
+	user.HashedPassword = arg.HashedPassword
+	if arg.Username.Valid {
+		user.Username = arg.Username
+	}
+	if arg.DisplayName.Valid {
+		user.DisplayName = arg.DisplayName.String
+	}
+	if arg.Bio.Valid {
+		user.Bio = arg.Bio.String
+	}
+	if arg.AvatarUrl.Valid {
+		user.AvatarUrl = arg.AvatarUrl.String
+	}
+	m.users[user.ID] = user
+	return user, nil
+}
+
+func (m *Memory) UpdateUserChirpyRed(ctx context.Context, arg UpdateUserChirpyRedParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[arg.ID]
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	user.IsChirpyRed = arg.IsChirpyRed
+	user.RedExpiresAt = arg.RedExpiresAt
+	m.users[user.ID] = user
+	return user, nil
+}
+
+// deleteChirp removes a chirp and applies the ON DELETE actions of every
+// reference to it. Callers must hold m.mu.
+func (m *Memory) deleteChirp(id uuid.UUID) {
+	if _, ok := m.chirps[id]; !ok {
+		return
+	}
+	delete(m.chirps, id)
+	for key := range m.likes {
+		if key.chirp == id {
+			delete(m.likes, key)
+		}
+	}
+	for flagID, flag := range m.flags {
+		if flag.ChirpID == id {
+			delete(m.flags, flagID)
+		}
+	}
+	for key := range m.tagged {
+		if key.chirp == id {
+			delete(m.tagged, key)
+		}
+	}
+	for key := range m.mentions {
+		if key.chirp == id {
+			delete(m.mentions, key)
+		}
+	}
+	for revID, rev := range m.revisions {
+		if rev.ChirpID == id {
+			delete(m.revisions, revID)
+		}
+	}
+	delete(m.polls, id)
+	for mediumID, medium := range m.media {
+		if medium.ChirpID.Valid && medium.ChirpID.UUID == id {
+			delete(m.media, mediumID)
+		}
+	}
+	for key := range m.pollVotes {
+		if key.chirp == id {
+			delete(m.pollVotes, key)
+		}
+	}
+	for nID, n := range m.notifications {
+		if n.ChirpID.Valid && n.ChirpID.UUID == id {
+			m.deleteNotification(nID)
+		}
+	}
+	for _, chirp := range m.chirps {
+		// rechirp_of_id is ON DELETE CASCADE
+		if chirp.RechirpOfID.Valid && chirp.RechirpOfID.UUID == id {
+			m.deleteChirp(chirp.ID)
+			continue
+		}
+		// parent_id and root_id are ON DELETE SET NULL
+		changed := false
+		if chirp.ParentID.Valid && chirp.ParentID.UUID == id {
+			chirp.ParentID = uuid.NullUUID{}
+			changed = true
+		}
+		if chirp.RootID.Valid && chirp.RootID.UUID == id {
+			chirp.RootID = uuid.NullUUID{}
+			changed = true
+		}
+		if changed {
+			m.chirps[chirp.ID] = chirp
+		}
+	}
+}
+
+// liveChirp looks up a chirp that isn't in the trash. Callers must hold m.mu.
+func (m *Memory) liveChirp(id uuid.UUID) (Chirp, bool) {
+	chirp, ok := m.chirps[id]
+	return chirp, ok && chirp.DeletedAt == nil
+}
+
+// liveUser looks up a user that hasn't deleted their account. Callers must
+// hold m.mu.
+func (m *Memory) liveUser(id uuid.UUID) (User, bool) {
+	user, ok := m.users[id]
+	return user, ok && !user.DeletedAt.Valid
+}
+
+// chirpExists reports whether a nullable chirp reference is satisfied.
+// Callers must hold m.mu.
+func (m *Memory) chirpExists(id uuid.NullUUID) bool {
+	if !id.Valid {
+		return true
+	}
+	_, ok := m.chirps[id.UUID]
+	return ok
+}
+
+// emailTaken reports whether a user other than except already uses email.
+// Callers must hold m.mu.
+func (m *Memory) emailTaken(email string, except uuid.UUID) bool {
+	for _, user := range m.users {
+		if user.Email == email && user.ID != except {
+			return true
+		}
+	}
+	return false
+}
+
+// usernameTaken compares case-insensitively, like the LOWER(username) index.
+func (m *Memory) usernameTaken(username sql.NullString, except uuid.UUID) bool {
+	if !username.Valid {
+		return false
+	}
+	for _, user := range m.users {
+		if user.Username.Valid && strings.EqualFold(user.Username.String, username.String) && user.ID != except {
+			return true
+		}
+	}
+	return false
+}
+
+func threadRow(chirp Chirp, depth int32) GetChirpThreadRow {
+	return GetChirpThreadRow{
+		ID:          chirp.ID,
+		CreatedAt:   chirp.CreatedAt,
+		UpdatedAt:   chirp.UpdatedAt,
+		Body:        chirp.Body,
+		UserID:      chirp.UserID,
+		ParentID:    chirp.ParentID,
+		RootID:      chirp.RootID,
+		RechirpOfID: chirp.RechirpOfID,
+		QuoteOfID:   chirp.QuoteOfID,
+		Depth:       depth,
+	}
+}
+
+// pageChirps sorts chirps by (created_at, id), keeps those strictly after the
+// cursor in that direction and truncates to pageSize, like the List* queries.
+func pageChirps(items []Chirp, afterCreatedAt sql.NullTime, afterID uuid.NullUUID, desc bool, pageSize int32) []Chirp {
+	if afterCreatedAt.Valid {
+		after := Chirp{CreatedAt: afterCreatedAt.Time, ID: afterID.UUID}
+		items = slices.DeleteFunc(items, func(chirp Chirp) bool {
+			if desc {
+				return !chirpLess(chirp, after)
+			}
+			return !chirpLess(after, chirp)
+		})
+	}
+	sortChirps(items)
+	if desc {
+		slices.Reverse(items)
+	}
+	if len(items) > int(pageSize) {
+		items = items[:pageSize]
+	}
+	return items
+}
+
+// sortChirps orders chirps by (created_at, id).
+func sortChirps(chirps []Chirp) {
+	sort.Slice(chirps, func(i, j int) bool {
+		return chirpLess(chirps[i], chirps[j])
+	})
+}
+
+// chirpLess compares chirps the way Postgres compares (created_at, id) rows.
+func chirpLess(a, b Chirp) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return bytes.Compare(a.ID[:], b.ID[:]) < 0
+}
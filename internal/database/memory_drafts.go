@@ -0,0 +1,170 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// drafts.sql
+
+// ClaimDueDraft has nothing to skip: Memory takes no row locks.
+func (m *Memory) ClaimDueDraft(ctx context.Context, now time.Time) (Draft, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var due []Draft
+	for _, draft := range m.drafts {
+		if _, ok := m.liveUser(draft.UserID); !ok || !draft.PublishAt.Valid || draft.PublishAt.Time.After(now) {
+			continue
+		}
+		if draft.NextAttemptAt.Valid && draft.NextAttemptAt.Time.After(now) {
+			continue
+		}
+		due = append(due, draft)
+	}
+	if len(due) == 0 {
+		return Draft{}, sql.ErrNoRows
+	}
+	return slices.MinFunc(due, func(a, b Draft) int {
+		if c := a.PublishAt.Time.Compare(b.PublishAt.Time); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	}), nil
+}
+
+func (m *Memory) CreateDraft(ctx context.Context, arg CreateDraftParams) (Draft, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return Draft{}, ErrForeignKeyViolation
+	}
+	now := m.now()
+	draft := Draft{
+		ID:        uuid.New(),
+		UserID:    arg.UserID,
+		Body:      arg.Body,
+		ParentID:  arg.ParentID,
+		QuoteOfID: arg.QuoteOfID,
+		PublishAt: arg.PublishAt,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	m.drafts[draft.ID] = draft
+	return draft, nil
+}
+
+func (m *Memory) DeleteDraft(ctx context.Context, arg DeleteDraftParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	draft, ok := m.drafts[arg.ID]
+	if !ok || draft.UserID != arg.UserID {
+		return 0, nil
+	}
+	delete(m.drafts, arg.ID)
+	return 1, nil
+}
+
+func (m *Memory) FailDraft(ctx context.Context, arg FailDraftParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	draft, ok := m.drafts[arg.ID]
+	if !ok {
+		return nil
+	}
+	draft.PublishAt = sql.NullTime{}
+	draft.LastError = arg.LastError
+	draft.Attempts = 0
+	draft.NextAttemptAt = sql.NullTime{}
+	draft.UpdatedAt = m.now()
+	m.drafts[draft.ID] = draft
+	return nil
+}
+
+func (m *Memory) GetDraft(ctx context.Context, arg GetDraftParams) (Draft, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	draft, ok := m.drafts[arg.ID]
+	if !ok || draft.UserID != arg.UserID {
+		return Draft{}, sql.ErrNoRows
+	}
+	return draft, nil
+}
+
+func (m *Memory) ListDrafts(ctx context.Context, arg ListDraftsParams) ([]Draft, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	compare := func(a, b Draft) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	}
+	after := Draft{ID: arg.AfterID.UUID, CreatedAt: arg.AfterCreatedAt.Time}
+
+	var items []Draft
+	for _, draft := range m.drafts {
+		if draft.UserID != arg.UserID {
+			continue
+		}
+		if arg.AfterCreatedAt.Valid && compare(draft, after) >= 0 {
+			continue
+		}
+		items = append(items, draft)
+	}
+	slices.SortFunc(items, func(a, b Draft) int { return compare(b, a) })
+	if len(items) > int(arg.PageSize) {
+		items = items[:arg.PageSize]
+	}
+	return items, nil
+}
+
+// LockDraft is GetDraft: Memory takes no row locks.
+func (m *Memory) LockDraft(ctx context.Context, arg LockDraftParams) (Draft, error) {
+	return m.GetDraft(ctx, GetDraftParams(arg))
+}
+
+func (m *Memory) RetryDraft(ctx context.Context, arg RetryDraftParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	draft, ok := m.drafts[arg.ID]
+	if !ok {
+		return nil
+	}
+	draft.Attempts++
+	draft.NextAttemptAt = arg.NextAttemptAt
+	draft.LastError = arg.LastError
+	m.drafts[draft.ID] = draft
+	return nil
+}
+
+func (m *Memory) UpdateDraft(ctx context.Context, arg UpdateDraftParams) (Draft, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	draft, ok := m.drafts[arg.ID]
+	if !ok || draft.UserID != arg.UserID {
+		return Draft{}, sql.ErrNoRows
+	}
+	draft.Body = arg.Body
+	draft.ParentID = arg.ParentID
+	draft.QuoteOfID = arg.QuoteOfID
+	draft.PublishAt = arg.PublishAt
+	draft.LastError = sql.NullString{}
+	draft.Attempts = 0
+	draft.NextAttemptAt = sql.NullTime{}
+	draft.UpdatedAt = m.now()
+	m.drafts[draft.ID] = draft
+	return draft, nil
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+type followKey struct {
+	follower uuid.UUID
+	followee uuid.UUID
+}
+
+// follows.sql
+
+func (m *Memory) FollowUser(ctx context.Context, arg FollowUserParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if arg.FollowerID == arg.FolloweeID {
+		return 0, ErrCheckViolation
+	}
+	_, okFollower := m.users[arg.FollowerID]
+	_, okFollowee := m.users[arg.FolloweeID]
+	if !okFollower || !okFollowee {
+		return 0, ErrForeignKeyViolation
+	}
+	key := followKey{arg.FollowerID, arg.FolloweeID}
+	if _, ok := m.follows[key]; ok {
+		return 0, nil
+	}
+	m.follows[key] = Follow{
+		FollowerID: arg.FollowerID,
+		FolloweeID: arg.FolloweeID,
+		CreatedAt:  m.now(),
+	}
+	return 1, nil
+}
+
+func (m *Memory) ListFolloweeIDs(ctx context.Context, followerID uuid.UUID) ([]uuid.UUID, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var ids []uuid.UUID
+	for key := range m.follows {
+		if key.follower == followerID {
+			ids = append(ids, key.followee)
+		}
+	}
+	return ids, nil
+}
+
+func (m *Memory) ListFollowers(ctx context.Context, arg ListFollowersParams) ([]ListFollowersRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []ListFollowersRow
+	for _, row := range m.listFollows(arg.AfterCreatedAt, arg.AfterID, arg.PageSize, func(f Follow) (uuid.UUID, bool) {
+		_, live := m.liveUser(f.FollowerID)
+		return f.FollowerID, live && f.FolloweeID == arg.UserID
+	}) {
+		items = append(items, ListFollowersRow(row))
+	}
+	return items, nil
+}
+
+func (m *Memory) ListFollowing(ctx context.Context, arg ListFollowingParams) ([]ListFollowingRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.listFollows(arg.AfterCreatedAt, arg.AfterID, arg.PageSize, func(f Follow) (uuid.UUID, bool) {
+		_, live := m.liveUser(f.FolloweeID)
+		return f.FolloweeID, live && f.FollowerID == arg.UserID
+	}), nil
+}
+
+func (m *Memory) ListTimeline(ctx context.Context, arg ListTimelineParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []Chirp
+	for _, chirp := range m.chirps {
+		_, following := m.follows[followKey{arg.UserID, chirp.UserID}]
+		if chirp.DeletedAt == nil && (chirp.UserID == arg.UserID || following) {
+			items = append(items, chirp)
+		}
+	}
+	return pageChirps(items, arg.AfterCreatedAt, arg.AfterID, true, arg.PageSize), nil
+}
+
+func (m *Memory) UnfollowUser(ctx context.Context, arg UnfollowUserParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := followKey{arg.FollowerID, arg.FolloweeID}
+	if _, ok := m.follows[key]; !ok {
+		return 0, nil
+	}
+	delete(m.follows, key)
+	return 1, nil
+}
+
+// listFollows is the newest-first keyset scan behind ListFollowers and
+// ListFollowing. match picks the user on the other side of each matching
+// follow. Callers must hold m.mu.
+func (m *Memory) listFollows(afterCreatedAt sql.NullTime, afterID uuid.NullUUID, pageSize int32, match func(Follow) (uuid.UUID, bool)) []ListFollowingRow {
+	var items []ListFollowingRow
+	for _, f := range m.follows {
+		if id, ok := match(f); ok {
+			items = append(items, ListFollowingRow{UserID: id, CreatedAt: f.CreatedAt})
+		}
+	}
+	compare := func(a, b ListFollowingRow) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.UserID[:], b.UserID[:])
+	}
+	if afterCreatedAt.Valid {
+		after := ListFollowingRow{UserID: afterID.UUID, CreatedAt: afterCreatedAt.Time}
+		items = slices.DeleteFunc(items, func(row ListFollowingRow) bool {
+			return compare(row, after) >= 0
+		})
+	}
+	slices.SortFunc(items, func(a, b ListFollowingRow) int { return compare(b, a) })
+	if len(items) > int(pageSize) {
+		items = items[:pageSize]
+	}
+	return items
+}
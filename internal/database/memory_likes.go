@@ -0,0 +1,106 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+type likeKey struct {
+	user  uuid.UUID
+	chirp uuid.UUID
+}
+
+// likes.sql
+
+func (m *Memory) CountChirpLikes(ctx context.Context, chirpIds []uuid.UUID) ([]CountChirpLikesRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := map[uuid.UUID]int64{}
+	for key := range m.likes {
+		if _, ok := m.liveUser(key.user); ok && slices.Contains(chirpIds, key.chirp) {
+			counts[key.chirp]++
+		}
+	}
+	var items []CountChirpLikesRow
+	for id, n := range counts {
+		items = append(items, CountChirpLikesRow{ChirpID: id, LikeCount: n})
+	}
+	return items, nil
+}
+
+func (m *Memory) LikeChirp(ctx context.Context, arg LikeChirpParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, okUser := m.users[arg.UserID]
+	_, okChirp := m.chirps[arg.ChirpID]
+	if !okUser || !okChirp {
+		return 0, ErrForeignKeyViolation
+	}
+	key := likeKey{arg.UserID, arg.ChirpID}
+	if _, ok := m.likes[key]; ok {
+		return 0, nil
+	}
+	m.likes[key] = Like{UserID: arg.UserID, ChirpID: arg.ChirpID, CreatedAt: m.now()}
+	return 1, nil
+}
+
+func (m *Memory) ListLikedChirpIDs(ctx context.Context, arg ListLikedChirpIDsParams) ([]uuid.UUID, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []uuid.UUID
+	for _, id := range arg.ChirpIds {
+		if _, ok := m.likes[likeKey{arg.UserID, id}]; ok && !slices.Contains(items, id) {
+			items = append(items, id)
+		}
+	}
+	return items, nil
+}
+
+func (m *Memory) ListUserLikes(ctx context.Context, arg ListUserLikesParams) ([]ListUserLikesRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	compare := func(a, b ListUserLikesRow) int {
+		if c := a.LikedAt.Compare(b.LikedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.Chirp.ID[:], b.Chirp.ID[:])
+	}
+	after := ListUserLikesRow{Chirp: Chirp{ID: arg.AfterID.UUID}, LikedAt: arg.AfterCreatedAt.Time}
+
+	var items []ListUserLikesRow
+	for key, like := range m.likes {
+		chirp, ok := m.liveChirp(key.chirp)
+		if !ok || key.user != arg.UserID {
+			continue
+		}
+		row := ListUserLikesRow{Chirp: chirp, LikedAt: like.CreatedAt}
+		if arg.AfterCreatedAt.Valid && compare(row, after) >= 0 {
+			continue
+		}
+		items = append(items, row)
+	}
+	slices.SortFunc(items, func(a, b ListUserLikesRow) int { return compare(b, a) })
+	if len(items) > int(arg.PageSize) {
+		items = items[:arg.PageSize]
+	}
+	return items, nil
+}
+
+func (m *Memory) UnlikeChirp(ctx context.Context, arg UnlikeChirpParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := likeKey{arg.UserID, arg.ChirpID}
+	if _, ok := m.likes[key]; !ok {
+		return 0, nil
+	}
+	delete(m.likes, key)
+	return 1, nil
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// media.sql
+
+func (m *Memory) AttachMedia(ctx context.Context, arg AttachMediaParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	medium, ok := m.media[arg.ID]
+	if !ok || medium.UserID != arg.UserID || medium.ChirpID.Valid {
+		return 0, nil
+	}
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return 0, ErrForeignKeyViolation
+	}
+	medium.ChirpID = uuid.NullUUID{UUID: arg.ChirpID, Valid: true}
+	medium.Position = arg.Position
+	m.media[arg.ID] = medium
+	return 1, nil
+}
+
+func (m *Memory) CountPendingMedia(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var n int64
+	for _, medium := range m.media {
+		if medium.UserID == userID && !medium.ChirpID.Valid {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *Memory) CreateMedia(ctx context.Context, arg CreateMediaParams) (Medium, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return Medium{}, ErrForeignKeyViolation
+	}
+	if _, ok := m.media[arg.ID]; ok {
+		return Medium{}, ErrUniqueViolation
+	}
+	medium := Medium{
+		ID:          arg.ID,
+		UserID:      arg.UserID,
+		ContentType: arg.ContentType,
+		Width:       arg.Width,
+		Height:      arg.Height,
+		SizeBytes:   arg.SizeBytes,
+		CreatedAt:   m.now(),
+	}
+	m.media[medium.ID] = medium
+	return medium, nil
+}
+
+func (m *Memory) GetMedia(ctx context.Context, id uuid.UUID) (Medium, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	medium, ok := m.media[id]
+	if !ok {
+		return Medium{}, sql.ErrNoRows
+	}
+	return medium, nil
+}
+
+func (m *Memory) ListChirpMedia(ctx context.Context, chirpIds []uuid.UUID) ([]Medium, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []Medium
+	for _, medium := range m.media {
+		if medium.ChirpID.Valid && slices.Contains(chirpIds, medium.ChirpID.UUID) {
+			items = append(items, medium)
+		}
+	}
+	slices.SortFunc(items, func(a, b Medium) int {
+		if c := bytes.Compare(a.ChirpID.UUID[:], b.ChirpID.UUID[:]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Position, b.Position)
+	})
+	return items, nil
+}
+
+func (m *Memory) PurgeMedia(ctx context.Context, arg PurgeMediaParams) ([]Medium, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var items []Medium
+	for id, medium := range m.media {
+		chirp, attached := m.chirps[medium.ChirpID.UUID]
+		user := m.users[medium.UserID]
+		switch {
+		case medium.ChirpID.Valid && attached && chirp.DeletedAt != nil && chirp.DeletedAt.Before(arg.Before),
+			user.DeletedAt.Valid && user.DeletedAt.Time.Before(arg.Before),
+			!medium.ChirpID.Valid && medium.CreatedAt.Before(arg.UnattachedBefore):
+			delete(m.media, id)
+			items = append(items, medium)
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+type mentionKey struct {
+	chirp uuid.UUID
+	start int32
+}
+
+// mentions.sql
+
+func (m *Memory) CreateMention(ctx context.Context, arg CreateMentionParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if _, ok := m.users[arg.UserID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	key := mentionKey{arg.ChirpID, arg.StartIndex}
+	if _, ok := m.mentions[key]; ok {
+		return nil
+	}
+	m.mentions[key] = Mention{
+		ChirpID:    arg.ChirpID,
+		UserID:     arg.UserID,
+		StartIndex: arg.StartIndex,
+		EndIndex:   arg.EndIndex,
+		CreatedAt:  m.now(),
+	}
+	return nil
+}
+
+func (m *Memory) DeleteChirpMentions(ctx context.Context, chirpID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key := range m.mentions {
+		if key.chirp == chirpID {
+			delete(m.mentions, key)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ListChirpMentions(ctx context.Context, chirpIds []uuid.UUID) ([]ListChirpMentionsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []ListChirpMentionsRow
+	for _, mention := range m.mentions {
+		user, ok := m.liveUser(mention.UserID)
+		if ok && slices.Contains(chirpIds, mention.ChirpID) {
+			items = append(items, ListChirpMentionsRow{
+				ChirpID:    mention.ChirpID,
+				UserID:     mention.UserID,
+				StartIndex: mention.StartIndex,
+				EndIndex:   mention.EndIndex,
+				Username:   user.Username,
+			})
+		}
+	}
+	slices.SortFunc(items, func(a, b ListChirpMentionsRow) int {
+		if c := slices.Compare(a.ChirpID[:], b.ChirpID[:]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.StartIndex, b.StartIndex)
+	})
+	return items, nil
+}
+
+func (m *Memory) ListUserMentions(ctx context.Context, arg ListUserMentionsParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := map[uuid.UUID]bool{}
+	var items []Chirp
+	for key, mention := range m.mentions {
+		chirp, ok := m.liveChirp(key.chirp)
+		if ok && mention.UserID == arg.UserID && chirp.UserID != arg.UserID && !seen[chirp.ID] {
+			seen[chirp.ID] = true
+			items = append(items, chirp)
+		}
+	}
+	return pageChirps(items, arg.AfterCreatedAt, arg.AfterID, true, arg.PageSize), nil
+}
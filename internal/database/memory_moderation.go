@@ -0,0 +1,88 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// moderation.sql
+
+func (m *Memory) AddBannedWord(ctx context.Context, word string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.words[word]; ok {
+		return 0, nil
+	}
+	m.words[word] = BannedWord{Word: word, CreatedAt: m.now()}
+	return 1, nil
+}
+
+func (m *Memory) CreateModerationFlag(ctx context.Context, arg CreateModerationFlagParams) (ModerationFlag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return ModerationFlag{}, ErrForeignKeyViolation
+	}
+	flag := ModerationFlag{
+		ID:        uuid.New(),
+		ChirpID:   arg.ChirpID,
+		Reason:    arg.Reason,
+		CreatedAt: m.now(),
+	}
+	m.flags[flag.ID] = flag
+	return flag, nil
+}
+
+func (m *Memory) DeleteBannedWord(ctx context.Context, word string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.words[word]; !ok {
+		return 0, nil
+	}
+	delete(m.words, word)
+	return 1, nil
+}
+
+func (m *Memory) ListBannedWords(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []string
+	for word := range m.words {
+		items = append(items, word)
+	}
+	slices.Sort(items)
+	return items, nil
+}
+
+func (m *Memory) ListModerationFlags(ctx context.Context, arg ListModerationFlagsParams) ([]ModerationFlag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	compare := func(a, b ModerationFlag) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	}
+	after := ModerationFlag{ID: arg.AfterID.UUID, CreatedAt: arg.AfterCreatedAt.Time}
+
+	var items []ModerationFlag
+	for _, flag := range m.flags {
+		if arg.AfterCreatedAt.Valid && compare(flag, after) >= 0 {
+			continue
+		}
+		items = append(items, flag)
+	}
+	slices.SortFunc(items, func(a, b ModerationFlag) int { return compare(b, a) })
+	if len(items) > int(arg.PageSize) {
+		items = items[:arg.PageSize]
+	}
+	return items, nil
+}
@@ -0,0 +1,231 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type notificationActorKey struct {
+	notification uuid.UUID
+	actor        uuid.UUID
+}
+
+type notificationPrefKey struct {
+	user uuid.UUID
+	kind string
+}
+
+// notificationTypes mirrors the CHECK constraints in 014_notifications.sql.
+var notificationTypes = []string{"reply", "like", "follow", "mention"}
+
+// notifications.sql
+
+func (m *Memory) AddNotificationActor(ctx context.Context, arg AddNotificationActorParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.notifications[arg.NotificationID]; !ok {
+		return 0, ErrForeignKeyViolation
+	}
+	if _, ok := m.users[arg.ActorID]; !ok {
+		return 0, ErrForeignKeyViolation
+	}
+	key := notificationActorKey{arg.NotificationID, arg.ActorID}
+	if _, ok := m.notificationActors[key]; ok {
+		return 0, nil
+	}
+	m.notificationActors[key] = NotificationActor{
+		NotificationID: arg.NotificationID,
+		ActorID:        arg.ActorID,
+		CreatedAt:      m.now(),
+	}
+	return 1, nil
+}
+
+func (m *Memory) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var n int64
+	for _, notification := range m.notifications {
+		if notification.UserID == userID && !notification.ReadAt.Valid {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *Memory) ListNotificationActors(ctx context.Context, notificationIds []uuid.UUID) ([]NotificationActor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []NotificationActor
+	for key, actor := range m.notificationActors {
+		if slices.Contains(notificationIds, key.notification) {
+			items = append(items, actor)
+		}
+	}
+	slices.SortFunc(items, func(a, b NotificationActor) int {
+		if c := slices.Compare(a.NotificationID[:], b.NotificationID[:]); c != 0 {
+			return c
+		}
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return slices.Compare(a.ActorID[:], b.ActorID[:])
+	})
+	return items, nil
+}
+
+func (m *Memory) ListNotificationPreferences(ctx context.Context, userID uuid.UUID) ([]NotificationPreference, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []NotificationPreference
+	for key, pref := range m.notificationPrefs {
+		if key.user == userID {
+			items = append(items, pref)
+		}
+	}
+	slices.SortFunc(items, func(a, b NotificationPreference) int {
+		return strings.Compare(a.Type, b.Type)
+	})
+	return items, nil
+}
+
+func (m *Memory) ListNotifications(ctx context.Context, arg ListNotificationsParams) ([]Notification, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []Notification
+	for _, notification := range m.notifications {
+		if notification.UserID != arg.UserID {
+			continue
+		}
+		after := Notification{UpdatedAt: arg.AfterUpdatedAt.Time, ID: arg.AfterID.UUID}
+		if arg.AfterUpdatedAt.Valid && !notificationLess(notification, after) {
+			continue
+		}
+		items = append(items, notification)
+	}
+	// newest first
+	slices.SortFunc(items, func(a, b Notification) int {
+		if notificationLess(b, a) {
+			return -1
+		}
+		return 1
+	})
+	if len(items) > int(arg.PageSize) {
+		items = items[:arg.PageSize]
+	}
+	return items, nil
+}
+
+func (m *Memory) MarkNotificationsRead(ctx context.Context, arg MarkNotificationsReadParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for id, notification := range m.notifications {
+		if notification.UserID != arg.UserID || notification.ReadAt.Valid {
+			continue
+		}
+		upTo := Notification{UpdatedAt: arg.UpToUpdatedAt.Time, ID: arg.UpToID.UUID}
+		if arg.UpToUpdatedAt.Valid && notificationLess(upTo, notification) {
+			continue
+		}
+		notification.ReadAt.Time, notification.ReadAt.Valid = m.now(), true
+		m.notifications[id] = notification
+		n++
+	}
+	return n, nil
+}
+
+func (m *Memory) SetNotificationPreference(ctx context.Context, arg SetNotificationPreferenceParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if !slices.Contains(notificationTypes, arg.Type) {
+		return ErrCheckViolation
+	}
+	m.notificationPrefs[notificationPrefKey{arg.UserID, arg.Type}] = NotificationPreference{
+		UserID:    arg.UserID,
+		Type:      arg.Type,
+		Enabled:   arg.Enabled,
+		UpdatedAt: m.now(),
+	}
+	return nil
+}
+
+func (m *Memory) TouchNotification(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notification, ok := m.notifications[id]
+	if !ok {
+		return nil
+	}
+	notification.UpdatedAt = m.now()
+	m.notifications[id] = notification
+	return nil
+}
+
+func (m *Memory) UpsertNotification(ctx context.Context, arg UpsertNotificationParams) (Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return Notification{}, ErrForeignKeyViolation
+	}
+	if !m.chirpExists(arg.ChirpID) {
+		return Notification{}, ErrForeignKeyViolation
+	}
+	if !slices.Contains(notificationTypes, arg.Type) {
+		return Notification{}, ErrCheckViolation
+	}
+	// the partial unique index: one unread notification per user, type and chirp
+	for _, notification := range m.notifications {
+		if notification.UserID == arg.UserID && notification.Type == arg.Type &&
+			notification.ChirpID == arg.ChirpID && !notification.ReadAt.Valid {
+			return notification, nil
+		}
+	}
+	now := m.now()
+	notification := Notification{
+		ID:        uuid.New(),
+		UserID:    arg.UserID,
+		Type:      arg.Type,
+		ChirpID:   arg.ChirpID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	m.notifications[notification.ID] = notification
+	return notification, nil
+}
+
+// deleteNotification removes a notification and its actors. Callers hold
+// the write lock.
+func (m *Memory) deleteNotification(id uuid.UUID) {
+	delete(m.notifications, id)
+	for key := range m.notificationActors {
+		if key.notification == id {
+			delete(m.notificationActors, key)
+		}
+	}
+}
+
+// notificationLess compares notifications the way Postgres compares
+// (updated_at, id) rows.
+func notificationLess(a, b Notification) bool {
+	if !a.UpdatedAt.Equal(b.UpdatedAt) {
+		return a.UpdatedAt.Before(b.UpdatedAt)
+	}
+	return bytes.Compare(a.ID[:], b.ID[:]) < 0
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// outbox.sql
+
+func (m *Memory) ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]OutboxEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	var items []OutboxEvent
+	for _, e := range m.outbox {
+		if !e.DeliveredAt.Valid && !e.NextAttemptAt.After(now) && e.Attempts < arg.MaxAttempts {
+			items = append(items, e)
+		}
+	}
+	slices.SortFunc(items, func(a, b OutboxEvent) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+	if len(items) > int(arg.BatchSize) {
+		items = items[:arg.BatchSize]
+	}
+	for i := range items {
+		items[i].Attempts++
+		items[i].NextAttemptAt = arg.LockedUntil
+		m.outbox[items[i].ID] = items[i]
+	}
+	return items, nil
+}
+
+func (m *Memory) CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (OutboxEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	e := OutboxEvent{
+		ID:            uuid.New(),
+		Type:          arg.Type,
+		Payload:       json.RawMessage(arg.Payload),
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}
+	m.outbox[e.ID] = e
+	return e, nil
+}
+
+func (m *Memory) DeleteDeliveredOutboxEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for id, e := range m.outbox {
+		if e.DeliveredAt.Valid && e.DeliveredAt.Time.Before(before) {
+			delete(m.outbox, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *Memory) MarkOutboxEventDelivered(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.outbox[id]
+	if !ok {
+		return nil
+	}
+	e.DeliveredAt = sql.NullTime{Time: m.now(), Valid: true}
+	e.LastError = sql.NullString{}
+	m.outbox[id] = e
+	return nil
+}
+
+func (m *Memory) MarkOutboxEventFailed(ctx context.Context, arg MarkOutboxEventFailedParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.outbox[arg.ID]
+	if !ok {
+		return nil
+	}
+	e.NextAttemptAt = arg.NextAttemptAt
+	e.LastError = arg.LastError
+	m.outbox[arg.ID] = e
+	return nil
+}
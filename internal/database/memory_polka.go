@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// polka.sql
+
+func (m *Memory) CreateRedStatusChange(ctx context.Context, arg CreateRedStatusChangeParams) (RedStatusChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return RedStatusChange{}, ErrForeignKeyViolation
+	}
+	if arg.WebhookEventID.Valid {
+		if _, ok := m.webhookEvents[arg.WebhookEventID.String]; !ok {
+			return RedStatusChange{}, ErrForeignKeyViolation
+		}
+	}
+	change := RedStatusChange{
+		ID:             uuid.New(),
+		UserID:         arg.UserID,
+		IsChirpyRed:    arg.IsChirpyRed,
+		Reason:         arg.Reason,
+		WebhookEventID: arg.WebhookEventID,
+		EffectiveAt:    arg.EffectiveAt,
+		CreatedAt:      m.now(),
+		ExpiresAt:      arg.ExpiresAt,
+	}
+	m.redStatusChanges[change.ID] = change
+	return change, nil
+}
+
+func (m *Memory) GetLatestRedStatusChange(ctx context.Context, userID uuid.UUID) (RedStatusChange, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	changes := m.listRedStatusChanges(userID)
+	if len(changes) == 0 {
+		return RedStatusChange{}, sql.ErrNoRows
+	}
+	return changes[0], nil
+}
+
+func (m *Memory) ListRedStatusChanges(ctx context.Context, userID uuid.UUID) ([]RedStatusChange, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.listRedStatusChanges(userID), nil
+}
+
+func (m *Memory) RecordWebhookEvent(ctx context.Context, arg RecordWebhookEventParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.webhookEvents[arg.ID]; ok {
+		return 0, nil
+	}
+	m.webhookEvents[arg.ID] = WebhookEvent{
+		ID:         arg.ID,
+		Source:     arg.Source,
+		Type:       arg.Type,
+		ReceivedAt: m.now(),
+	}
+	return 1, nil
+}
+
+// listRedStatusChanges returns userID's changes, latest effective first.
+// Callers must hold m.mu.
+func (m *Memory) listRedStatusChanges(userID uuid.UUID) []RedStatusChange {
+	var items []RedStatusChange
+	for _, change := range m.redStatusChanges {
+		if change.UserID == userID {
+			items = append(items, change)
+		}
+	}
+	slices.SortFunc(items, func(a, b RedStatusChange) int {
+		if c := b.EffectiveAt.Compare(a.EffectiveAt); c != 0 {
+			return c
+		}
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return items
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+type pollVoteKey struct {
+	chirp uuid.UUID
+	user  uuid.UUID
+}
+
+// polls.sql
+
+func (m *Memory) CountPollVotes(ctx context.Context, chirpIds []uuid.UUID) ([]CountPollVotesRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	type optionKey struct {
+		chirp  uuid.UUID
+		option int32
+	}
+	counts := map[optionKey]int64{}
+	for key, vote := range m.pollVotes {
+		if _, ok := m.liveUser(key.user); ok && slices.Contains(chirpIds, key.chirp) {
+			counts[optionKey{key.chirp, vote.OptionIndex}]++
+		}
+	}
+	var items []CountPollVotesRow
+	for key, n := range counts {
+		items = append(items, CountPollVotesRow{
+			ChirpID:     key.chirp,
+			OptionIndex: key.option,
+			VoteCount:   n,
+		})
+	}
+	return items, nil
+}
+
+func (m *Memory) CreatePoll(ctx context.Context, arg CreatePollParams) (Poll, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return Poll{}, ErrForeignKeyViolation
+	}
+	if _, ok := m.polls[arg.ChirpID]; ok {
+		return Poll{}, ErrUniqueViolation
+	}
+	poll := Poll{
+		ChirpID:   arg.ChirpID,
+		Options:   slices.Clone(arg.Options),
+		ClosesAt:  arg.ClosesAt,
+		CreatedAt: m.now(),
+	}
+	m.polls[poll.ChirpID] = poll
+	return poll, nil
+}
+
+func (m *Memory) CreatePollVote(ctx context.Context, arg CreatePollVoteParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, okPoll := m.polls[arg.ChirpID]
+	_, okUser := m.users[arg.UserID]
+	if !okPoll || !okUser {
+		return ErrForeignKeyViolation
+	}
+	key := pollVoteKey{chirp: arg.ChirpID, user: arg.UserID}
+	if _, ok := m.pollVotes[key]; ok {
+		return ErrUniqueViolation
+	}
+	m.pollVotes[key] = PollVote{
+		ChirpID:     arg.ChirpID,
+		UserID:      arg.UserID,
+		OptionIndex: arg.OptionIndex,
+		CreatedAt:   m.now(),
+	}
+	return nil
+}
+
+func (m *Memory) GetPoll(ctx context.Context, chirpID uuid.UUID) (Poll, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	poll, ok := m.polls[chirpID]
+	if !ok {
+		return Poll{}, sql.ErrNoRows
+	}
+	poll.Options = slices.Clone(poll.Options)
+	return poll, nil
+}
+
+func (m *Memory) ListPolls(ctx context.Context, chirpIds []uuid.UUID) ([]Poll, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []Poll
+	for _, id := range chirpIds {
+		poll, ok := m.polls[id]
+		if !ok || slices.ContainsFunc(items, func(p Poll) bool { return p.ChirpID == id }) {
+			continue
+		}
+		poll.Options = slices.Clone(poll.Options)
+		items = append(items, poll)
+	}
+	return items, nil
+}
+
+func (m *Memory) ListUserPollVotes(ctx context.Context, arg ListUserPollVotesParams) ([]ListUserPollVotesRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []ListUserPollVotesRow
+	for _, id := range arg.ChirpIds {
+		vote, ok := m.pollVotes[pollVoteKey{chirp: id, user: arg.UserID}]
+		if !ok || slices.ContainsFunc(items, func(row ListUserPollVotesRow) bool { return row.ChirpID == id }) {
+			continue
+		}
+		items = append(items, ListUserPollVotesRow{ChirpID: id, OptionIndex: vote.OptionIndex})
+	}
+	return items, nil
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// revisions.sql
+
+func (m *Memory) CreateChirpRevision(ctx context.Context, arg CreateChirpRevisionParams) (ChirpRevision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return ChirpRevision{}, ErrForeignKeyViolation
+	}
+	rev := ChirpRevision{
+		ID:         uuid.New(),
+		ChirpID:    arg.ChirpID,
+		Body:       arg.Body,
+		CreatedAt:  arg.CreatedAt,
+		ReplacedAt: m.now(),
+	}
+	m.revisions[rev.ID] = rev
+	return rev, nil
+}
+
+func (m *Memory) ListChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]ChirpRevision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var items []ChirpRevision
+	for _, rev := range m.revisions {
+		if rev.ChirpID == chirpID {
+			items = append(items, rev)
+		}
+	}
+	// newest first
+	slices.SortFunc(items, func(a, b ChirpRevision) int {
+		if c := b.ReplacedAt.Compare(a.ReplacedAt); c != 0 {
+			return c
+		}
+		return slices.Compare(b.ID[:], a.ID[:])
+	})
+	return items, nil
+}
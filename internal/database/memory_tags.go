@@ -0,0 +1,109 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type chirpTagKey struct {
+	chirp uuid.UUID
+	tag   uuid.UUID
+}
+
+// tags.sql
+
+func (m *Memory) ListTagChirps(ctx context.Context, arg ListTagChirpsParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tag, ok := m.tags[arg.Tag]
+	if !ok {
+		return nil, nil
+	}
+	var items []Chirp
+	for key := range m.tagged {
+		if chirp, ok := m.liveChirp(key.chirp); ok && key.tag == tag.ID {
+			items = append(items, chirp)
+		}
+	}
+	return pageChirps(items, arg.AfterCreatedAt, arg.AfterID, true, arg.PageSize), nil
+}
+
+func (m *Memory) ListTrendingTags(ctx context.Context, arg ListTrendingTagsParams) ([]ListTrendingTagsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := map[uuid.UUID]string{}
+	for name, tag := range m.tags {
+		names[tag.ID] = name
+	}
+	counts := map[string]int64{}
+	for key, ct := range m.tagged {
+		if _, ok := m.liveChirp(key.chirp); ok && !ct.CreatedAt.Before(arg.Since) {
+			counts[names[key.tag]]++
+		}
+	}
+	var items []ListTrendingTagsRow
+	for name, n := range counts {
+		items = append(items, ListTrendingTagsRow{Name: name, ChirpCount: n})
+	}
+	slices.SortFunc(items, func(a, b ListTrendingTagsRow) int {
+		if c := cmp.Compare(b.ChirpCount, a.ChirpCount); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	if len(items) > int(arg.MaxTags) {
+		items = items[:arg.MaxTags]
+	}
+	return items, nil
+}
+
+func (m *Memory) TagChirp(ctx context.Context, arg TagChirpParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	key := chirpTagKey{arg.ChirpID, arg.TagID}
+	if _, ok := m.tagged[key]; ok {
+		return nil
+	}
+	m.tagged[key] = ChirpTag{ChirpID: arg.ChirpID, TagID: arg.TagID, CreatedAt: m.now()}
+	return nil
+}
+
+func (m *Memory) UntagChirp(ctx context.Context, arg UntagChirpParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keep := map[uuid.UUID]bool{}
+	for _, name := range arg.Keep {
+		if tag, ok := m.tags[name]; ok {
+			keep[tag.ID] = true
+		}
+	}
+	for key := range m.tagged {
+		if key.chirp == arg.ChirpID && !keep[key.tag] {
+			delete(m.tagged, key)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) UpsertTag(ctx context.Context, name string) (Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if tag, ok := m.tags[name]; ok {
+		return tag, nil
+	}
+	tag := Tag{ID: uuid.New(), Name: name, CreatedAt: m.now()}
+	m.tags[name] = tag
+	return tag, nil
+}
@@ -0,0 +1,242 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMemoryUsers(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+
+	user := mustCreateUser(t, m, "cheems@example.com")
+
+	t.Run("duplicate email rejected", func(t *testing.T) {
+		_, err := m.CreateUser(ctx, CreateUserParams{Email: "cheems@example.com", HashedPassword: "x"})
+		if !errors.Is(err, ErrUniqueViolation) {
+			t.Fatalf("got %v want %v", err, ErrUniqueViolation)
+		}
+	})
+
+	t.Run("lookup by email", func(t *testing.T) {
+		got, err := m.GetUserAndHashPassByEmail(ctx, "cheems@example.com")
+		if err != nil {
+			t.Fatalf("GetUserAndHashPassByEmail error: %v", err)
+		}
+		if got.ID != user.ID {
+			t.Fatalf("got %s want %s", got.ID, user.ID)
+		}
+	})
+
+	t.Run("missing email", func(t *testing.T) {
+		_, err := m.GetUserAndHashPassByEmail(ctx, "nobody@example.com")
+		if err != sql.ErrNoRows {
+			t.Fatalf("got %v want sql.ErrNoRows", err)
+		}
+	})
+
+	t.Run("update to taken email rejected", func(t *testing.T) {
+		other := mustCreateUser(t, m, "doge@example.com")
+		_, err := m.UpdateUser(ctx, UpdateUserParams{Email: "cheems@example.com", HashedPassword: "x", ID: other.ID})
+		if !errors.Is(err, ErrUniqueViolation) {
+			t.Fatalf("got %v want %v", err, ErrUniqueViolation)
+		}
+	})
+
+	t.Run("upgrade to red and back", func(t *testing.T) {
+		got, err := m.UpdateUserChirpyRed(ctx, UpdateUserChirpyRedParams{ID: user.ID, IsChirpyRed: true})
+		if err != nil {
+			t.Fatalf("UpdateUserChirpyRed error: %v", err)
+		}
+		if !got.IsChirpyRed {
+			t.Fatalf("expected user to be chirpy red")
+		}
+		got, err = m.UpdateUserChirpyRed(ctx, UpdateUserChirpyRedParams{ID: user.ID, IsChirpyRed: false})
+		if err != nil || got.IsChirpyRed {
+			t.Fatalf("UpdateUserChirpyRed(false) = %v, %v; want a regular user", got.IsChirpyRed, err)
+		}
+		if _, err := m.UpdateUserChirpyRed(ctx, UpdateUserChirpyRedParams{ID: uuid.New(), IsChirpyRed: true}); err != sql.ErrNoRows {
+			t.Fatalf("got %v want sql.ErrNoRows", err)
+		}
+	})
+}
+
+func TestMemoryChirps(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tick := 0
+	m.now = func() time.Time {
+		tick++
+		return base.Add(time.Duration(tick) * time.Second)
+	}
+
+	user := mustCreateUser(t, m, "cheems@example.com")
+
+	if _, err := m.CreateChirp(ctx, CreateChirpParams{Body: "orphan", UserID: uuid.New()}); !errors.Is(err, ErrForeignKeyViolation) {
+		t.Fatalf("got %v want %v", err, ErrForeignKeyViolation)
+	}
+
+	for _, body := range []string{"first", "second", "third"} {
+		if _, err := m.CreateChirp(ctx, CreateChirpParams{Body: body, UserID: user.ID}); err != nil {
+			t.Fatalf("CreateChirp error: %v", err)
+		}
+	}
+
+	chirps, err := m.GetAllChirps(ctx)
+	if err != nil {
+		t.Fatalf("GetAllChirps error: %v", err)
+	}
+	if len(chirps) != 3 {
+		t.Fatalf("got %d chirps want 3", len(chirps))
+	}
+	for i, want := range []string{"first", "second", "third"} {
+		if chirps[i].Body != want {
+			t.Errorf("chirps[%d] = %q want %q", i, chirps[i].Body, want)
+		}
+	}
+
+	if err := m.DeleteChirpByID(ctx, chirps[0].ID); err != nil {
+		t.Fatalf("DeleteChirpByID error: %v", err)
+	}
+	if _, err := m.GetChirpByID(ctx, chirps[0].ID); err != sql.ErrNoRows {
+		t.Fatalf("got %v want sql.ErrNoRows", err)
+	}
+}
+
+func TestMemoryCascade(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+
+	user := mustCreateUser(t, m, "cheems@example.com")
+	chirp, err := m.CreateChirp(ctx, CreateChirpParams{Body: "hello", UserID: user.ID})
+	if err != nil {
+		t.Fatalf("CreateChirp error: %v", err)
+	}
+	if _, err := m.CreateRefreshToken(ctx, CreateRefreshTokenParams{Token: "tok", UserID: user.ID}); err != nil {
+		t.Fatalf("CreateRefreshToken error: %v", err)
+	}
+
+	if err := m.DeleteAllUsers(ctx); err != nil {
+		t.Fatalf("DeleteAllUsers error: %v", err)
+	}
+	if _, err := m.GetChirpByID(ctx, chirp.ID); err != sql.ErrNoRows {
+		t.Fatalf("chirp survived cascade: %v", err)
+	}
+	if _, err := m.GetTokenByTokenValue(ctx, "tok"); err != sql.ErrNoRows {
+		t.Fatalf("token survived cascade: %v", err)
+	}
+}
+
+func TestMemoryRefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+
+	user := mustCreateUser(t, m, "cheems@example.com")
+	if _, err := m.CreateRefreshToken(ctx, CreateRefreshTokenParams{Token: "tok", UserID: user.ID}); err != nil {
+		t.Fatalf("CreateRefreshToken error: %v", err)
+	}
+	if _, err := m.CreateRefreshToken(ctx, CreateRefreshTokenParams{Token: "tok", UserID: user.ID}); !errors.Is(err, ErrUniqueViolation) {
+		t.Fatalf("got %v want %v", err, ErrUniqueViolation)
+	}
+
+	got, err := m.GetUserFromRefreshToken(ctx, "tok")
+	if err != nil {
+		t.Fatalf("GetUserFromRefreshToken error: %v", err)
+	}
+	if got.ID != user.ID {
+		t.Fatalf("got %s want %s", got.ID, user.ID)
+	}
+
+	revoke := SetTokenTimestampsParams{
+		RevokedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		UpdatedAt: time.Now(),
+		Token:     "tok",
+	}
+	if n, _ := m.SetTokenTimestamps(ctx, revoke); n != 1 {
+		t.Fatalf("first revoke affected %d rows want 1", n)
+	}
+	if n, _ := m.SetTokenTimestamps(ctx, revoke); n != 0 {
+		t.Fatalf("second revoke affected %d rows want 0", n)
+	}
+}
+
+func TestMemoryInTx(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	user := mustCreateUser(t, m, "cheems@example.com")
+
+	t.Run("failed transaction leaves no rows", func(t *testing.T) {
+		errBoom := errors.New("boom")
+		var chirp Chirp
+		err := m.InTx(ctx, func(tx Store) error {
+			var err error
+			chirp, err = tx.CreateChirp(ctx, CreateChirpParams{Body: "half done", UserID: user.ID})
+			if err != nil {
+				return err
+			}
+			// not visible outside the transaction until it commits
+			if _, err := m.GetChirpByID(ctx, chirp.ID); err != sql.ErrNoRows {
+				t.Errorf("uncommitted chirp outside the transaction: %v", err)
+			}
+			_, err = tx.UpdateUserChirpyRed(ctx, UpdateUserChirpyRedParams{ID: user.ID, IsChirpyRed: true})
+			if err != nil {
+				return err
+			}
+			return errBoom
+		})
+		if !errors.Is(err, errBoom) {
+			t.Fatalf("got %v want %v", err, errBoom)
+		}
+		if _, err := m.GetChirpByID(ctx, chirp.ID); err != sql.ErrNoRows {
+			t.Fatalf("chirp survived rollback: %v", err)
+		}
+		if got, _ := m.GetUserByID(ctx, user.ID); got.IsChirpyRed {
+			t.Fatalf("upgrade survived rollback")
+		}
+	})
+
+	t.Run("successful transaction commits", func(t *testing.T) {
+		var chirp Chirp
+		err := m.InTx(ctx, func(tx Store) error {
+			var err error
+			chirp, err = tx.CreateChirp(ctx, CreateChirpParams{Body: "all done", UserID: user.ID})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("InTx error: %v", err)
+		}
+		if _, err := m.GetChirpByID(ctx, chirp.ID); err != nil {
+			t.Fatalf("committed chirp: %v", err)
+		}
+	})
+
+	t.Run("writes outside the transaction survive it", func(t *testing.T) {
+		var other User
+		err := m.InTx(ctx, func(tx Store) error {
+			other = mustCreateUser(t, m, "doge@example.com")
+			_, err := tx.CreateChirp(ctx, CreateChirpParams{Body: "meanwhile", UserID: user.ID})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("InTx error: %v", err)
+		}
+		if _, err := m.GetUserByID(ctx, other.ID); err != nil {
+			t.Fatalf("user created outside the transaction: %v", err)
+		}
+	})
+}
+
+func mustCreateUser(t *testing.T, m *Memory, email string) User {
+	t.Helper()
+	user, err := m.CreateUser(context.Background(), CreateUserParams{Email: email, HashedPassword: "hash"})
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	return user
+}
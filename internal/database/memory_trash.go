@@ -0,0 +1,244 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// trash.sql
+
+func (m *Memory) DeleteUserChirps(ctx context.Context, arg DeleteUserChirpsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deletedAt := arg.DeletedAt
+	for _, chirp := range m.chirps {
+		if chirp.DeletedAt == nil && m.ownOrRechirpOfOwn(chirp, arg.UserID) {
+			chirp.DeletedAt = &deletedAt
+			m.chirps[chirp.ID] = chirp
+		}
+	}
+	return nil
+}
+
+func (m *Memory) GetTrashedChirp(ctx context.Context, arg GetTrashedChirpParams) (Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	chirp, ok := m.chirps[arg.ID]
+	if !ok || chirp.UserID != arg.UserID || !m.inTrash(chirp, arg.Since) {
+		return Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}
+
+func (m *Memory) ListTrash(ctx context.Context, arg ListTrashParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	compare := func(a, b Chirp) int {
+		if c := a.DeletedAt.Compare(*b.DeletedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	}
+	after := Chirp{ID: arg.AfterID.UUID, DeletedAt: &arg.AfterDeletedAt.Time}
+
+	var items []Chirp
+	for _, chirp := range m.chirps {
+		if chirp.UserID != arg.UserID || !m.inTrash(chirp, arg.Since) {
+			continue
+		}
+		if arg.AfterDeletedAt.Valid && compare(chirp, after) >= 0 {
+			continue
+		}
+		items = append(items, chirp)
+	}
+	slices.SortFunc(items, func(a, b Chirp) int { return compare(b, a) })
+	if len(items) > int(arg.PageSize) {
+		items = items[:arg.PageSize]
+	}
+	return items, nil
+}
+
+func (m *Memory) PurgeDeletedChirps(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for id, chirp := range m.chirps {
+		if chirp.DeletedAt != nil && chirp.DeletedAt.Before(before) {
+			m.deleteChirp(id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *Memory) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for id, user := range m.users {
+		if user.DeletedAt.Valid && user.DeletedAt.Time.Before(before) {
+			m.deleteUser(id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *Memory) RestoreChirp(ctx context.Context, arg RestoreChirpParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.restoreChirps(arg.DeletedAt, func(chirp Chirp) bool {
+		return chirp.ID == arg.ID || chirp.RechirpOfID.Valid && chirp.RechirpOfID.UUID == arg.ID
+	})
+}
+
+func (m *Memory) RestoreUserChirps(ctx context.Context, arg RestoreUserChirpsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.restoreChirps(arg.DeletedAt, func(chirp Chirp) bool {
+		return m.ownOrRechirpOfOwn(chirp, arg.UserID)
+	})
+}
+
+// restoreChirps takes the matching chirps deleted at deletedAt out of the
+// trash. Like the UPDATE, it restores none of them if a restored rechirp
+// would duplicate a live one. Callers must hold the write lock.
+func (m *Memory) restoreChirps(deletedAt time.Time, match func(Chirp) bool) error {
+	var restored []Chirp
+	for _, chirp := range m.chirps {
+		if chirp.DeletedAt == nil || !chirp.DeletedAt.Equal(deletedAt) || !match(chirp) {
+			continue
+		}
+		if chirp.RechirpOfID.Valid && m.rechirped(chirp.UserID, chirp.RechirpOfID.UUID) {
+			return ErrUniqueViolation
+		}
+		restored = append(restored, chirp)
+	}
+	for _, chirp := range restored {
+		chirp.DeletedAt = nil
+		m.chirps[chirp.ID] = chirp
+	}
+	return nil
+}
+
+// inTrash reports whether chirp was deleted after since, and isn't a rechirp
+// of a chirp that's been deleted too. Callers must hold m.mu.
+func (m *Memory) inTrash(chirp Chirp, since time.Time) bool {
+	if chirp.DeletedAt == nil || !chirp.DeletedAt.After(since) {
+		return false
+	}
+	if chirp.RechirpOfID.Valid {
+		_, ok := m.liveChirp(chirp.RechirpOfID.UUID)
+		return ok
+	}
+	return true
+}
+
+// ownOrRechirpOfOwn reports whether chirp is one of userID's, or a rechirp of
+// one of theirs. Callers must hold m.mu.
+func (m *Memory) ownOrRechirpOfOwn(chirp Chirp, userID uuid.UUID) bool {
+	if chirp.UserID == userID {
+		return true
+	}
+	return chirp.RechirpOfID.Valid && m.chirps[chirp.RechirpOfID.UUID].UserID == userID
+}
+
+// rechirped reports whether userID already has a live rechirp of chirpID,
+// which the partial unique index allows only once. Callers must hold m.mu.
+func (m *Memory) rechirped(userID, chirpID uuid.UUID) bool {
+	for _, chirp := range m.chirps {
+		if chirp.DeletedAt == nil && chirp.UserID == userID && chirp.RechirpOfID.Valid && chirp.RechirpOfID.UUID == chirpID {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteUser removes a user and applies the ON DELETE CASCADE of every
+// reference to them. Callers must hold m.mu.
+func (m *Memory) deleteUser(id uuid.UUID) {
+	delete(m.users, id)
+	for chirpID, chirp := range m.chirps {
+		if chirp.UserID == id {
+			m.deleteChirp(chirpID)
+		}
+	}
+	for draftID, draft := range m.drafts {
+		if draft.UserID == id {
+			delete(m.drafts, draftID)
+		}
+	}
+	for mediumID, medium := range m.media {
+		if medium.UserID == id {
+			delete(m.media, mediumID)
+		}
+	}
+	for token, refToken := range m.tokens {
+		if refToken.UserID == id {
+			delete(m.tokens, token)
+		}
+	}
+	for key := range m.follows {
+		if key.follower == id || key.followee == id {
+			delete(m.follows, key)
+		}
+	}
+	for key := range m.likes {
+		if key.user == id {
+			delete(m.likes, key)
+		}
+	}
+	for key := range m.pollVotes {
+		if key.user == id {
+			delete(m.pollVotes, key)
+		}
+	}
+	for key, mention := range m.mentions {
+		if mention.UserID == id {
+			delete(m.mentions, key)
+		}
+	}
+	for nID, n := range m.notifications {
+		if n.UserID == id {
+			m.deleteNotification(nID)
+		}
+	}
+	for key := range m.notificationActors {
+		if key.actor == id {
+			delete(m.notificationActors, key)
+		}
+	}
+	for key := range m.notificationPrefs {
+		if key.user == id {
+			delete(m.notificationPrefs, key)
+		}
+	}
+	for changeID, change := range m.redStatusChanges {
+		if change.UserID == id {
+			delete(m.redStatusChanges, changeID)
+		}
+	}
+	for endpointID, endpoint := range m.webhooks {
+		if endpoint.UserID != (uuid.NullUUID{UUID: id, Valid: true}) {
+			continue
+		}
+		delete(m.webhooks, endpointID)
+		for deliveryID, d := range m.deliveries {
+			if d.EndpointID == endpointID {
+				delete(m.deliveries, deliveryID)
+			}
+		}
+	}
+}
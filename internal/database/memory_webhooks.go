@@ -0,0 +1,315 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// webhooks.sql
+
+func (m *Memory) ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	var items []WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.Status == "pending" && !d.NextAttemptAt.After(now) && m.webhooks[d.EndpointID].Enabled {
+			items = append(items, d)
+		}
+	}
+	slices.SortFunc(items, func(a, b WebhookDelivery) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+	if len(items) > int(arg.BatchSize) {
+		items = items[:arg.BatchSize]
+	}
+	for i := range items {
+		items[i].Attempts++
+		items[i].NextAttemptAt = arg.LockedUntil
+		items[i].LastAttemptAt = sql.NullTime{Time: now, Valid: true}
+		m.deliveries[items[i].ID] = items[i]
+	}
+	return items, nil
+}
+
+func (m *Memory) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.webhooks[arg.EndpointID]; !ok {
+		return 0, ErrForeignKeyViolation
+	}
+	for _, d := range m.deliveries {
+		if d.EndpointID == arg.EndpointID && d.EventID == arg.EventID {
+			return 0, nil
+		}
+	}
+	now := m.now()
+	d := WebhookDelivery{
+		ID:            uuid.New(),
+		EndpointID:    arg.EndpointID,
+		EventID:       arg.EventID,
+		EventType:     arg.EventType,
+		Payload:       json.RawMessage(arg.Payload),
+		Status:        "pending",
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	m.deliveries[d.ID] = d
+	return 1, nil
+}
+
+func (m *Memory) CreateWebhookEndpoint(ctx context.Context, arg CreateWebhookEndpointParams) (WebhookEndpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID.UUID]; arg.UserID.Valid && !ok {
+		return WebhookEndpoint{}, ErrForeignKeyViolation
+	}
+	now := m.now()
+	endpoint := WebhookEndpoint{
+		ID:        uuid.New(),
+		UserID:    arg.UserID,
+		Url:       arg.Url,
+		Secret:    arg.Secret,
+		Events:    slices.Clone(arg.Events),
+		Enabled:   true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	m.webhooks[endpoint.ID] = endpoint
+	return endpoint, nil
+}
+
+func (m *Memory) DeleteFinishedWebhookDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for id, d := range m.deliveries {
+		last := d.CreatedAt
+		if d.LastAttemptAt.Valid {
+			last = d.LastAttemptAt.Time
+		}
+		if d.Status != "pending" && last.Before(before) {
+			delete(m.deliveries, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *Memory) DeleteWebhookEndpoint(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.webhooks, id)
+	for deliveryID, d := range m.deliveries {
+		if d.EndpointID == id {
+			delete(m.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) FinishWebhookDelivery(ctx context.Context, arg FinishWebhookDeliveryParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d, ok := m.deliveries[arg.ID]
+	if !ok {
+		return nil
+	}
+	d.Status = arg.Status
+	d.NextAttemptAt = arg.NextAttemptAt
+	d.ResponseStatus = arg.ResponseStatus
+	d.LastError = arg.LastError
+	m.deliveries[arg.ID] = d
+	return nil
+}
+
+func (m *Memory) GetWebhookDelivery(ctx context.Context, id uuid.UUID) (WebhookDelivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	d, ok := m.deliveries[id]
+	if !ok {
+		return WebhookDelivery{}, sql.ErrNoRows
+	}
+	return d, nil
+}
+
+func (m *Memory) GetWebhookEndpoint(ctx context.Context, id uuid.UUID) (WebhookEndpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	endpoint, ok := m.webhooks[id]
+	if !ok {
+		return WebhookEndpoint{}, sql.ErrNoRows
+	}
+	return endpoint, nil
+}
+
+func (m *Memory) ListAdminWebhookEndpoints(ctx context.Context) ([]WebhookEndpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.listWebhooks(func(endpoint WebhookEndpoint) bool {
+		return !endpoint.UserID.Valid
+	}), nil
+}
+
+func (m *Memory) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	compare := func(a, b WebhookDelivery) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	}
+	after := WebhookDelivery{CreatedAt: arg.AfterCreatedAt.Time, ID: arg.AfterID.UUID}
+	var items []WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.EndpointID != arg.EndpointID {
+			continue
+		}
+		if arg.AfterCreatedAt.Valid && compare(d, after) >= 0 {
+			continue
+		}
+		items = append(items, d)
+	}
+	// newest first
+	slices.SortFunc(items, func(a, b WebhookDelivery) int { return compare(b, a) })
+	if len(items) > int(arg.PageSize) {
+		items = items[:arg.PageSize]
+	}
+	return items, nil
+}
+
+func (m *Memory) ListWebhookEndpoints(ctx context.Context, userID uuid.UUID) ([]WebhookEndpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.listWebhooks(func(endpoint WebhookEndpoint) bool {
+		return endpoint.UserID.Valid && endpoint.UserID.UUID == userID
+	}), nil
+}
+
+func (m *Memory) ListWebhookEndpointsForEvent(ctx context.Context, arg ListWebhookEndpointsForEventParams) ([]WebhookEndpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.listWebhooks(func(endpoint WebhookEndpoint) bool {
+		if !endpoint.Enabled || !slices.Contains(endpoint.Events, arg.EventType) {
+			return false
+		}
+		if !endpoint.UserID.Valid {
+			return true
+		}
+		_, live := m.liveUser(endpoint.UserID.UUID)
+		return live && endpoint.UserID.UUID == arg.UserID
+	}), nil
+}
+
+func (m *Memory) RecordWebhookFailure(ctx context.Context, arg RecordWebhookFailureParams) (WebhookEndpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	endpoint, ok := m.webhooks[arg.ID]
+	if !ok {
+		return WebhookEndpoint{}, sql.ErrNoRows
+	}
+	endpoint.FailureCount++
+	if endpoint.Enabled && endpoint.FailureCount >= arg.DisableAfter {
+		endpoint.Enabled = false
+		endpoint.DisabledAt = sql.NullTime{Time: m.now(), Valid: true}
+	}
+	m.webhooks[arg.ID] = endpoint
+	return endpoint, nil
+}
+
+func (m *Memory) RecordWebhookSuccess(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	endpoint, ok := m.webhooks[id]
+	if !ok {
+		return nil
+	}
+	endpoint.FailureCount = 0
+	m.webhooks[id] = endpoint
+	return nil
+}
+
+func (m *Memory) RedeliverWebhookDelivery(ctx context.Context, id uuid.UUID) (WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d, ok := m.deliveries[id]
+	if !ok {
+		return WebhookDelivery{}, sql.ErrNoRows
+	}
+	d.Status = "pending"
+	d.Attempts = 0
+	d.NextAttemptAt = m.now()
+	m.deliveries[id] = d
+	return d, nil
+}
+
+func (m *Memory) UpdateWebhookEndpoint(ctx context.Context, arg UpdateWebhookEndpointParams) (WebhookEndpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	endpoint, ok := m.webhooks[arg.ID]
+	if !ok {
+		return WebhookEndpoint{}, sql.ErrNoRows
+	}
+	if arg.Url.Valid {
+		endpoint.Url = arg.Url.String
+	}
+	if arg.Events != nil {
+		endpoint.Events = slices.Clone(arg.Events)
+	}
+	if arg.Enabled.Valid {
+		endpoint.Enabled = arg.Enabled.Bool
+		if arg.Enabled.Bool {
+			endpoint.FailureCount = 0
+			endpoint.DisabledAt = sql.NullTime{}
+		} else if !endpoint.DisabledAt.Valid {
+			endpoint.DisabledAt = sql.NullTime{Time: m.now(), Valid: true}
+		}
+	}
+	endpoint.UpdatedAt = m.now()
+	m.webhooks[arg.ID] = endpoint
+	return endpoint, nil
+}
+
+// listWebhooks returns the endpoints match accepts, oldest first. Callers
+// must hold m.mu.
+func (m *Memory) listWebhooks(match func(WebhookEndpoint) bool) []WebhookEndpoint {
+	var items []WebhookEndpoint
+	for _, endpoint := range m.webhooks {
+		if match(endpoint) {
+			items = append(items, endpoint)
+		}
+	}
+	slices.SortFunc(items, func(a, b WebhookEndpoint) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+	return items
+}
@@ -6,17 +6,154 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type BannedWord struct {
+	Word      string    `json:"word"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Chirp struct {
+	ID          uuid.UUID     `json:"id"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	Body        string        `json:"body"`
+	UserID      uuid.UUID     `json:"user_id"`
+	ParentID    uuid.NullUUID `json:"parent_id"`
+	RootID      uuid.NullUUID `json:"root_id"`
+	RechirpOfID uuid.NullUUID `json:"rechirp_of_id"`
+	QuoteOfID   uuid.NullUUID `json:"quote_of_id"`
+	DeletedAt   *time.Time    `json:"deleted_at"`
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID `json:"id"`
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+type ChirpTag struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	TagID     uuid.UUID `json:"tag_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Draft struct {
+	ID            uuid.UUID      `json:"id"`
+	UserID        uuid.UUID      `json:"user_id"`
+	Body          string         `json:"body"`
+	ParentID      uuid.NullUUID  `json:"parent_id"`
+	QuoteOfID     uuid.NullUUID  `json:"quote_of_id"`
+	PublishAt     sql.NullTime   `json:"publish_at"`
+	LastError     sql.NullString `json:"last_error"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	Attempts      int32          `json:"attempts"`
+	NextAttemptAt sql.NullTime   `json:"next_attempt_at"`
+}
+
+type Follow struct {
+	FollowerID uuid.UUID `json:"follower_id"`
+	FolloweeID uuid.UUID `json:"followee_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Like struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Medium struct {
+	ID          uuid.UUID     `json:"id"`
+	UserID      uuid.UUID     `json:"user_id"`
+	ChirpID     uuid.NullUUID `json:"chirp_id"`
+	Position    int32         `json:"position"`
+	ContentType string        `json:"content_type"`
+	Width       int32         `json:"width"`
+	Height      int32         `json:"height"`
+	SizeBytes   int64         `json:"size_bytes"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+type Mention struct {
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	StartIndex int32     `json:"start_index"`
+	EndIndex   int32     `json:"end_index"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ModerationFlag struct {
 	ID        uuid.UUID `json:"id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Reason    string    `json:"reason"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
+}
+
+type Notification struct {
+	ID        uuid.UUID     `json:"id"`
+	UserID    uuid.UUID     `json:"user_id"`
+	Type      string        `json:"type"`
+	ChirpID   uuid.NullUUID `json:"chirp_id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	ReadAt    sql.NullTime  `json:"read_at"`
+}
+
+type NotificationActor struct {
+	NotificationID uuid.UUID `json:"notification_id"`
+	ActorID        uuid.UUID `json:"actor_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type NotificationPreference struct {
 	UserID    uuid.UUID `json:"user_id"`
+	Type      string    `json:"type"`
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type OutboxEvent struct {
+	ID            uuid.UUID       `json:"id"`
+	Type          string          `json:"type"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Attempts      int32           `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	DeliveredAt   sql.NullTime    `json:"delivered_at"`
+	LastError     sql.NullString  `json:"last_error"`
+}
+
+type Poll struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Options   []string  `json:"options"`
+	ClosesAt  time.Time `json:"closes_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PollVote struct {
+	ChirpID     uuid.UUID `json:"chirp_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	OptionIndex int32     `json:"option_index"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type RedStatusChange struct {
+	ID             uuid.UUID      `json:"id"`
+	UserID         uuid.UUID      `json:"user_id"`
+	IsChirpyRed    bool           `json:"is_chirpy_red"`
+	Reason         string         `json:"reason"`
+	WebhookEventID sql.NullString `json:"webhook_event_id"`
+	EffectiveAt    time.Time      `json:"effective_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+	ExpiresAt      sql.NullTime   `json:"expires_at"`
 }
 
 type RefreshToken struct {
@@ -28,11 +165,58 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime `json:"revoked_at"`
 }
 
+type Tag struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type User struct {
-	ID             uuid.UUID `json:"id"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	Email          string    `json:"email"`
-	HashedPassword string    `json:"hashed_password"`
-	IsChirpyRed    bool      `json:"is_chirpy_red"`
+	ID             uuid.UUID      `json:"id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	Email          string         `json:"email"`
+	HashedPassword string         `json:"hashed_password"`
+	IsChirpyRed    bool           `json:"is_chirpy_red"`
+	Username       sql.NullString `json:"username"`
+	DisplayName    string         `json:"display_name"`
+	Bio            string         `json:"bio"`
+	AvatarUrl      string         `json:"avatar_url"`
+	RedExpiresAt   sql.NullTime   `json:"red_expires_at"`
+	DeletedAt      sql.NullTime   `json:"deleted_at"`
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	EndpointID     uuid.UUID       `json:"endpoint_id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastAttemptAt  sql.NullTime    `json:"last_attempt_at"`
+	ResponseStatus sql.NullInt32   `json:"response_status"`
+	LastError      sql.NullString  `json:"last_error"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type WebhookEndpoint struct {
+	ID           uuid.UUID     `json:"id"`
+	UserID       uuid.NullUUID `json:"user_id"`
+	Url          string        `json:"url"`
+	Secret       string        `json:"secret"`
+	Events       []string      `json:"events"`
+	Enabled      bool          `json:"enabled"`
+	FailureCount int32         `json:"failure_count"`
+	DisabledAt   sql.NullTime  `json:"disabled_at"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+type WebhookEvent struct {
+	ID         string    `json:"id"`
+	Source     string    `json:"source"`
+	Type       string    `json:"type"`
+	ReceivedAt time.Time `json:"received_at"`
 }
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Store is every query the HTTP handlers rely on. *Queries implements it on
+// top of Postgres and *Memory implements it in-process for tests and demos,
+// so handlers never depend on the concrete sqlc type.
+type Store interface {
+	// InTx runs fn against a Store whose queries all happen in one
+	// transaction, committed if fn returns nil and rolled back otherwise.
+	InTx(ctx context.Context, fn func(Store) error) error
+
+	// chirps.sql
+	CountChirpReplies(ctx context.Context, parentIds []uuid.UUID) ([]CountChirpRepliesRow, error)
+	CountRechirps(ctx context.Context, chirpIds []uuid.UUID) ([]CountRechirpsRow, error)
+	CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error)
+	DeleteChirpByID(ctx context.Context, id uuid.UUID) error
+	GetAllChirps(ctx context.Context) ([]Chirp, error)
+	GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
+	GetChirpThread(ctx context.Context, arg GetChirpThreadParams) ([]GetChirpThreadRow, error)
+	GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error)
+	GetUserRechirp(ctx context.Context, arg GetUserRechirpParams) (Chirp, error)
+	ListChirpReplies(ctx context.Context, arg ListChirpRepliesParams) ([]Chirp, error)
+	ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error)
+	ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error)
+	UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error)
+
+	// drafts.sql
+	ClaimDueDraft(ctx context.Context, now time.Time) (Draft, error)
+	CreateDraft(ctx context.Context, arg CreateDraftParams) (Draft, error)
+	DeleteDraft(ctx context.Context, arg DeleteDraftParams) (int64, error)
+	FailDraft(ctx context.Context, arg FailDraftParams) error
+	GetDraft(ctx context.Context, arg GetDraftParams) (Draft, error)
+	ListDrafts(ctx context.Context, arg ListDraftsParams) ([]Draft, error)
+	LockDraft(ctx context.Context, arg LockDraftParams) (Draft, error)
+	RetryDraft(ctx context.Context, arg RetryDraftParams) error
+	UpdateDraft(ctx context.Context, arg UpdateDraftParams) (Draft, error)
+
+	// follows.sql
+	FollowUser(ctx context.Context, arg FollowUserParams) (int64, error)
+	ListFolloweeIDs(ctx context.Context, followerID uuid.UUID) ([]uuid.UUID, error)
+	ListFollowers(ctx context.Context, arg ListFollowersParams) ([]ListFollowersRow, error)
+	ListFollowing(ctx context.Context, arg ListFollowingParams) ([]ListFollowingRow, error)
+	ListTimeline(ctx context.Context, arg ListTimelineParams) ([]Chirp, error)
+	UnfollowUser(ctx context.Context, arg UnfollowUserParams) (int64, error)
+
+	// likes.sql
+	CountChirpLikes(ctx context.Context, chirpIds []uuid.UUID) ([]CountChirpLikesRow, error)
+	LikeChirp(ctx context.Context, arg LikeChirpParams) (int64, error)
+	ListLikedChirpIDs(ctx context.Context, arg ListLikedChirpIDsParams) ([]uuid.UUID, error)
+	ListUserLikes(ctx context.Context, arg ListUserLikesParams) ([]ListUserLikesRow, error)
+	UnlikeChirp(ctx context.Context, arg UnlikeChirpParams) (int64, error)
+
+	// media.sql
+	AttachMedia(ctx context.Context, arg AttachMediaParams) (int64, error)
+	CountPendingMedia(ctx context.Context, userID uuid.UUID) (int64, error)
+	CreateMedia(ctx context.Context, arg CreateMediaParams) (Medium, error)
+	GetMedia(ctx context.Context, id uuid.UUID) (Medium, error)
+	ListChirpMedia(ctx context.Context, chirpIds []uuid.UUID) ([]Medium, error)
+	PurgeMedia(ctx context.Context, arg PurgeMediaParams) ([]Medium, error)
+
+	// mentions.sql
+	CreateMention(ctx context.Context, arg CreateMentionParams) error
+	DeleteChirpMentions(ctx context.Context, chirpID uuid.UUID) error
+	ListChirpMentions(ctx context.Context, chirpIds []uuid.UUID) ([]ListChirpMentionsRow, error)
+	ListUserMentions(ctx context.Context, arg ListUserMentionsParams) ([]Chirp, error)
+
+	// moderation.sql
+	AddBannedWord(ctx context.Context, word string) (int64, error)
+	CreateModerationFlag(ctx context.Context, arg CreateModerationFlagParams) (ModerationFlag, error)
+	DeleteBannedWord(ctx context.Context, word string) (int64, error)
+	ListBannedWords(ctx context.Context) ([]string, error)
+	ListModerationFlags(ctx context.Context, arg ListModerationFlagsParams) ([]ModerationFlag, error)
+
+	// notifications.sql
+	AddNotificationActor(ctx context.Context, arg AddNotificationActorParams) (int64, error)
+	CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error)
+	ListNotificationActors(ctx context.Context, notificationIds []uuid.UUID) ([]NotificationActor, error)
+	ListNotificationPreferences(ctx context.Context, userID uuid.UUID) ([]NotificationPreference, error)
+	ListNotifications(ctx context.Context, arg ListNotificationsParams) ([]Notification, error)
+	MarkNotificationsRead(ctx context.Context, arg MarkNotificationsReadParams) (int64, error)
+	SetNotificationPreference(ctx context.Context, arg SetNotificationPreferenceParams) error
+	TouchNotification(ctx context.Context, id uuid.UUID) error
+	UpsertNotification(ctx context.Context, arg UpsertNotificationParams) (Notification, error)
+
+	// outbox.sql
+	ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]OutboxEvent, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (OutboxEvent, error)
+	DeleteDeliveredOutboxEventsBefore(ctx context.Context, before time.Time) (int64, error)
+	MarkOutboxEventDelivered(ctx context.Context, id uuid.UUID) error
+	MarkOutboxEventFailed(ctx context.Context, arg MarkOutboxEventFailedParams) error
+
+	// polka.sql
+	CreateRedStatusChange(ctx context.Context, arg CreateRedStatusChangeParams) (RedStatusChange, error)
+	GetLatestRedStatusChange(ctx context.Context, userID uuid.UUID) (RedStatusChange, error)
+	ListRedStatusChanges(ctx context.Context, userID uuid.UUID) ([]RedStatusChange, error)
+	RecordWebhookEvent(ctx context.Context, arg RecordWebhookEventParams) (int64, error)
+
+	// polls.sql
+	CountPollVotes(ctx context.Context, chirpIds []uuid.UUID) ([]CountPollVotesRow, error)
+	CreatePoll(ctx context.Context, arg CreatePollParams) (Poll, error)
+	CreatePollVote(ctx context.Context, arg CreatePollVoteParams) error
+	GetPoll(ctx context.Context, chirpID uuid.UUID) (Poll, error)
+	ListPolls(ctx context.Context, chirpIds []uuid.UUID) ([]Poll, error)
+	ListUserPollVotes(ctx context.Context, arg ListUserPollVotesParams) ([]ListUserPollVotesRow, error)
+
+	// revisions.sql
+	CreateChirpRevision(ctx context.Context, arg CreateChirpRevisionParams) (ChirpRevision, error)
+	ListChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]ChirpRevision, error)
+
+	// tags.sql
+	ListTagChirps(ctx context.Context, arg ListTagChirpsParams) ([]Chirp, error)
+	ListTrendingTags(ctx context.Context, arg ListTrendingTagsParams) ([]ListTrendingTagsRow, error)
+	TagChirp(ctx context.Context, arg TagChirpParams) error
+	UntagChirp(ctx context.Context, arg UntagChirpParams) error
+	UpsertTag(ctx context.Context, name string) (Tag, error)
+
+	// trash.sql
+	DeleteUserChirps(ctx context.Context, arg DeleteUserChirpsParams) error
+	GetTrashedChirp(ctx context.Context, arg GetTrashedChirpParams) (Chirp, error)
+	ListTrash(ctx context.Context, arg ListTrashParams) ([]Chirp, error)
+	PurgeDeletedChirps(ctx context.Context, before time.Time) (int64, error)
+	PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error)
+	RestoreChirp(ctx context.Context, arg RestoreChirpParams) error
+	RestoreUserChirps(ctx context.Context, arg RestoreUserChirpsParams) error
+
+	// tokens.sql
+	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error)
+	GetTokenByTokenValue(ctx context.Context, token string) (RefreshToken, error)
+	GetUserFromRefreshToken(ctx context.Context, token string) (User, error)
+	RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error
+	SetTokenTimestamps(ctx context.Context, arg SetTokenTimestampsParams) (int64, error)
+
+	// users.sql
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	DeleteAllUsers(ctx context.Context) error
+	DeleteUser(ctx context.Context, id uuid.UUID) (User, error)
+	GetUserAndHashPassByEmail(ctx context.Context, email string) (User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
+	GetUserByUsername(ctx context.Context, username string) (User, error)
+	GetUserCounts(ctx context.Context, userID uuid.UUID) (GetUserCountsRow, error)
+	GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error)
+	GetUsersByUsernames(ctx context.Context, usernames []string) ([]User, error)
+	RestoreUser(ctx context.Context, id uuid.UUID) (User, error)
+	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserChirpyRed(ctx context.Context, arg UpdateUserChirpyRedParams) (User, error)
+
+	// webhooks.sql
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (int64, error)
+	CreateWebhookEndpoint(ctx context.Context, arg CreateWebhookEndpointParams) (WebhookEndpoint, error)
+	DeleteFinishedWebhookDeliveriesBefore(ctx context.Context, before time.Time) (int64, error)
+	DeleteWebhookEndpoint(ctx context.Context, id uuid.UUID) error
+	FinishWebhookDelivery(ctx context.Context, arg FinishWebhookDeliveryParams) error
+	GetWebhookDelivery(ctx context.Context, id uuid.UUID) (WebhookDelivery, error)
+	GetWebhookEndpoint(ctx context.Context, id uuid.UUID) (WebhookEndpoint, error)
+	ListAdminWebhookEndpoints(ctx context.Context) ([]WebhookEndpoint, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhookEndpoints(ctx context.Context, userID uuid.UUID) ([]WebhookEndpoint, error)
+	ListWebhookEndpointsForEvent(ctx context.Context, arg ListWebhookEndpointsForEventParams) ([]WebhookEndpoint, error)
+	RecordWebhookFailure(ctx context.Context, arg RecordWebhookFailureParams) (WebhookEndpoint, error)
+	RecordWebhookSuccess(ctx context.Context, id uuid.UUID) error
+	RedeliverWebhookDelivery(ctx context.Context, id uuid.UUID) (WebhookDelivery, error)
+	UpdateWebhookEndpoint(ctx context.Context, arg UpdateWebhookEndpointParams) (WebhookEndpoint, error)
+}
+
+var (
+	_ Store = (*Queries)(nil)
+	_ Store = (*Memory)(nil)
+)
+
+// InTx begins a transaction on the *sql.DB q was created with. Queries that
+// are already running in a transaction run fn in that one.
+func (q *Queries) InTx(ctx context.Context, fn func(Store) error) error {
+	db, ok := q.db.(*sql.DB)
+	if !ok {
+		return fn(q)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// a no-op once committed
+	defer tx.Rollback()
+
+	if err := fn(q.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -66,11 +66,11 @@ func (q *Queries) GetTokenByTokenValue(ctx context.Context, token string) (Refre
 }
 
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red 
+SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red, users.username, users.display_name, users.bio, users.avatar_url, users.red_expires_at, users.deleted_at 
 FROM refresh_tokens
 JOIN users
 ON users.id = refresh_tokens.user_id
-WHERE token = $1
+WHERE token = $1 AND users.deleted_at IS NULL
 `
 
 func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (User, error) {
@@ -83,10 +83,27 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (Us
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.RedExpiresAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserRefreshTokens, userID)
+	return err
+}
+
 const setTokenTimestamps = `-- name: SetTokenTimestamps :execrows
 UPDATE refresh_tokens
 SET revoked_at = $1, updated_at = $2
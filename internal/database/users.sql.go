@@ -7,29 +7,33 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users(id, created_at, updated_at, email, hashed_password)
+INSERT INTO users(id, created_at, updated_at, email, hashed_password, username)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, display_name, bio, avatar_url, red_expires_at, deleted_at
 `
 
 type CreateUserParams struct {
-	Email          string `json:"email"`
-	HashedPassword string `json:"hashed_password"`
+	Email          string         `json:"email"`
+	HashedPassword string         `json:"hashed_password"`
+	Username       sql.NullString `json:"username"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Username)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -38,6 +42,12 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.RedExpiresAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -51,8 +61,34 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 	return err
 }
 
+const deleteUser = `-- name: DeleteUser :one
+UPDATE users SET deleted_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, display_name, bio, avatar_url, red_expires_at, deleted_at
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, deleteUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.RedExpiresAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
 const getUserAndHashPassByEmail = `-- name: GetUserAndHashPassByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, display_name, bio, avatar_url, red_expires_at, deleted_at
 FROM users
 WHERE email = $1
 `
@@ -67,25 +103,234 @@ func (q *Queries) GetUserAndHashPassByEmail(ctx context.Context, email string) (
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.RedExpiresAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, display_name, bio, avatar_url, red_expires_at, deleted_at
+FROM users
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.RedExpiresAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getUserByUsername = `-- name: GetUserByUsername :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, display_name, bio, avatar_url, red_expires_at, deleted_at
+FROM users
+WHERE LOWER(username) = LOWER($1) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByUsername, username)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.RedExpiresAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getUserCounts = `-- name: GetUserCounts :one
+SELECT
+    (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = $1 AND chirps.deleted_at IS NULL) AS chirp_count,
+    (SELECT COUNT(*) FROM follows JOIN users ON users.id = follows.follower_id WHERE follows.followee_id = $1 AND users.deleted_at IS NULL) AS follower_count,
+    (SELECT COUNT(*) FROM follows JOIN users ON users.id = follows.followee_id WHERE follows.follower_id = $1 AND users.deleted_at IS NULL) AS following_count
+`
+
+type GetUserCountsRow struct {
+	ChirpCount     int64 `json:"chirp_count"`
+	FollowerCount  int64 `json:"follower_count"`
+	FollowingCount int64 `json:"following_count"`
+}
+
+func (q *Queries) GetUserCounts(ctx context.Context, userID uuid.UUID) (GetUserCountsRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserCounts, userID)
+	var i GetUserCountsRow
+	err := row.Scan(
+		&i.ChirpCount,
+		&i.FollowerCount,
+		&i.FollowingCount,
+	)
+	return i, err
+}
+
+const getUsersByIDs = `-- name: GetUsersByIDs :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, display_name, bio, avatar_url, red_expires_at, deleted_at
+FROM users
+WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.Username,
+			&i.DisplayName,
+			&i.Bio,
+			&i.AvatarUrl,
+			&i.RedExpiresAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsersByUsernames = `-- name: GetUsersByUsernames :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, display_name, bio, avatar_url, red_expires_at, deleted_at
+FROM users
+WHERE LOWER(username) = ANY($1::text[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUsersByUsernames(ctx context.Context, usernames []string) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByUsernames, pq.Array(usernames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.Username,
+			&i.DisplayName,
+			&i.Bio,
+			&i.AvatarUrl,
+			&i.RedExpiresAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreUser = `-- name: RestoreUser :one
+UPDATE users SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, display_name, bio, avatar_url, red_expires_at, deleted_at
+`
+
+func (q *Queries) RestoreUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, restoreUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.RedExpiresAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
-SET email = $1, hashed_password = $2
-WHERE id = $3
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SET email = $1,
+    hashed_password = $2,
+    username = COALESCE($3, username),
+    display_name = COALESCE($4, display_name),
+    bio = COALESCE($5, bio),
+    avatar_url = COALESCE($6, avatar_url)
+WHERE id = $7 AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, display_name, bio, avatar_url, red_expires_at, deleted_at
 `
 
 type UpdateUserParams struct {
-	Email          string    `json:"email"`
-	HashedPassword string    `json:"hashed_password"`
-	ID             uuid.UUID `json:"id"`
+	Email          string         `json:"email"`
+	HashedPassword string         `json:"hashed_password"`
+	Username       sql.NullString `json:"username"`
+	DisplayName    sql.NullString `json:"display_name"`
+	Bio            sql.NullString `json:"bio"`
+	AvatarUrl      sql.NullString `json:"avatar_url"`
+	ID             uuid.UUID      `json:"id"`
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.Email, arg.HashedPassword, arg.ID)
+	row := q.db.QueryRowContext(ctx, updateUser,
+		arg.Email,
+		arg.HashedPassword,
+		arg.Username,
+		arg.DisplayName,
+		arg.Bio,
+		arg.AvatarUrl,
+		arg.ID,
+	)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -94,19 +339,31 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.RedExpiresAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const updateUserToRedByID = `-- name: UpdateUserToRedByID :one
+const updateUserChirpyRed = `-- name: UpdateUserChirpyRed :one
 UPDATE users
-SET is_chirpy_red = true 
+SET is_chirpy_red = $2, red_expires_at = $3
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, display_name, bio, avatar_url, red_expires_at, deleted_at
 `
 
-func (q *Queries) UpdateUserToRedByID(ctx context.Context, id uuid.UUID) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUserToRedByID, id)
+type UpdateUserChirpyRedParams struct {
+	ID           uuid.UUID    `json:"id"`
+	IsChirpyRed  bool         `json:"is_chirpy_red"`
+	RedExpiresAt sql.NullTime `json:"red_expires_at"`
+}
+
+func (q *Queries) UpdateUserChirpyRed(ctx context.Context, arg UpdateUserChirpyRedParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserChirpyRed, arg.ID, arg.IsChirpyRed, arg.RedExpiresAt)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -115,6 +372,12 @@ func (q *Queries) UpdateUserToRedByID(ctx context.Context, id uuid.UUID) (User,
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.RedExpiresAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -0,0 +1,149 @@
+// Package entities finds the structured parts of a chirp body, such as
+// #hashtags and @mentions, so they can be indexed and linked.
+package entities
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxTagLength is the longest hashtag, in characters, that is recognised.
+const MaxTagLength = 100
+
+// MaxUsernameLength is the longest username, and so the longest @mention.
+const MaxUsernameLength = 15
+
+// Hashtag is a #tag in a body. Start and End are byte offsets of the whole
+// entity including the #, Tag is the normalized name without it.
+type Hashtag struct {
+	Start int
+	End   int
+	Tag   string
+}
+
+// Hashtags returns the hashtags in body in order of appearance. A hashtag is
+// a # that doesn't follow a word character, then letters, digits, marks or
+// underscores with at least one letter, so "#1" and "a#b" aren't tags.
+func Hashtags(body string) []Hashtag {
+	var tags []Hashtag
+	for i := 0; i < len(body); i++ {
+		if body[i] != '#' || (i > 0 && isWordRune(lastRune(body[:i]))) {
+			continue
+		}
+		end := i + 1
+		for end < len(body) {
+			r, size := utf8.DecodeRuneInString(body[end:])
+			if !isWordRune(r) {
+				break
+			}
+			end += size
+		}
+		if name, ok := NormalizeTag(body[i+1 : end]); ok {
+			tags = append(tags, Hashtag{Start: i, End: end, Tag: name})
+		}
+		i = end - 1
+	}
+	return tags
+}
+
+// NormalizeTag lowercases a tag name, with or without its leading #, and
+// reports whether it is a valid tag at all.
+func NormalizeTag(name string) (string, bool) {
+	name = strings.TrimPrefix(name, "#")
+	n := utf8.RuneCountInString(name)
+	if n == 0 || n > MaxTagLength {
+		return "", false
+	}
+	hasLetter := false
+	for _, r := range name {
+		if !isWordRune(r) {
+			return "", false
+		}
+		hasLetter = hasLetter || unicode.IsLetter(r)
+	}
+	if !hasLetter {
+		return "", false
+	}
+	return strings.ToLower(name), true
+}
+
+// UniqueTags is the set of tag names in hashtags, in order of appearance.
+func UniqueTags(hashtags []Hashtag) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, h := range hashtags {
+		if !seen[h.Tag] {
+			seen[h.Tag] = true
+			names = append(names, h.Tag)
+		}
+	}
+	return names
+}
+
+// Mention is an @username in a body. Start and End are byte offsets of the
+// whole entity including the @, Username is the name as written.
+type Mention struct {
+	Start    int
+	End      int
+	Username string
+}
+
+// Mentions returns the mentions in body in order of appearance. A mention is
+// an @ that doesn't follow a word character, then a valid username. A run of
+// name characters that is too long isn't cut short, it's not a mention, and
+// neither is the "@example" in "me@example.com".
+func Mentions(body string) []Mention {
+	var mentions []Mention
+	for i := 0; i < len(body); i++ {
+		if body[i] != '@' || (i > 0 && isWordRune(lastRune(body[:i]))) {
+			continue
+		}
+		end := i + 1
+		for end < len(body) && isUsernameByte(body[end]) {
+			end++
+		}
+		if end < len(body) && isWordRune(firstRune(body[end:])) {
+			// "@bobé" is not a mention of bob
+			i = end
+			continue
+		}
+		if ValidUsername(body[i+1 : end]) {
+			mentions = append(mentions, Mention{Start: i, End: end, Username: body[i+1 : end]})
+		}
+		i = end - 1
+	}
+	return mentions
+}
+
+// ValidUsername reports whether name is 1 to MaxUsernameLength ASCII letters,
+// digits or underscores.
+func ValidUsername(name string) bool {
+	if len(name) == 0 || len(name) > MaxUsernameLength {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isUsernameByte(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isUsernameByte(b byte) bool {
+	return b == '_' || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
@@ -0,0 +1,89 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Hashtag
+	}{
+		{name: "None", body: "no tags here"},
+		{
+			name: "Several",
+			body: "#Go and #golang_tips!",
+			want: []Hashtag{{Start: 0, End: 3, Tag: "go"}, {Start: 8, End: 20, Tag: "golang_tips"}},
+		},
+		{name: "Digits only", body: "#1 #2024"},
+		{name: "Inside a word", body: "issue#12 c#sharp"},
+		{name: "Unicode", body: "नमस्ते #हिंदी", want: []Hashtag{{Start: 19, End: 35, Tag: "हिंदी"}}},
+		{name: "Bare hash", body: "# #"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Hashtags(tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Hashtags(%q) = %+v, want %+v", tt.body, got, tt.want)
+			}
+			for _, h := range got {
+				if tag, _ := NormalizeTag(tt.body[h.Start:h.End]); tag != h.Tag {
+					t.Errorf("offsets %d:%d don't cover #%s", h.Start, h.End, h.Tag)
+				}
+			}
+		})
+	}
+}
+
+func TestUniqueTags(t *testing.T) {
+	got := UniqueTags(Hashtags("#go #Go #rust #GO"))
+	if !reflect.DeepEqual(got, []string{"go", "rust"}) {
+		t.Errorf("UniqueTags = %v", got)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Mention
+	}{
+		{name: "None", body: "nobody here"},
+		{
+			name: "Several",
+			body: "@Alice, meet @bob_2!",
+			want: []Mention{{Start: 0, End: 6, Username: "Alice"}, {Start: 13, End: 19, Username: "bob_2"}},
+		},
+		{name: "Email", body: "mail me@example.com"},
+		{name: "Too long", body: "@abcdefghijklmnop"},
+		{name: "Non-ASCII tail", body: "@bobé"},
+		{name: "Bare at", body: "@ @"},
+		{name: "After unicode", body: "नमस्ते @raj", want: []Mention{{Start: 19, End: 23, Username: "raj"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Mentions(tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mentions(%q) = %+v, want %+v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidUsername(t *testing.T) {
+	for name, want := range map[string]bool{
+		"bob":              true,
+		"Bob_99":           true,
+		"":                 false,
+		"has space":        false,
+		"abcdefghijklmno":  true,
+		"abcdefghijklmnop": false,
+		"josé":             false,
+	} {
+		if got := ValidUsername(name); got != want {
+			t.Errorf("ValidUsername(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Package entitlements decides what each user may do from the plan they're
+// on. A user is on Red while their Chirpy Red subscription is active, until
+// its expiry if it has one, and on Free otherwise. Which features each plan
+// includes is configuration, a Matrix, so handlers only ever ask whether a
+// user has a feature, never which plan they're on.
+package entitlements
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+)
+
+// Feature is something a plan can include.
+type Feature string
+
+const (
+	// LongChirps raises the chirp length limit.
+	LongChirps Feature = "long_chirps"
+	// EditChirps allows editing chirps after posting them.
+	EditChirps Feature = "edit_chirps"
+	// HigherRateLimits raises how many writes a user can make a minute.
+	HigherRateLimits Feature = "higher_rate_limits"
+	// ScheduledChirps allows posting chirps at a later time.
+	ScheduledChirps Feature = "scheduled_chirps"
+)
+
+// Features lists every Feature, in the order they're reported.
+var Features = []Feature{LongChirps, EditChirps, HigherRateLimits, ScheduledChirps}
+
+// Plan is what a user is subscribed to.
+type Plan string
+
+const (
+	Free Plan = "free"
+	Red  Plan = "red"
+)
+
+// Plans lists every Plan.
+var Plans = []Plan{Free, Red}
+
+// PlanOf returns the plan user is on at now. Chirpy Red ends at its expiry
+// whether or not Polka has told us yet.
+func PlanOf(user database.User, now time.Time) Plan {
+	if !user.IsChirpyRed {
+		return Free
+	}
+	if user.RedExpiresAt.Valid && !now.Before(user.RedExpiresAt.Time) {
+		return Free
+	}
+	return Red
+}
+
+// Matrix is the features each plan includes.
+type Matrix map[Plan][]Feature
+
+// DefaultMatrix gives Red every feature and Free none of them.
+func DefaultMatrix() Matrix {
+	return Matrix{
+		Free: nil,
+		Red:  slices.Clone(Features),
+	}
+}
+
+// ParseFeatures reads a comma separated list of features, as in
+// "long_chirps, edit_chirps".
+func ParseFeatures(s string) ([]Feature, error) {
+	var features []Feature
+	for name := range strings.SplitSeq(s, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+		f := Feature(name)
+		if !slices.Contains(Features, f) {
+			return nil, fmt.Errorf("unknown feature %q", name)
+		}
+		features = append(features, f)
+	}
+	return features, nil
+}
+
+// Entitlements answers what users may do under one Matrix.
+type Entitlements struct {
+	features map[Plan]map[Feature]bool
+
+	// now is swapped out in tests that need a fixed time.
+	now func() time.Time
+}
+
+func New(m Matrix) *Entitlements {
+	e := &Entitlements{
+		features: map[Plan]map[Feature]bool{},
+		now:      time.Now,
+	}
+	for plan, features := range m {
+		e.features[plan] = map[Feature]bool{}
+		for _, f := range features {
+			e.features[plan][f] = true
+		}
+	}
+	return e
+}
+
+// Plan returns the plan user is on right now.
+func (e *Entitlements) Plan(user database.User) Plan {
+	return PlanOf(user, e.now())
+}
+
+// Has reports whether user's plan includes f right now.
+func (e *Entitlements) Has(user database.User, f Feature) bool {
+	return e.features[e.Plan(user)][f]
+}
+
+// Features returns every feature user has right now, in Features order.
+func (e *Entitlements) Features(user database.User) []Feature {
+	features := []Feature{}
+	for _, f := range Features {
+		if e.Has(user, f) {
+			features = append(features, f)
+		}
+	}
+	return features
+}
@@ -0,0 +1,52 @@
+package entitlements
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+)
+
+func TestHas(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	e := New(Matrix{Free: {EditChirps}, Red: {EditChirps, LongChirps}})
+	e.now = func() time.Time { return now }
+
+	expiring := func(at time.Time) sql.NullTime { return sql.NullTime{Time: at, Valid: true} }
+	tests := []struct {
+		name string
+		user database.User
+		plan Plan
+		want []Feature
+	}{
+		{name: "free", user: database.User{}, plan: Free, want: []Feature{EditChirps}},
+		{name: "red", user: database.User{IsChirpyRed: true}, plan: Red, want: []Feature{LongChirps, EditChirps}},
+		{name: "red until later", user: database.User{IsChirpyRed: true, RedExpiresAt: expiring(now.Add(time.Hour))}, plan: Red, want: []Feature{LongChirps, EditChirps}},
+		{name: "red expired", user: database.User{IsChirpyRed: true, RedExpiresAt: expiring(now)}, plan: Free, want: []Feature{EditChirps}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.Plan(tt.user); got != tt.plan {
+				t.Errorf("Plan() = %q, want %q", got, tt.plan)
+			}
+			if got := e.Features(tt.user); !slices.Equal(got, tt.want) {
+				t.Errorf("Features() = %v, want %v", got, tt.want)
+			}
+			if e.Has(tt.user, ScheduledChirps) {
+				t.Errorf("Has(%q) = true for a feature no plan includes", ScheduledChirps)
+			}
+		})
+	}
+}
+
+func TestParseFeatures(t *testing.T) {
+	got, err := ParseFeatures(" Long_Chirps,, scheduled_chirps ")
+	if err != nil || !slices.Equal(got, []Feature{LongChirps, ScheduledChirps}) {
+		t.Fatalf("ParseFeatures() = %v, %v", got, err)
+	}
+	if _, err := ParseFeatures("long_chirps,teleport"); err == nil {
+		t.Fatal("ParseFeatures accepted an unknown feature")
+	}
+}
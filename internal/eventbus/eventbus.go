@@ -0,0 +1,170 @@
+// Package eventbus carries events between every instance of the server, so
+// something that happens on one instance, like a chirp being posted, can be
+// acted on by all of them, like pushing it to every open stream.
+//
+// Delivery is best effort. An event published while an instance is
+// disconnected from the bus never reaches that instance, so subscribers
+// must treat events as hints and the database as the truth.
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Event types published on the bus.
+const (
+	ChirpCreated  = "chirp.created"
+	ChirpUpdated  = "chirp.updated"
+	ChirpDeleted  = "chirp.deleted"
+	ChirpRestored = "chirp.restored"
+)
+
+// Event is something that happened. Data is its JSON payload, which depends
+// on Type.
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Handler is called for every event, on every instance including the one
+// that published it. Handlers run one at a time, in publish order, on a
+// goroutine of the bus's own, so one that waits on something holds up the
+// events after it but never the publisher. They must not publish.
+type Handler func(Event)
+
+// Bus delivers events to the handlers subscribed on every instance.
+type Bus interface {
+	Publish(ctx context.Context, e Event) error
+	// Subscribe adds h to this instance's handlers. The returned function
+	// removes it again.
+	Subscribe(h Handler) (unsubscribe func())
+	Close() error
+}
+
+// handlers is the set of subscribed handlers both buses share.
+type handlers struct {
+	mu     sync.Mutex
+	nextID int
+	byID   map[int]Handler
+}
+
+func (hs *handlers) Subscribe(h Handler) func() {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if hs.byID == nil {
+		hs.byID = map[int]Handler{}
+	}
+	id := hs.nextID
+	hs.nextID++
+	hs.byID[id] = h
+	return func() {
+		hs.mu.Lock()
+		defer hs.mu.Unlock()
+		delete(hs.byID, id)
+	}
+}
+
+// snapshot returns the handlers subscribed right now. They are called
+// without the lock held, so a handler can subscribe or unsubscribe, and
+// Subscribe doesn't wait on a slow one.
+func (hs *handlers) snapshot() []Handler {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	return slices.Collect(maps.Values(hs.byID))
+}
+
+// dispatch calls every handler in subscribed with e.
+func dispatch(e Event, subscribed []Handler) {
+	for _, h := range subscribed {
+		h(e)
+	}
+}
+
+// queueSize is how many events Local holds for its handlers before Publish
+// waits for them to catch up.
+const queueSize = 256
+
+// ErrClosed is returned by Publish on a Local bus that has been closed.
+var ErrClosed = errors.New("eventbus: closed")
+
+// Local is a Bus for a single instance. Publish queues each event, with the
+// handlers subscribed at the time, for a goroutine that hands it to them.
+// Events too large for Postgres are refused here as well, so they don't only
+// go missing in production.
+type Local struct {
+	handlers
+	queue     chan queued
+	closed    chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// queued is an event waiting for the handlers that were subscribed when it
+// was published.
+type queued struct {
+	event    Event
+	handlers []Handler
+}
+
+func NewLocal() *Local {
+	l := &Local{
+		queue:  make(chan queued, queueSize),
+		closed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+func (l *Local) Publish(ctx context.Context, e Event) error {
+	if _, err := encode(e); err != nil {
+		return err
+	}
+	select {
+	case <-l.closed:
+		return ErrClosed
+	default:
+	}
+	select {
+	case l.queue <- queued{event: e, handlers: l.snapshot()}:
+		return nil
+	case <-l.closed:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run hands queued events to their handlers until the bus is closed, and
+// then whatever was queued before that.
+func (l *Local) run() {
+	defer close(l.done)
+	for {
+		select {
+		case q := <-l.queue:
+			dispatch(q.event, q.handlers)
+		case <-l.closed:
+			for {
+				select {
+				case q := <-l.queue:
+					dispatch(q.event, q.handlers)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// Close stops taking events, and returns once the ones already published
+// have been handled.
+func (l *Local) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	<-l.done
+	return nil
+}
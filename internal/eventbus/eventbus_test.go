@@ -0,0 +1,47 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	bus := NewLocal()
+	var first, second []string
+	unsubscribe := bus.Subscribe(func(e Event) { first = append(first, e.Type+" "+string(e.Data)) })
+	bus.Subscribe(func(e Event) { second = append(second, e.Type) })
+	// a handler can unsubscribe itself without waiting on the bus
+	var once func()
+	once = bus.Subscribe(func(e Event) { once() })
+
+	publish := func(eventType, data string) {
+		t.Helper()
+		if err := bus.Publish(context.Background(), Event{Type: eventType, Data: json.RawMessage(data)}); err != nil {
+			t.Fatalf("Publish error: %v", err)
+		}
+	}
+	publish(ChirpCreated, `{"id":1}`)
+	unsubscribe()
+	publish(ChirpDeleted, `{}`)
+
+	// what wouldn't fit in a notification isn't published here either
+	big := Event{Type: ChirpCreated, Data: json.RawMessage(`"` + strings.Repeat("a", maxPayloadBytes) + `"`)}
+	if err := bus.Publish(context.Background(), big); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Publish of a %d byte event error = %v, want ErrTooLarge", len(big.Data), err)
+	}
+
+	// closing waits for the handlers to catch up
+	bus.Close()
+	if len(first) != 1 || first[0] != `chirp.created {"id":1}` {
+		t.Errorf("first handler got %q", first)
+	}
+	if len(second) != 2 || second[1] != ChirpDeleted {
+		t.Errorf("second handler got %q", second)
+	}
+	if err := bus.Publish(context.Background(), Event{Type: ChirpCreated}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Publish after Close error = %v, want ErrClosed", err)
+	}
+}
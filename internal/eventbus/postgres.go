@@ -0,0 +1,100 @@
+package eventbus
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Channel is the Postgres notification channel events are sent on.
+const Channel = "chirpy_events"
+
+// maxPayloadBytes is the largest NOTIFY payload Postgres accepts by default,
+// less one byte for its terminator.
+const maxPayloadBytes = 7999
+
+// ErrTooLarge is returned for events too big to fit in a notification.
+var ErrTooLarge = errors.New("eventbus: event too large")
+
+// Postgres is a Bus shared by every instance using the same database. Events
+// are sent with NOTIFY and received on a dedicated LISTEN connection, which
+// is re-established if it drops; events sent in the meantime are lost.
+type Postgres struct {
+	handlers
+	db       *sql.DB
+	listener *pq.Listener
+	done     chan struct{}
+}
+
+// NewPostgres publishes through db and listens on a connection of its own to
+// dbURL, which should be the database db is connected to.
+func NewPostgres(db *sql.DB, dbURL string) (*Postgres, error) {
+	listener := pq.NewListener(dbURL, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Event bus connection: %v", err)
+		}
+	})
+	if err := listener.Listen(Channel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	p := &Postgres{
+		db:       db,
+		listener: listener,
+		done:     make(chan struct{}),
+	}
+	go p.receive()
+	return p, nil
+}
+
+// Publish sends e to every listening instance, this one included.
+func (p *Postgres) Publish(ctx context.Context, e Event) error {
+	payload, err := encode(e)
+	if err != nil {
+		return err
+	}
+	_, err = p.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", Channel, string(payload))
+	return err
+}
+
+// encode turns e into a notification payload, or fails with ErrTooLarge.
+func encode(e Event) ([]byte, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > maxPayloadBytes {
+		return nil, ErrTooLarge
+	}
+	return payload, nil
+}
+
+// Close stops listening. It doesn't close db.
+func (p *Postgres) Close() error {
+	err := p.listener.Close()
+	<-p.done
+	return err
+}
+
+func (p *Postgres) receive() {
+	defer close(p.done)
+	for n := range p.listener.Notify {
+		if n == nil {
+			// the listener reconnected, whatever was sent while it was
+			// down is gone
+			log.Print("Event bus reconnected, events may have been missed")
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal([]byte(n.Extra), &e); err != nil {
+			log.Printf("Event bus: dropping malformed event: %v", err)
+			continue
+		}
+		dispatch(e, p.snapshot())
+	}
+}
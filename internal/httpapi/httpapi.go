@@ -0,0 +1,170 @@
+// Package httpapi is the JSON contract every handler shares: errors go out
+// in one envelope with a machine-readable code, request bodies are decoded
+// strictly, and responses are written the same way everywhere.
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/Cheemx/chirpy/internal/validation"
+)
+
+// Error codes sent in every error response. Clients can switch on these, the
+// messages next to them are for people and may change.
+const (
+	CodeInvalidJSON          = "invalid_json"
+	CodeUnknownField         = "unknown_field"
+	CodeBodyTooLarge         = "body_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeInvalidParameter     = "invalid_parameter"
+	CodeValidationFailed     = "validation_failed"
+	CodeContentRejected      = "content_rejected"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeConflict             = "conflict"
+	CodeRateLimited          = "rate_limited"
+	CodeInternal             = "internal_error"
+)
+
+// MaxBodyBytes caps every JSON request body.
+const MaxBodyBytes = 1 << 20
+
+// Error is an error that knows how it should be reported to the client.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Fields  []validation.FieldError
+	// Err is the underlying cause. It is logged, never sent to the client.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func BadRequest(code, message string) *Error {
+	return &Error{Status: 400, Code: code, Message: message}
+}
+
+func Unauthorized(err error) *Error {
+	return &Error{Status: 401, Code: CodeUnauthorized, Message: "Missing or invalid credentials", Err: err}
+}
+
+func Forbidden(message string) *Error {
+	return &Error{Status: 403, Code: CodeForbidden, Message: message}
+}
+
+func NotFound(message string) *Error {
+	return &Error{Status: 404, Code: CodeNotFound, Message: message}
+}
+
+func Conflict(message string) *Error {
+	return &Error{Status: 409, Code: CodeConflict, Message: message}
+}
+
+func InternalError(err error) *Error {
+	return &Error{Status: 500, Code: CodeInternal, Message: "Something went wrong", Err: err}
+}
+
+// ValidationFailed reports field errors, using the first one as the message.
+func ValidationFailed(errs validation.Errors) *Error {
+	return &Error{Status: 400, Code: CodeValidationFailed, Message: errs[0].Message, Fields: errs}
+}
+
+// RespondWithJSON writes payload as the response body.
+func RespondWithJSON(w http.ResponseWriter, status int, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshalling JSON: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// Responding!
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// RespondWithError writes err as an error response. Anything that isn't an
+// *Error is reported as a 500 without leaking its details.
+func RespondWithError(w http.ResponseWriter, err error) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		apiErr = InternalError(err)
+	}
+	log.Printf("%d %s: %v", apiErr.Status, apiErr.Code, apiErr)
+
+	// error is where clients found the message before there were codes, so
+	// it stays a plain string
+	RespondWithJSON(w, apiErr.Status, struct {
+		Error  string                  `json:"error"`
+		Code   string                  `json:"code"`
+		Fields []validation.FieldError `json:"errors,omitempty"`
+	}{
+		Error:  apiErr.Message,
+		Code:   apiErr.Code,
+		Fields: apiErr.Fields,
+	})
+}
+
+// DecodeJSON strictly decodes the request body into v: it must be a single
+// JSON object of at most MaxBodyBytes with no fields v doesn't know about.
+// A missing Content-Type is accepted, anything but application/json isn't.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || mediaType != "application/json" {
+			return &Error{Status: 415, Code: CodeUnsupportedMediaType, Message: "Content-Type must be application/json"}
+		}
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err == nil {
+		// anything after the object is an error too
+		if err = decoder.Decode(&struct{}{}); err == io.EOF {
+			return nil
+		}
+		if err == nil {
+			return BadRequest(CodeInvalidJSON, "Request body must be a single JSON object")
+		}
+	}
+
+	var (
+		maxBytesErr  *http.MaxBytesError
+		syntaxErr    *json.SyntaxError
+		unmarshalErr *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return &Error{Status: 413, Code: CodeBodyTooLarge, Message: fmt.Sprintf("Request body must be at most %d bytes", maxBytesErr.Limit)}
+	case errors.Is(err, io.EOF):
+		return BadRequest(CodeInvalidJSON, "Request body is empty")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return BadRequest(CodeInvalidJSON, "Request body is not valid JSON")
+	case errors.As(err, &unmarshalErr):
+		return BadRequest(CodeInvalidJSON, fmt.Sprintf("Field %q must be a %s", unmarshalErr.Field, unmarshalErr.Type))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return BadRequest(CodeUnknownField, "Unknown field "+field)
+	}
+	return BadRequest(CodeInvalidJSON, "Request body is not valid JSON")
+}
@@ -0,0 +1,93 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Cheemx/chirpy/internal/validation"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		code        string
+	}{
+		{name: "Valid", contentType: "application/json; charset=utf-8", body: `{"body": "hi"}`},
+		{name: "No content type", body: `{"body": "hi"}`},
+		{name: "Empty", body: ``, status: 400, code: CodeInvalidJSON},
+		{name: "Malformed", body: `{"body": `, status: 400, code: CodeInvalidJSON},
+		{name: "Wrong type", body: `{"body": 1}`, status: 400, code: CodeInvalidJSON},
+		{name: "Unknown field", body: `{"bdy": "hi"}`, status: 400, code: CodeUnknownField},
+		{name: "Trailing data", body: `{"body": "hi"} {}`, status: 400, code: CodeInvalidJSON},
+		{name: "Wrong content type", contentType: "text/plain", body: `{"body": "hi"}`, status: 415, code: CodeUnsupportedMediaType},
+		{name: "Too large", body: `{"body": "` + strings.Repeat("a", MaxBodyBytes) + `"}`, status: 413, code: CodeBodyTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			var v struct {
+				Body string `json:"body"`
+			}
+			err := DecodeJSON(httptest.NewRecorder(), r, &v)
+			if tt.status == 0 {
+				if err != nil || v.Body != "hi" {
+					t.Fatalf("DecodeJSON() = %v, body %q; want nil, \"hi\"", err, v.Body)
+				}
+				return
+			}
+			var apiErr *Error
+			if !errors.As(err, &apiErr) || apiErr.Status != tt.status || apiErr.Code != tt.code {
+				t.Fatalf("DecodeJSON() = %#v, want %d %s", err, tt.status, tt.code)
+			}
+		})
+	}
+}
+
+func TestRespondWithError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		body   string
+	}{
+		{
+			name:   "API error",
+			err:    NotFound("Chirp not found"),
+			status: 404,
+			body:   `{"error":"Chirp not found","code":"not_found"}`,
+		},
+		{
+			name:   "Field errors",
+			err:    ValidationFailed(validation.Errors{{Field: "body", Code: validation.CodeRequired, Message: "body is required"}}),
+			status: 400,
+			body:   `{"error":"body is required","code":"validation_failed","errors":[{"field":"body","code":"required","message":"body is required"}]}`,
+		},
+		{
+			// the cause stays in the log
+			name:   "Anything else",
+			err:    errors.New("connection refused"),
+			status: 500,
+			body:   `{"error":"Something went wrong","code":"internal_error"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			RespondWithError(w, tt.err)
+			if w.Code != tt.status || w.Header().Get("Content-Type") != "application/json" {
+				t.Fatalf("got %d %q, want %d application/json", w.Code, w.Header().Get("Content-Type"), tt.status)
+			}
+			if got := w.Body.String(); got != tt.body {
+				t.Fatalf("body = %s, want %s", got, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// ErrNotFound is returned for keys a BlobStore has nothing under.
+var ErrNotFound = errors.New("media: blob not found")
+
+// BlobStore keeps the bytes of uploads under keys. It's modelled on object
+// stores such as S3, so a client for one can stand in for FSStore: keys are
+// flat names, and blobs are written whole and never changed.
+type BlobStore interface {
+	Put(ctx context.Context, key, contentType string, data io.Reader) error
+	// Get returns the blob under key, or ErrNotFound. The caller closes it.
+	Get(ctx context.Context, key string) (*Blob, error)
+	// Delete removes the blob under key, if there is one.
+	Delete(ctx context.Context, key string) error
+}
+
+// Blob is the content of a blob, with what is known about it.
+type Blob struct {
+	io.ReadCloser
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// validKey matches the keys FSStore accepts, which can't leave its
+// directory.
+var validKey = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// FSStore is a BlobStore keeping each blob as a file in one directory. The
+// content type comes back from the file's extension.
+type FSStore struct {
+	dir string
+}
+
+// NewFSStore keeps blobs in dir, creating it if needed.
+func NewFSStore(dir string) (*FSStore, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+	return &FSStore{dir: dir}, nil
+}
+
+func (s *FSStore) path(key string) (string, error) {
+	if !validKey.MatchString(key) {
+		return "", fmt.Errorf("media: invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
+
+// Put writes the blob to a temporary file first, so a reader never sees half
+// of it.
+func (s *FSStore) Put(ctx context.Context, key, contentType string, data io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *FSStore) Get(ctx context.Context, key string) (*Blob, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Blob{
+		ReadCloser:  f,
+		ContentType: mime.TypeByExtension(filepath.Ext(key)),
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+func (s *FSStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,168 @@
+// Package media turns uploaded images into what chirps can safely show:
+// re-encoded without their metadata, the right way up, with a thumbnail, and
+// kept in a BlobStore under keys served back through signed URLs.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// Types lists the content types Process accepts, as sniffed from the data
+// rather than taken from the client.
+var Types = []string{"image/jpeg", "image/png", "image/gif"}
+
+const (
+	// MaxPixels caps the pixels an image may decode to, over every frame of
+	// an animated GIF, so a small file can't take up gigabytes of memory.
+	MaxPixels = 40_000_000
+	// ThumbnailSize is the longest side of a thumbnail.
+	ThumbnailSize = 320
+)
+
+var (
+	ErrUnsupportedType = errors.New("media: unsupported content type")
+	ErrTooManyPixels   = errors.New("media: image is too large")
+	ErrInvalidImage    = errors.New("media: invalid image")
+)
+
+// Image is an upload ready to be stored.
+type Image struct {
+	ContentType string
+	Width       int
+	Height      int
+	// Data is the image re-encoded, which leaves out EXIF and every other
+	// kind of metadata.
+	Data []byte
+	// Thumbnail is a JPEG for JPEG images and a PNG for the others, which
+	// may be transparent.
+	Thumbnail     []byte
+	ThumbnailType string
+}
+
+// Process checks that data is an image of one of Types and gets it ready to
+// store. JPEGs are turned the way their EXIF orientation says before that is
+// stripped; animated GIFs keep their animation.
+func Process(data []byte) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	if !slices.Contains(Types, contentType) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	frames := 1
+	if contentType == "image/gif" {
+		frames, err = gifFrames(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+	}
+	pixels := config.Width * config.Height
+	if config.Width <= 0 || config.Height <= 0 || pixels > MaxPixels || frames > MaxPixels/pixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels, %d frames", ErrTooManyPixels, config.Width, config.Height, frames)
+	}
+	if frames == 0 {
+		return nil, fmt.Errorf("%w: GIF without frames", ErrInvalidImage)
+	}
+
+	img := &Image{ContentType: contentType, ThumbnailType: thumbnailType(contentType)}
+	var still image.Image
+	var buf bytes.Buffer
+	switch contentType {
+	case "image/jpeg":
+		decoded, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+		still = orient(decoded, jpegOrientation(data))
+		err = jpeg.Encode(&buf, still, &jpeg.Options{Quality: 90})
+		if err != nil {
+			return nil, err
+		}
+	case "image/png":
+		still, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+		err = png.Encode(&buf, still)
+		if err != nil {
+			return nil, err
+		}
+	case "image/gif":
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+		// only the frames, delays, disposal and loop count are written back
+		err = gif.EncodeAll(&buf, g)
+		if err != nil {
+			return nil, err
+		}
+		still = firstFrame(g)
+	}
+	img.Data = buf.Bytes()
+	img.Width, img.Height = still.Bounds().Dx(), still.Bounds().Dy()
+
+	var thumb bytes.Buffer
+	small := thumbnail(still, ThumbnailSize)
+	if img.ThumbnailType == "image/jpeg" {
+		err = jpeg.Encode(&thumb, small, &jpeg.Options{Quality: 80})
+	} else {
+		err = png.Encode(&thumb, small)
+	}
+	if err != nil {
+		return nil, err
+	}
+	img.Thumbnail = thumb.Bytes()
+	return img, nil
+}
+
+// firstFrame is the first frame of g drawn on its full canvas, since a
+// frame may only cover part of it.
+func firstFrame(g *gif.GIF) image.Image {
+	canvas := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	frame := g.Image[0]
+	draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+	return canvas
+}
+
+// Key is the blob key of the image uploaded as id.
+func Key(id uuid.UUID, contentType string) string {
+	return id.String() + extension(contentType)
+}
+
+// ThumbnailKey is the blob key of the thumbnail of the image uploaded as id.
+func ThumbnailKey(id uuid.UUID, contentType string) string {
+	return id.String() + "_thumb" + extension(thumbnailType(contentType))
+}
+
+func thumbnailType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+func extension(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	}
+	return ""
+}
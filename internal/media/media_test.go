@@ -0,0 +1,279 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// halves is a w by h image, red on the left and blue on the right.
+func halves(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// withOrientation inserts an EXIF segment with the given orientation, and a
+// camera model to be stripped, after the SOI marker of a JPEG.
+func withOrientation(t *testing.T, data []byte, orientation uint16) []byte {
+	t.Helper()
+	var tiff bytes.Buffer
+	write := func(values ...any) {
+		for _, v := range values {
+			binary.Write(&tiff, binary.BigEndian, v)
+		}
+	}
+	tiff.WriteString("MM")
+	write(uint16(42), uint32(8), uint16(2))
+	// Orientation, SHORT, 1 value
+	write(uint16(0x0112), uint16(3), uint32(1), orientation, uint16(0))
+	// Model, ASCII, 4 bytes inline
+	write(uint16(0x0110), uint16(2), uint32(4))
+	tiff.WriteString("Cam\x00")
+	write(uint32(0))
+
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	var out bytes.Buffer
+	out.Write(data[:2])
+	out.Write([]byte{0xFF, 0xE1})
+	binary.Write(&out, binary.BigEndian, uint16(len(segment)+2))
+	out.Write(segment)
+	out.Write(data[2:])
+	return out.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatalf("jpeg.Encode error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessJPEG(t *testing.T) {
+	data := withOrientation(t, encodeJPEG(t, halves(64, 32)), 6)
+	if got := jpegOrientation(data); got != 6 {
+		t.Fatalf("jpegOrientation = %d, want 6", got)
+	}
+
+	img, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process error: %v", err)
+	}
+	if img.ContentType != "image/jpeg" || img.ThumbnailType != "image/jpeg" {
+		t.Fatalf("types = %s, %s; want image/jpeg", img.ContentType, img.ThumbnailType)
+	}
+	if bytes.Contains(img.Data, []byte("Exif")) || bytes.Contains(img.Data, []byte("Cam")) {
+		t.Fatalf("processed JPEG still has its EXIF")
+	}
+
+	// turned clockwise: the red left half is now on top
+	if img.Width != 32 || img.Height != 64 {
+		t.Fatalf("size = %dx%d, want 32x64", img.Width, img.Height)
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatalf("decoding processed JPEG: %v", err)
+	}
+	if r, _, b, _ := decoded.At(16, 8).RGBA(); r < 0xC000 || b > 0x4000 {
+		t.Errorf("top pixel = %v, want red", decoded.At(16, 8))
+	}
+	if r, _, b, _ := decoded.At(16, 56).RGBA(); b < 0xC000 || r > 0x4000 {
+		t.Errorf("bottom pixel = %v, want blue", decoded.At(16, 56))
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// a 3x2 image with distinct pixels, and where each orientation shows
+	// the pixel at (0, 0) of the original
+	src := image.NewGray(image.Rect(0, 0, 3, 2))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i * 10)
+	}
+	tests := []struct {
+		orientation int
+		w, h        int
+		x, y        int
+	}{
+		{1, 3, 2, 0, 0},
+		{2, 3, 2, 2, 0},
+		{3, 3, 2, 2, 1},
+		{4, 3, 2, 0, 1},
+		{5, 2, 3, 0, 0},
+		{6, 2, 3, 1, 0},
+		{7, 2, 3, 1, 2},
+		{8, 2, 3, 0, 2},
+	}
+	for _, tt := range tests {
+		got := orient(src, tt.orientation)
+		if b := got.Bounds(); b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("orient(%d) size = %dx%d, want %dx%d", tt.orientation, b.Dx(), b.Dy(), tt.w, tt.h)
+			continue
+		}
+		if y, _, _, _ := got.At(tt.x, tt.y).RGBA(); y != 0 {
+			t.Errorf("orient(%d) at (%d, %d) = %v, want the original (0, 0)", tt.orientation, tt.x, tt.y, got.At(tt.x, tt.y))
+		}
+	}
+}
+
+func TestProcessPNGThumbnail(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, halves(640, 480)); err != nil {
+		t.Fatalf("png.Encode error: %v", err)
+	}
+	img, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Process error: %v", err)
+	}
+	thumb, err := png.Decode(bytes.NewReader(img.Thumbnail))
+	if err != nil || img.ThumbnailType != "image/png" {
+		t.Fatalf("thumbnail is not a PNG: %v", err)
+	}
+	if b := thumb.Bounds(); b.Dx() != ThumbnailSize || b.Dy() != 240 {
+		t.Fatalf("thumbnail size = %dx%d, want %dx240", b.Dx(), b.Dy(), ThumbnailSize)
+	}
+	if r, _, _, _ := thumb.At(10, 10).RGBA(); r != 0xFFFF {
+		t.Errorf("thumbnail pixel = %v, want red", thumb.At(10, 10))
+	}
+}
+
+func TestProcessGIF(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	anim := &gif.GIF{LoopCount: 0}
+	for i := range 2 {
+		frame := image.NewPaletted(image.Rect(0, 0, 8, 8), palette)
+		frame.Pix[0] = uint8(i)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatalf("gif.EncodeAll error: %v", err)
+	}
+	if n, err := gifFrames(buf.Bytes()); err != nil || n != 2 {
+		t.Fatalf("gifFrames = %d, %v; want 2, nil", n, err)
+	}
+
+	img, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Process error: %v", err)
+	}
+	got, err := gif.DecodeAll(bytes.NewReader(img.Data))
+	if err != nil || len(got.Image) != 2 {
+		t.Fatalf("processed GIF has %v frames, error %v; want 2", len(got.Image), err)
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	if _, err := Process([]byte("<html>not an image</html>")); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Process(html) error = %v, want ErrUnsupportedType", err)
+	}
+	// a GIF header claiming 7000x7000 pixels is turned away before decoding
+	huge := []byte("GIF89a\x58\x1b\x58\x1b\x00\x00\x00\x3b")
+	if _, err := Process(huge); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("Process(huge GIF) error = %v, want ErrTooManyPixels", err)
+	}
+	truncated := encodeJPEG(t, halves(16, 16))
+	if _, err := Process(truncated[:len(truncated)/2]); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("Process(truncated JPEG) error = %v, want ErrInvalidImage", err)
+	}
+}
+
+func TestFSStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFSStore error: %v", err)
+	}
+	key := Key(uuid.New(), "image/png")
+	if err := store.Put(ctx, key, "image/png", bytes.NewReader([]byte("pixels"))); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+	blob, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	data, _ := io.ReadAll(blob)
+	blob.Close()
+	if string(data) != "pixels" || blob.ContentType != "image/png" || blob.Size != 6 {
+		t.Fatalf("Get = %q, %s, %d", data, blob.ContentType, blob.Size)
+	}
+
+	for _, bad := range []string{"../escape.png", ".hidden", "a/b.png", ""} {
+		if err := store.Put(ctx, bad, "image/png", bytes.NewReader(nil)); err == nil {
+			t.Errorf("Put(%q) succeeded, want an error", bad)
+		}
+		if _, err := store.Get(ctx, bad); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) error = %v, want ErrNotFound", bad, err)
+		}
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete twice error: %v", err)
+	}
+}
+
+func TestSigner(t *testing.T) {
+	s := NewSigner("secret")
+	now := time.Now()
+	query := s.Sign("a.png", now.Add(time.Hour))
+
+	if err := s.Verify("a.png", query, now); err != nil {
+		t.Fatalf("Verify error: %v", err)
+	}
+	if err := s.Verify("b.png", query, now); err == nil {
+		t.Error("Verify accepted the signature for another key")
+	}
+	if err := NewSigner("other").Verify("a.png", query, now); err == nil {
+		t.Error("Verify accepted a signature made with another secret")
+	}
+	if err := s.Verify("a.png", query, now.Add(2*time.Hour)); err == nil {
+		t.Error("Verify accepted an expired URL")
+	}
+	later := url.Values{"expires": {"9999999999"}, "sig": query["sig"]}
+	if err := s.Verify("a.png", later, now); err == nil {
+		t.Error("Verify accepted a changed expiry")
+	}
+	if err := s.Verify("a.png", url.Values{}, now); err == nil {
+		t.Error("Verify accepted a URL without a signature")
+	}
+
+	// a derived key signs for itself, and not like the secret it came from
+	derived, err := DerivedSigner("secret")
+	if err != nil {
+		t.Fatalf("DerivedSigner error: %v", err)
+	}
+	if err := derived.Verify("a.png", derived.Sign("a.png", now.Add(time.Hour)), now); err != nil {
+		t.Fatalf("Verify with the derived key error: %v", err)
+	}
+	if err := derived.Verify("a.png", query, now); err == nil {
+		t.Error("the derived key accepted a signature made with the secret itself")
+	}
+}
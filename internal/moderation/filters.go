@@ -0,0 +1,186 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// WordList matches banned words regardless of case or the punctuation around
+// them, so "Kerfuffle!" matches "kerfuffle". It is safe for concurrent use and
+// can be edited while the server runs.
+type WordList struct {
+	mu    sync.RWMutex
+	words map[string]bool
+}
+
+func NewWordList(words ...string) *WordList {
+	l := &WordList{words: map[string]bool{}}
+	for _, w := range words {
+		l.Add(w)
+	}
+	return l
+}
+
+// ReadWords parses a word list file: one word per line, blank lines and lines
+// starting with # are ignored.
+func ReadWords(r io.Reader) ([]string, error) {
+	var words []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words, scanner.Err()
+}
+
+// NormalizeWord lowercases word and checks that it is a single word as Check
+// would tokenize it.
+func NormalizeWord(word string) (string, error) {
+	word = strings.ToLower(strings.TrimSpace(word))
+	spans := wordSpans(word)
+	if len(spans) != 1 || spans[0][0] != 0 || spans[0][1] != len(word) {
+		return "", fmt.Errorf("%q is not a single word", word)
+	}
+	return word, nil
+}
+
+// Add bans word. It reports false if word isn't a single word.
+func (l *WordList) Add(word string) bool {
+	word, err := NormalizeWord(word)
+	if err != nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.words[word] = true
+	return true
+}
+
+func (l *WordList) Remove(word string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.words, strings.ToLower(strings.TrimSpace(word)))
+}
+
+// Words returns the banned words in alphabetical order.
+func (l *WordList) Words() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	words := make([]string, 0, len(l.words))
+	for w := range l.words {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return words
+}
+
+func (l *WordList) Check(body string) []Match {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var matches []Match
+	for _, span := range wordSpans(body) {
+		if l.words[strings.ToLower(body[span[0]:span[1]])] {
+			matches = append(matches, Match{Start: span[0], End: span[1], Reason: "banned word"})
+		}
+	}
+	return matches
+}
+
+// wordSpans returns the byte offsets of every run of letters, digits and
+// combining marks in s.
+func wordSpans(s string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range s {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(s)})
+	}
+	return spans
+}
+
+// RegexFilter matches any of a set of regular expressions.
+type RegexFilter struct {
+	reason   string
+	patterns []*regexp.Regexp
+}
+
+// NewRegexFilter compiles patterns. reason is reported for every match.
+func NewRegexFilter(reason string, patterns ...string) (*RegexFilter, error) {
+	f := &RegexFilter{reason: reason}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		f.patterns = append(f.patterns, re)
+	}
+	return f, nil
+}
+
+func (f *RegexFilter) Check(body string) []Match {
+	var matches []Match
+	for _, re := range f.patterns {
+		for _, loc := range re.FindAllStringIndex(body, -1) {
+			matches = append(matches, Match{Start: loc[0], End: loc[1], Reason: f.reason})
+		}
+	}
+	return matches
+}
+
+// linkPattern finds links with or without a scheme; group 1 is the host.
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://)?((?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,})(?::\d+)?(?:/\S*)?`)
+
+// LinkBlocklist matches links to blocked domains and their subdomains.
+type LinkBlocklist struct {
+	domains []string
+}
+
+func NewLinkBlocklist(domains ...string) *LinkBlocklist {
+	f := &LinkBlocklist{}
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d != "" {
+			f.domains = append(f.domains, d)
+		}
+	}
+	return f
+}
+
+func (f *LinkBlocklist) Check(body string) []Match {
+	var matches []Match
+	for _, loc := range linkPattern.FindAllStringSubmatchIndex(body, -1) {
+		host := strings.ToLower(body[loc[2]:loc[3]])
+		if f.blocked(host) {
+			matches = append(matches, Match{Start: loc[0], End: loc[1], Reason: "blocked link"})
+		}
+	}
+	return matches
+}
+
+func (f *LinkBlocklist) blocked(host string) bool {
+	for _, d := range f.domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+// Package moderation decides what happens to a chirp body before it is
+// stored. A Pipeline runs a chain of Filters; each filter finds spans of the
+// body it objects to and the rule wrapping it says whether those spans are
+// masked, the chirp is rejected, or the chirp is flagged for review.
+package moderation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Action is what a Pipeline does with a filter's matches.
+type Action int
+
+const (
+	// Mask replaces every match with "****".
+	Mask Action = iota
+	// Reject refuses the chirp outright.
+	Reject
+	// Flag lets the chirp through unchanged but records it for review.
+	Flag
+)
+
+func (a Action) String() string {
+	switch a {
+	case Mask:
+		return "mask"
+	case Reject:
+		return "reject"
+	case Flag:
+		return "flag"
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+// ParseAction is the inverse of Action.String.
+func ParseAction(s string) (Action, error) {
+	switch strings.ToLower(s) {
+	case "mask":
+		return Mask, nil
+	case "reject":
+		return Reject, nil
+	case "flag":
+		return Flag, nil
+	}
+	return 0, fmt.Errorf("unknown moderation action %q", s)
+}
+
+const mask = "****"
+
+// Match is a span of the body, in byte offsets, that a filter objects to.
+type Match struct {
+	Start int
+	End   int
+	// Reason says why, e.g. "banned word" or "blocked link".
+	Reason string
+}
+
+// Filter finds the parts of a chirp body it objects to.
+type Filter interface {
+	Check(body string) []Match
+}
+
+// Rule pairs a filter with what to do when it matches.
+type Rule struct {
+	Filter Filter
+	Action Action
+}
+
+// Result is the outcome of running a body through a Pipeline.
+type Result struct {
+	// Body is the text to store, with masked spans replaced.
+	Body string
+	// Rejected is set when a Reject rule matched; Reason says why.
+	Rejected bool
+	Reason   string
+	// Flags holds the reasons of every Flag rule that matched.
+	Flags []string
+}
+
+// Pipeline runs a chain of rules in order.
+type Pipeline struct {
+	rules []Rule
+}
+
+func NewPipeline(rules ...Rule) *Pipeline {
+	return &Pipeline{rules: rules}
+}
+
+// Moderate runs body through every rule. A rejection stops the chain, masks
+// are applied before the next rule sees the body.
+func (p *Pipeline) Moderate(body string) Result {
+	res := Result{Body: body}
+	for _, rule := range p.rules {
+		matches := rule.Filter.Check(res.Body)
+		if len(matches) == 0 {
+			continue
+		}
+		switch rule.Action {
+		case Mask:
+			res.Body = applyMask(res.Body, matches)
+		case Reject:
+			res.Rejected = true
+			res.Reason = matches[0].Reason
+			return res
+		case Flag:
+			for _, m := range matches {
+				res.Flags = appendUnique(res.Flags, m.Reason)
+			}
+		}
+	}
+	return res
+}
+
+// applyMask replaces the matched spans of body, merging overlapping ones.
+func applyMask(body string, matches []Match) string {
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Start < matches[j].Start
+	})
+	var b strings.Builder
+	pos := 0
+	for _, m := range matches {
+		if m.Start < pos {
+			// overlaps the previous mask, extend it
+			pos = max(pos, m.End)
+			continue
+		}
+		b.WriteString(body[pos:m.Start])
+		b.WriteString(mask)
+		pos = m.End
+	}
+	b.WriteString(body[pos:])
+	return b.String()
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
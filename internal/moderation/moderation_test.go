@@ -0,0 +1,101 @@
+package moderation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWordList(t *testing.T) {
+	words := NewWordList("kerfuffle", "Sharbert")
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "Plain", body: "I had a kerfuffle today", want: "I had a **** today"},
+		{name: "Case and punctuation", body: "Kerfuffle! SHARBERT?", want: "****! ****?"},
+		{name: "Part of a longer word", body: "kerfuffles happen", want: "kerfuffles happen"},
+		{name: "Unicode neighbours", body: "«kerfuffle»", want: "«****»"},
+	}
+	p := NewPipeline(Rule{Filter: words, Action: Mask})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Moderate(tt.body).Body; got != tt.want {
+				t.Errorf("Moderate(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+
+	words.Remove("KERFUFFLE")
+	if !words.Add("fornax") || words.Add("two words") {
+		t.Fatal("Add should accept single words only")
+	}
+	if got := words.Words(); !reflect.DeepEqual(got, []string{"fornax", "sharbert"}) {
+		t.Errorf("Words() = %v", got)
+	}
+}
+
+func TestReadWords(t *testing.T) {
+	got, err := ReadWords(strings.NewReader("# banned\nkerfuffle\n\n  fornax  \n"))
+	if err != nil {
+		t.Fatalf("ReadWords error: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"kerfuffle", "fornax"}) {
+		t.Errorf("ReadWords = %v", got)
+	}
+}
+
+func TestLinkBlocklist(t *testing.T) {
+	f := NewLinkBlocklist("spam.example")
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{body: "see https://spam.example/deal", want: 1},
+		{body: "see www.SPAM.example and http://a.spam.example:8080", want: 2},
+		{body: "see notspam.example and spam.example.org", want: 0},
+	}
+	for _, tt := range tests {
+		if got := len(f.Check(tt.body)); got != tt.want {
+			t.Errorf("Check(%q) found %d links, want %d", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	words := NewWordList("kerfuffle")
+	phones, err := NewRegexFilter("phone number", `\d{3}-\d{4}`)
+	if err != nil {
+		t.Fatalf("NewRegexFilter error: %v", err)
+	}
+	p := NewPipeline(
+		Rule{Filter: words, Action: Mask},
+		Rule{Filter: phones, Action: Flag},
+		Rule{Filter: NewLinkBlocklist("spam.example"), Action: Reject},
+	)
+
+	res := p.Moderate("kerfuffle, call 555-1234")
+	want := Result{Body: "****, call 555-1234", Flags: []string{"phone number"}}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("Moderate = %+v, want %+v", res, want)
+	}
+
+	res = p.Moderate("kerfuffle at spam.example")
+	if !res.Rejected || res.Reason != "blocked link" {
+		t.Errorf("Moderate = %+v, want rejected for blocked link", res)
+	}
+
+	if _, err := NewRegexFilter("bad", `(`); err == nil {
+		t.Error("NewRegexFilter should reject invalid patterns")
+	}
+}
+
+func TestApplyMaskOverlaps(t *testing.T) {
+	got := applyMask("abcdefgh", []Match{{Start: 4, End: 6}, {Start: 1, End: 3}, {Start: 2, End: 5}})
+	if got != "a****gh" {
+		t.Errorf("applyMask = %q, want %q", got, "a****gh")
+	}
+}
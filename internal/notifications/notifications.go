@@ -0,0 +1,139 @@
+// Package notifications records what happens to a user's chirps and account,
+// such as replies, likes, follows and mentions, in their notification inbox.
+//
+// Notifications are aggregated as they are written: while a user hasn't read
+// it, one notification collects every actor of the same type on the same
+// chirp, so ten likes show up as "10 people liked your chirp" rather than ten
+// entries. Once it is read the next event starts a new one.
+//
+// Every event that adds to an inbox is also published to the recipient's
+// live subscriptions, see Notifier.Subscribe.
+package notifications
+
+import (
+	"context"
+
+	"github.com/Cheemx/chirpy/internal/broker"
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Type is what a notification is about.
+type Type string
+
+const (
+	Reply   Type = "reply"
+	Like    Type = "like"
+	Follow  Type = "follow"
+	Mention Type = "mention"
+)
+
+// Types lists every Type.
+var Types = []Type{Reply, Like, Follow, Mention}
+
+// Event is something Actor did that Recipient should hear about.
+type Event struct {
+	Type      Type
+	Recipient uuid.UUID
+	Actor     uuid.UUID
+	// ChirpID is the recipient's chirp that was replied to or liked, or the
+	// chirp that mentions them. It is uuid.Nil for follows.
+	ChirpID uuid.UUID
+	// NotificationID is the notification the event was recorded in. Notify
+	// fills it in before publishing.
+	NotificationID uuid.UUID
+}
+
+// liveBuffer is how many events a live subscriber may fall behind by before
+// it is dropped.
+const liveBuffer = 64
+
+// Store is the part of database.Store the Notifier needs.
+type Store interface {
+	AddNotificationActor(ctx context.Context, arg database.AddNotificationActorParams) (int64, error)
+	ListNotificationPreferences(ctx context.Context, userID uuid.UUID) ([]database.NotificationPreference, error)
+	TouchNotification(ctx context.Context, id uuid.UUID) error
+	UpsertNotification(ctx context.Context, arg database.UpsertNotificationParams) (database.Notification, error)
+}
+
+// Notifier writes events into their recipients' inboxes.
+type Notifier struct {
+	db     Store
+	events *broker.Broker[Event]
+}
+
+func NewNotifier(db Store) *Notifier {
+	return &Notifier{
+		db: db,
+		// live subscribers catch up from the inbox, not from a replay buffer
+		events: broker.New[Event](0, liveBuffer),
+	}
+}
+
+// Notify records e, unless the recipient is the actor or has turned the
+// type off. An actor already counted in the unread notification for e isn't
+// counted again, so liking, unliking and liking again notifies once.
+func (n *Notifier) Notify(ctx context.Context, e Event) error {
+	if e.Actor == e.Recipient {
+		return nil
+	}
+	prefs, err := n.Preferences(ctx, e.Recipient)
+	if err != nil {
+		return err
+	}
+	if !prefs[e.Type] {
+		return nil
+	}
+
+	params := database.UpsertNotificationParams{UserID: e.Recipient, Type: string(e.Type)}
+	if e.ChirpID != uuid.Nil {
+		params.ChirpID = uuid.NullUUID{UUID: e.ChirpID, Valid: true}
+	}
+	notification, err := n.db.UpsertNotification(ctx, params)
+	if err != nil {
+		return err
+	}
+	added, err := n.db.AddNotificationActor(ctx, database.AddNotificationActorParams{
+		NotificationID: notification.ID,
+		ActorID:        e.Actor,
+	})
+	if err != nil {
+		return err
+	}
+	if added == 0 {
+		return nil
+	}
+	// a new actor moves the notification back to the top of the inbox
+	if err := n.db.TouchNotification(ctx, notification.ID); err != nil {
+		return err
+	}
+	e.NotificationID = notification.ID
+	n.events.Publish(e)
+	return nil
+}
+
+// Subscribe delivers the events recorded for userID from now on. The
+// subscription is closed if its reader falls behind.
+func (n *Notifier) Subscribe(userID uuid.UUID) *broker.Subscription[Event] {
+	sub, _, _ := n.events.Subscribe(0, func(e Event) bool {
+		return e.Recipient == userID
+	})
+	return sub
+}
+
+// Preferences reports which types userID wants to be notified about. Types
+// the user never set are on.
+func (n *Notifier) Preferences(ctx context.Context, userID uuid.UUID) (map[Type]bool, error) {
+	rows, err := n.db.ListNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	prefs := map[Type]bool{}
+	for _, t := range Types {
+		prefs[t] = true
+	}
+	for _, row := range rows {
+		prefs[Type(row.Type)] = row.Enabled
+	}
+	return prefs, nil
+}
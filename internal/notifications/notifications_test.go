@@ -0,0 +1,123 @@
+package notifications
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestNotify(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemory()
+	newUser := func(email string) uuid.UUID {
+		t.Helper()
+		user, err := db.CreateUser(ctx, database.CreateUserParams{Email: email, HashedPassword: "x"})
+		if err != nil {
+			t.Fatalf("CreateUser error: %v", err)
+		}
+		return user.ID
+	}
+	owner, alice, bob := newUser("owner@example.com"), newUser("alice@example.com"), newUser("bob@example.com")
+	chirp, err := db.CreateChirp(ctx, database.CreateChirpParams{Body: "hi", UserID: owner})
+	if err != nil {
+		t.Fatalf("CreateChirp error: %v", err)
+	}
+
+	n := NewNotifier(db)
+	notify := func(e Event) {
+		t.Helper()
+		if err := n.Notify(ctx, e); err != nil {
+			t.Fatalf("Notify error: %v", err)
+		}
+	}
+	inbox := func() []database.Notification {
+		t.Helper()
+		items, err := db.ListNotifications(ctx, database.ListNotificationsParams{UserID: owner, PageSize: 10})
+		if err != nil {
+			t.Fatalf("ListNotifications error: %v", err)
+		}
+		return items
+	}
+	actorCount := func(id uuid.UUID) int {
+		t.Helper()
+		actors, err := db.ListNotificationActors(ctx, []uuid.UUID{id})
+		if err != nil {
+			t.Fatalf("ListNotificationActors error: %v", err)
+		}
+		return len(actors)
+	}
+
+	// two people liking, one of them twice, is one notification with two actors
+	notify(Event{Type: Like, Recipient: owner, Actor: alice, ChirpID: chirp.ID})
+	notify(Event{Type: Like, Recipient: owner, Actor: bob, ChirpID: chirp.ID})
+	notify(Event{Type: Like, Recipient: owner, Actor: alice, ChirpID: chirp.ID})
+	// liking your own chirp isn't news
+	notify(Event{Type: Like, Recipient: owner, Actor: owner, ChirpID: chirp.ID})
+	items := inbox()
+	if len(items) != 1 || actorCount(items[0].ID) != 2 {
+		t.Fatalf("inbox = %+v, want one like with 2 actors", items)
+	}
+
+	// after reading it, the next like starts a new notification
+	if _, err := db.MarkNotificationsRead(ctx, database.MarkNotificationsReadParams{UserID: owner}); err != nil {
+		t.Fatalf("MarkNotificationsRead error: %v", err)
+	}
+	notify(Event{Type: Like, Recipient: owner, Actor: bob, ChirpID: chirp.ID})
+	if items := inbox(); len(items) != 2 || items[0].ReadAt.Valid || actorCount(items[0].ID) != 1 {
+		t.Fatalf("inbox = %+v, want a new unread like first", items)
+	}
+
+	// turned off types are dropped
+	err = db.SetNotificationPreference(ctx, database.SetNotificationPreferenceParams{UserID: owner, Type: string(Follow), Enabled: false})
+	if err != nil {
+		t.Fatalf("SetNotificationPreference error: %v", err)
+	}
+	notify(Event{Type: Follow, Recipient: owner, Actor: alice})
+	if items := inbox(); len(items) != 2 {
+		t.Fatalf("inbox = %+v, want the follow dropped", items)
+	}
+	prefs, err := n.Preferences(ctx, owner)
+	if err != nil {
+		t.Fatalf("Preferences error: %v", err)
+	}
+	if prefs[Follow] || !prefs[Like] || !prefs[Reply] || !prefs[Mention] {
+		t.Fatalf("Preferences = %v, want only follow off", prefs)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemory()
+	owner, err := db.CreateUser(ctx, database.CreateUserParams{Email: "owner@example.com", HashedPassword: "x"})
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	fan, err := db.CreateUser(ctx, database.CreateUserParams{Email: "fan@example.com", HashedPassword: "x"})
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+
+	n := NewNotifier(db)
+	ownerSub, fanSub := n.Subscribe(owner.ID), n.Subscribe(fan.ID)
+	for range 2 {
+		if err := n.Notify(ctx, Event{Type: Follow, Recipient: owner.ID, Actor: fan.ID}); err != nil {
+			t.Fatalf("Notify error: %v", err)
+		}
+	}
+	ownerSub.Close()
+	fanSub.Close()
+
+	// only the recipient hears about it, and only once per actor
+	var got []Event
+	for msg := range ownerSub.Messages() {
+		got = append(got, msg.Value)
+	}
+	if len(got) != 1 || got[0].Actor != fan.ID || got[0].NotificationID == uuid.Nil {
+		t.Fatalf("owner got %+v, want one follow by fan", got)
+	}
+	if _, ok := <-fanSub.Messages(); ok {
+		t.Fatal("fan got an event about someone else's inbox")
+	}
+}
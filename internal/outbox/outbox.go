@@ -0,0 +1,231 @@
+// Package outbox delivers the domain events recorded in the outbox_events
+// table to the integrations that subscribe to them.
+//
+// An event is recorded with Record in the same transaction as the change it
+// describes, so it exists if and only if the change was committed. A Relay
+// then hands every event to every subscribed handler, retrying with
+// exponential backoff until they all succeed. Delivery is at least once: a
+// handler sees an event again when any handler failed on it, or the relay
+// crashed before marking it delivered, so handlers must be idempotent.
+// Events are delivered in the order they were recorded, except that one
+// being retried doesn't hold back those after it.
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Event types recorded in the outbox.
+const (
+	ChirpCreated   = "chirp.created"
+	ChirpUpdated   = "chirp.updated"
+	ChirpDeleted   = "chirp.deleted"
+	ChirpRestored  = "chirp.restored"
+	UserUpgraded   = "user.upgraded"
+	UserDowngraded = "user.downgraded"
+)
+
+const (
+	// pollInterval is how often the relay looks for due events when nothing
+	// wakes it sooner.
+	pollInterval = 5 * time.Second
+	batchSize    = 10
+	// handlerTimeout bounds each handler call, and lease how long claimed
+	// events are hidden from other relays. A whole batch has to fit in the
+	// lease, or another relay delivers the rest a second time.
+	handlerTimeout = 10 * time.Second
+	lease          = 5 * time.Minute
+	// MaxAttempts is how often an event is tried before the relay gives up
+	// on it. It stays in the table with its last error.
+	MaxAttempts = 15
+	maxBackoff  = time.Hour
+)
+
+// Event is a recorded event as handlers see it.
+type Event struct {
+	ID        uuid.UUID
+	Type      string
+	Payload   json.RawMessage
+	CreatedAt time.Time
+	// Attempt counts deliveries of the event, starting at 1.
+	Attempt int
+}
+
+// Handler delivers one event. An error makes the relay try again later.
+type Handler func(ctx context.Context, e Event) error
+
+// Record adds an event to the outbox. db should be the transaction making the
+// change the event is about.
+func Record(ctx context.Context, db database.Store, eventType string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = db.CreateOutboxEvent(ctx, database.CreateOutboxEventParams{Type: eventType, Payload: string(data)})
+	return err
+}
+
+// Store is the part of database.Store the Relay needs.
+type Store interface {
+	ClaimOutboxEvents(ctx context.Context, arg database.ClaimOutboxEventsParams) ([]database.OutboxEvent, error)
+	MarkOutboxEventDelivered(ctx context.Context, id uuid.UUID) error
+	MarkOutboxEventFailed(ctx context.Context, arg database.MarkOutboxEventFailedParams) error
+}
+
+// Relay delivers outbox events. Any number of relays, in any number of
+// processes, can share one database; each event is claimed by one of them
+// at a time.
+type Relay struct {
+	db   Store
+	wake chan struct{}
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+
+	// backoff is swapped out in tests that retry straight away.
+	backoff func(attempt int) time.Duration
+}
+
+func NewRelay(db Store) *Relay {
+	return &Relay{
+		db:       db,
+		wake:     make(chan struct{}, 1),
+		handlers: map[string]Handler{},
+		backoff:  backoff,
+	}
+}
+
+// Subscribe adds a handler for every event. name identifies it in errors.
+func (r *Relay) Subscribe(name string, h Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[name] = h
+}
+
+// Wake makes Run look for events now rather than at the next poll, e.g.
+// right after one was recorded.
+func (r *Relay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers events until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		// keep going while there's a backlog
+		for {
+			n, err := r.Deliver(ctx)
+			if err != nil {
+				log.Printf("Error relaying outbox events: %v", err)
+			}
+			if err != nil || n < batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// Deliver claims one batch of due events and hands each to the handlers. It
+// reports how many events it claimed.
+func (r *Relay) Deliver(ctx context.Context) (int, error) {
+	events, err := r.db.ClaimOutboxEvents(ctx, database.ClaimOutboxEventsParams{
+		LockedUntil: time.Now().UTC().Add(lease),
+		MaxAttempts: MaxAttempts,
+		BatchSize:   batchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+	// Postgres doesn't keep the order of UPDATE ... RETURNING
+	slices.SortFunc(events, func(a, b database.OutboxEvent) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+
+	r.mu.Lock()
+	handlers := maps.Clone(r.handlers)
+	r.mu.Unlock()
+	names := slices.Sorted(maps.Keys(handlers))
+
+	for _, row := range events {
+		e := Event{
+			ID:        row.ID,
+			Type:      row.Type,
+			Payload:   row.Payload,
+			CreatedAt: row.CreatedAt,
+			Attempt:   int(row.Attempts),
+		}
+		var errs []error
+		for _, name := range names {
+			if err := r.call(ctx, handlers[name], e); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+		if err := r.finish(ctx, e, errors.Join(errs...)); err != nil {
+			return len(events), err
+		}
+	}
+	return len(events), nil
+}
+
+// call runs h with a deadline, turning a panic into an error so one bad
+// handler can't take the relay down.
+func (r *Relay) call(ctx context.Context, h Handler, e Event) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return h(ctx, e)
+}
+
+// finish marks e delivered, or schedules its next attempt after a failure.
+func (r *Relay) finish(ctx context.Context, e Event, failure error) error {
+	if failure == nil {
+		return r.db.MarkOutboxEventDelivered(ctx, e.ID)
+	}
+	if e.Attempt >= MaxAttempts {
+		log.Printf("Giving up on outbox event %s (%s) after %d attempts: %v", e.ID, e.Type, e.Attempt, failure)
+	}
+	return r.db.MarkOutboxEventFailed(ctx, database.MarkOutboxEventFailedParams{
+		ID:            e.ID,
+		NextAttemptAt: time.Now().UTC().Add(r.backoff(e.Attempt)),
+		LastError:     sql.NullString{String: failure.Error(), Valid: true},
+	})
+}
+
+// backoff doubles the wait after every failed attempt, starting at one
+// second, up to maxBackoff.
+func backoff(attempt int) time.Duration {
+	if attempt > 12 {
+		return maxBackoff
+	}
+	return min(time.Second<<(attempt-1), maxBackoff)
+}
@@ -0,0 +1,91 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+)
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemory()
+	r := NewRelay(db)
+	r.backoff = func(int) time.Duration { return 0 }
+
+	var seen []string
+	failures := 1
+	r.Subscribe("log", func(ctx context.Context, e Event) error {
+		seen = append(seen, e.Type+" "+string(e.Payload))
+		return nil
+	})
+	r.Subscribe("flaky", func(ctx context.Context, e Event) error {
+		if e.Type == UserUpgraded && failures > 0 {
+			failures--
+			return errors.New("receiver down")
+		}
+		return nil
+	})
+	deliver := func(want int) {
+		t.Helper()
+		n, err := r.Deliver(ctx)
+		if err != nil || n != want {
+			t.Fatalf("Deliver() = %d, %v; want %d", n, err, want)
+		}
+	}
+
+	for _, eventType := range []string{ChirpCreated, UserUpgraded} {
+		if err := Record(ctx, db, eventType, map[string]int{"n": 1}); err != nil {
+			t.Fatalf("Record error: %v", err)
+		}
+	}
+	deliver(2)
+	// the failed event comes round again, to every handler
+	deliver(1)
+	deliver(0)
+
+	want := []string{`chirp.created {"n":1}`, `user.upgraded {"n":1}`, `user.upgraded {"n":1}`}
+	if len(seen) != len(want) {
+		t.Fatalf("seen = %q, want %q", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("seen = %q, want %q", seen, want)
+		}
+	}
+}
+
+func TestRelayGivesUp(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemory()
+	r := NewRelay(db)
+	r.backoff = func(int) time.Duration { return 0 }
+	attempts := 0
+	r.Subscribe("broken", func(ctx context.Context, e Event) error {
+		attempts++
+		panic("boom")
+	})
+
+	if err := Record(ctx, db, ChirpCreated, nil); err != nil {
+		t.Fatalf("Record error: %v", err)
+	}
+	for range MaxAttempts + 2 {
+		if _, err := r.Deliver(ctx); err != nil {
+			t.Fatalf("Deliver error: %v", err)
+		}
+	}
+	if attempts != MaxAttempts {
+		t.Fatalf("handler ran %d times, want %d", attempts, MaxAttempts)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 5: 16 * time.Second, 13: time.Hour, 100: time.Hour}
+	for attempt, want := range tests {
+		if got := backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
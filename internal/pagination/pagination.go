@@ -0,0 +1,127 @@
+package pagination
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 100
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidLimit  = errors.New("invalid limit")
+)
+
+// Cursor marks a position in a list ordered by (created_at, id). Clients only
+// ever see it as an opaque string.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor produced by Encode. An empty string decodes to
+// a nil cursor, meaning "start from the beginning".
+func DecodeCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{CreatedAt: createdAt, ID: uid}, nil
+}
+
+// ParseLimit reads a page size from a query parameter, falling back to
+// DefaultLimit when empty and rejecting anything outside 1..MaxLimit.
+func ParseLimit(s string) (int, error) {
+	if s == "" {
+		return DefaultLimit, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 || limit > MaxLimit {
+		return 0, ErrInvalidLimit
+	}
+	return limit, nil
+}
+
+// Page is a parsed page request: how many items to return and where to
+// start.
+type Page struct {
+	Limit  int
+	Cursor *Cursor
+}
+
+// Parse reads the limit and cursor query parameters.
+func Parse(query url.Values) (Page, error) {
+	limit, err := ParseLimit(query.Get("limit"))
+	if err != nil {
+		return Page{}, err
+	}
+	cursor, err := DecodeCursor(query.Get("cursor"))
+	if err != nil {
+		return Page{}, err
+	}
+	return Page{Limit: limit, Cursor: cursor}, nil
+}
+
+// After returns the cursor as the nullable keyset arguments taken by the
+// database List* queries.
+func (p Page) After() (sql.NullTime, uuid.NullUUID) {
+	if p.Cursor == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: p.Cursor.CreatedAt, Valid: true}, uuid.NullUUID{UUID: p.Cursor.ID, Valid: true}
+}
+
+// FetchSize is the number of rows to ask the database for. The one extra row
+// tells Trim whether there is a next page.
+func (p Page) FetchSize() int32 {
+	return int32(p.Limit + 1)
+}
+
+// Trim cuts items fetched with FetchSize down to the page limit and returns
+// the encoded cursor of the next page, or "" if this is the last one.
+func Trim[T any](p Page, items []T, key func(T) Cursor) ([]T, string) {
+	if len(items) <= p.Limit {
+		return items, ""
+	}
+	items = items[:p.Limit]
+	return items, key(items[p.Limit-1]).Encode()
+}
+
+// NextLink builds an RFC 8288 Link header pointing at the next page of u.
+func NextLink(u *url.URL, cursor string) string {
+	next := *u
+	q := next.Query()
+	q.Set("cursor", cursor)
+	next.RawQuery = q.Encode()
+	return "<" + next.RequestURI() + `>; rel="next"`
+}
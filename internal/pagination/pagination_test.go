@@ -0,0 +1,131 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := Cursor{
+		CreatedAt: time.Date(2025, 3, 14, 15, 9, 26, 535897000, time.UTC),
+		ID:        uuid.New(),
+	}
+
+	got, err := DecodeCursor(want.Encode())
+	if err != nil {
+		t.Fatalf("DecodeCursor error: %v", err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Fatalf("got %+v want %+v", got, want)
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		cursor  string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "empty", cursor: "", wantNil: true},
+		{name: "not base64", cursor: "!!!", wantErr: true},
+		{name: "no separator", cursor: "bm9wZQ", wantErr: true},
+		{name: "bad timestamp", cursor: base64.RawURLEncoding.EncodeToString([]byte("yesterday|" + uuid.NewString())), wantErr: true},
+		{name: "bad id", cursor: base64.RawURLEncoding.EncodeToString([]byte("2025-01-01T00:00:00Z|nope")), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeCursor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantNil && got != nil {
+				t.Fatalf("expected nil cursor, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{in: "", want: DefaultLimit},
+		{in: "10", want: 10},
+		{in: "100", want: 100},
+		{in: "0", wantErr: true},
+		{in: "101", wantErr: true},
+		{in: "ten", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLimit(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLimit(%q) = %d want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTrim(t *testing.T) {
+	items := []int{1, 2, 3}
+	key := func(i int) Cursor { return Cursor{CreatedAt: time.Unix(int64(i), 0).UTC()} }
+
+	got, next := Trim(Page{Limit: 2}, items, key)
+	if len(got) != 2 || next != key(2).Encode() {
+		t.Fatalf("got %v, %q", got, next)
+	}
+
+	got, next = Trim(Page{Limit: 3}, items, key)
+	if len(got) != 3 || next != "" {
+		t.Fatalf("got %v, %q", got, next)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	u, _ := url.Parse("/api/chirps?sort=desc&cursor=old")
+	got := NextLink(u, "new")
+	want := `</api/chirps?cursor=new&sort=desc>; rel="next"`
+	if got != want {
+		t.Fatalf("got %s want %s", got, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	at := Cursor{CreatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), ID: uuid.New()}
+
+	page, err := Parse(url.Values{})
+	if err != nil || page.Limit != DefaultLimit || page.Cursor != nil {
+		t.Fatalf("Parse() = %+v, %v; want the first page", page, err)
+	}
+	if after, id := page.After(); after.Valid || id.Valid {
+		t.Fatalf("After() on the first page = %v, %v", after, id)
+	}
+	if page.FetchSize() != DefaultLimit+1 {
+		t.Fatalf("FetchSize() = %d, want %d", page.FetchSize(), DefaultLimit+1)
+	}
+
+	page, err = Parse(url.Values{"limit": {"5"}, "cursor": {at.Encode()}})
+	if err != nil || page.Limit != 5 {
+		t.Fatalf("Parse() = %+v, %v", page, err)
+	}
+	if after, id := page.After(); !after.Time.Equal(at.CreatedAt) || id.UUID != at.ID {
+		t.Fatalf("After() = %v, %v; want %+v", after, id, at)
+	}
+
+	for _, query := range []url.Values{{"limit": {"0"}}, {"cursor": {"nope"}}} {
+		if _, err := Parse(query); err == nil {
+			t.Errorf("Parse(%v) accepted it", query)
+		}
+	}
+}
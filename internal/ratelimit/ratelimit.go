@@ -0,0 +1,85 @@
+// Package ratelimit limits how often each user can do something, with a
+// token bucket per user. Buckets are kept in memory, so every instance of
+// the server limits the requests it serves on its own.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sweepInterval is how often Allow forgets the buckets that have filled up
+// again, which are the same as no bucket at all.
+const sweepInterval = time.Minute
+
+// Rate is how many requests a user can make per Period. They can make them
+// all at once, and then get one more back every Period/Requests.
+type Rate struct {
+	Requests int
+	Period   time.Duration
+}
+
+// interval is how long it takes to get one request back.
+func (r Rate) interval() time.Duration {
+	return r.Period / time.Duration(r.Requests)
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+	// full is when the bucket has every request back.
+	full time.Time
+}
+
+// Limiter keeps a bucket for each user. The rate is passed on every call,
+// so a user whose plan changes gets the new rate straight away.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[uuid.UUID]bucket
+	swept   time.Time
+
+	// now is swapped out in tests that need time to pass.
+	now func() time.Time
+}
+
+func New() *Limiter {
+	return &Limiter{
+		buckets: map[uuid.UUID]bucket{},
+		now:     time.Now,
+	}
+}
+
+// Allow spends one of userID's requests under rate. When none are left it
+// returns false and how long until one is.
+func (l *Limiter) Allow(userID uuid.UUID, rate Rate) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.swept) >= sweepInterval {
+		for id, b := range l.buckets {
+			if !now.Before(b.full) {
+				delete(l.buckets, id)
+			}
+		}
+		l.swept = now
+	}
+
+	interval := rate.interval()
+	b, ok := l.buckets[userID]
+	if !ok {
+		b = bucket{tokens: float64(rate.Requests), at: now}
+	}
+	b.tokens = min(float64(rate.Requests), b.tokens+float64(now.Sub(b.at))/float64(interval))
+	b.at = now
+	if b.tokens < 1 {
+		l.buckets[userID] = b
+		return false, time.Duration((1 - b.tokens) * float64(interval))
+	}
+	b.tokens--
+	b.full = now.Add(time.Duration((float64(rate.Requests) - b.tokens) * float64(interval)))
+	l.buckets[userID] = b
+	return true, 0
+}
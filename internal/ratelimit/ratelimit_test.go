@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestAllow(t *testing.T) {
+	l := New()
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	rate := Rate{Requests: 3, Period: time.Minute}
+	user, other := uuid.New(), uuid.New()
+
+	// the whole burst at once, then nothing until one comes back
+	for i := range 3 {
+		if ok, _ := l.Allow(user, rate); !ok {
+			t.Fatalf("request %d refused", i+1)
+		}
+	}
+	if ok, wait := l.Allow(user, rate); ok || wait != 20*time.Second {
+		t.Fatalf("Allow over the limit = %v, %v; want false, 20s", ok, wait)
+	}
+	if ok, _ := l.Allow(other, rate); !ok {
+		t.Fatal("another user was limited too")
+	}
+
+	now = now.Add(20 * time.Second)
+	if ok, _ := l.Allow(user, rate); !ok {
+		t.Fatal("request refused after waiting")
+	}
+	if ok, _ := l.Allow(user, rate); ok {
+		t.Fatal("request allowed before the next one came back")
+	}
+
+	// a higher rate gives requests back sooner as soon as it's asked for
+	now = now.Add(2 * time.Second)
+	if ok, _ := l.Allow(user, Rate{Requests: 30, Period: time.Minute}); !ok {
+		t.Fatal("request refused under a higher rate")
+	}
+
+	// buckets that have filled up again are forgotten
+	now = now.Add(2 * time.Minute)
+	l.Allow(other, rate)
+	if _, ok := l.buckets[user]; ok || len(l.buckets) != 1 {
+		t.Fatalf("buckets after a sweep = %v, want only the one just used", l.buckets)
+	}
+}
@@ -0,0 +1,181 @@
+// Package scheduler publishes drafts when their publish_at comes round.
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+)
+
+const (
+	// pollInterval is how often the scheduler looks for due drafts, and so
+	// how late a scheduled chirp may be.
+	pollInterval = time.Second
+	// MaxAttempts is how often a draft is tried when publishing it fails for
+	// reasons other than a Rejection. After that it's unscheduled, like a
+	// rejected draft.
+	MaxAttempts = 10
+	maxBackoff  = time.Hour
+)
+
+// Rejection is returned by a Publisher for a draft that can't be published
+// as it is, e.g. because its body no longer passes validation. The draft is
+// kept, unscheduled, with Reason as its last error.
+type Rejection struct {
+	Reason string
+}
+
+func (r *Rejection) Error() string {
+	return "draft rejected: " + r.Reason
+}
+
+// Publisher turns a due draft into a chirp, writing through tx, which also
+// deletes the draft once the Publisher returns. What must wait until the
+// chirp is committed, such as announcing it, goes in done.
+type Publisher func(ctx context.Context, tx database.Store, draft database.Draft) (done func(context.Context), err error)
+
+// Store is the part of database.Store the Scheduler needs.
+type Store interface {
+	InTx(ctx context.Context, fn func(database.Store) error) error
+}
+
+// Scheduler publishes due drafts. Any number of schedulers, in any number of
+// processes, can share one database: each draft is claimed and published in
+// one transaction, under a row lock the others skip, so it is published
+// exactly once.
+type Scheduler struct {
+	db      Store
+	publish Publisher
+
+	// now is swapped out in tests that need drafts to be due.
+	now func() time.Time
+	// backoff is swapped out in tests that retry straight away.
+	backoff func(attempt int) time.Duration
+}
+
+func New(db Store, publish Publisher) *Scheduler {
+	return &Scheduler{
+		db:      db,
+		publish: publish,
+		now:     func() time.Time { return time.Now().UTC() },
+		backoff: backoff,
+	}
+}
+
+// Run publishes due drafts until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		_, err := s.PublishDue(ctx)
+		if err != nil {
+			log.Printf("Error publishing scheduled drafts: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishDue publishes every draft due by now, one transaction each, and
+// reports how many it published along with the errors of those it couldn't.
+// A draft failing with an error that isn't a Rejection is tried again after
+// a backoff, and doesn't hold back the drafts due after it.
+func (s *Scheduler) PublishDue(ctx context.Context) (int, error) {
+	n := 0
+	var errs []error
+	for {
+		draft, published, err := s.publishNext(ctx)
+		if err != nil && draft == nil {
+			return n, errors.Join(append(errs, err)...)
+		}
+		if draft == nil {
+			return n, errors.Join(errs...)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("publishing draft %s: %w", draft.ID, err))
+			err = s.retry(ctx, *draft)
+			if err != nil {
+				return n, errors.Join(append(errs, err)...)
+			}
+			continue
+		}
+		if published {
+			n++
+		}
+	}
+}
+
+// publishNext claims the earliest due draft and publishes or rejects it. It
+// returns the draft, nil if none was due, and whether it was published.
+func (s *Scheduler) publishNext(ctx context.Context) (claimed *database.Draft, published bool, err error) {
+	var done func(context.Context)
+	err = s.db.InTx(ctx, func(tx database.Store) error {
+		draft, err := tx.ClaimDueDraft(ctx, s.now())
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		claimed = &draft
+
+		done, err = s.publish(ctx, tx, draft)
+		var rejection *Rejection
+		if errors.As(err, &rejection) {
+			return tx.FailDraft(ctx, database.FailDraftParams{
+				ID:        draft.ID,
+				LastError: sql.NullString{String: rejection.Reason, Valid: true},
+			})
+		}
+		if err != nil {
+			return err
+		}
+		published = true
+		_, err = tx.DeleteDraft(ctx, database.DeleteDraftParams{ID: draft.ID, UserID: draft.UserID})
+		return err
+	})
+	if err != nil {
+		return claimed, false, err
+	}
+	if published && done != nil {
+		done(ctx)
+	}
+	return claimed, published, nil
+}
+
+// retry records a failed attempt at publishing draft, which is put off until
+// after the backoff, or unscheduled once it has had MaxAttempts. The draft's
+// owner sees last_error, so it doesn't say what went wrong.
+func (s *Scheduler) retry(ctx context.Context, draft database.Draft) error {
+	attempt := int(draft.Attempts) + 1
+	return s.db.InTx(ctx, func(tx database.Store) error {
+		if attempt >= MaxAttempts {
+			return tx.FailDraft(ctx, database.FailDraftParams{
+				ID:        draft.ID,
+				LastError: sql.NullString{String: fmt.Sprintf("publishing failed %d times", attempt), Valid: true},
+			})
+		}
+		return tx.RetryDraft(ctx, database.RetryDraftParams{
+			ID:            draft.ID,
+			NextAttemptAt: sql.NullTime{Time: s.now().Add(s.backoff(attempt)), Valid: true},
+			LastError:     sql.NullString{String: "publishing failed, trying again", Valid: true},
+		})
+	})
+}
+
+// backoff doubles the wait after every failed attempt, starting at ten
+// seconds, up to maxBackoff.
+func backoff(attempt int) time.Duration {
+	if attempt > 10 {
+		return maxBackoff
+	}
+	return min(10*time.Second<<(attempt-1), maxBackoff)
+}
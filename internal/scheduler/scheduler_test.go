@@ -0,0 +1,165 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+)
+
+func TestPublishDue(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemory()
+
+	user, err := db.CreateUser(ctx, database.CreateUserParams{Email: "author@example.com", HashedPassword: "x"})
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	now := time.Now().UTC()
+	draft := func(body string, publishAt time.Time) database.Draft {
+		t.Helper()
+		d, err := db.CreateDraft(ctx, database.CreateDraftParams{
+			UserID:    user.ID,
+			Body:      body,
+			PublishAt: sql.NullTime{Time: publishAt, Valid: !publishAt.IsZero()},
+		})
+		if err != nil {
+			t.Fatalf("CreateDraft error: %v", err)
+		}
+		return d
+	}
+	due := draft("due", now.Add(-time.Minute))
+	rejected := draft("rejected", now.Add(-2*time.Minute))
+	later := draft("later", now.Add(time.Hour))
+	unscheduled := draft("unscheduled", time.Time{})
+
+	var published []string
+	announced := 0
+	s := New(db, func(ctx context.Context, tx database.Store, draft database.Draft) (func(context.Context), error) {
+		if draft.Body == "rejected" {
+			return nil, &Rejection{Reason: "too rude"}
+		}
+		published = append(published, draft.Body)
+		return func(context.Context) { announced++ }, nil
+	})
+	s.now = func() time.Time { return now }
+
+	n, err := s.PublishDue(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("PublishDue() = %d, %v; want 1, nil", n, err)
+	}
+	if len(published) != 1 || published[0] != "due" || announced != 1 {
+		t.Fatalf("published %v, announced %d; want [due], 1", published, announced)
+	}
+	if _, err := db.GetDraft(ctx, database.GetDraftParams{ID: due.ID, UserID: user.ID}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetDraft(due) error = %v, want sql.ErrNoRows", err)
+	}
+
+	// a rejected draft is kept, unscheduled, saying why
+	got, err := db.GetDraft(ctx, database.GetDraftParams{ID: rejected.ID, UserID: user.ID})
+	if err != nil {
+		t.Fatalf("GetDraft(rejected) error: %v", err)
+	}
+	if got.PublishAt.Valid || got.LastError.String != "too rude" {
+		t.Fatalf("rejected draft = publish_at %v, last_error %q; want unscheduled, %q", got.PublishAt, got.LastError.String, "too rude")
+	}
+	for _, d := range []database.Draft{later, unscheduled} {
+		if _, err := db.GetDraft(ctx, database.GetDraftParams{ID: d.ID, UserID: user.ID}); err != nil {
+			t.Fatalf("GetDraft(%s) error: %v", d.Body, err)
+		}
+	}
+
+	// nothing else is due yet, and a failed publish is left for the next try
+	fail := errors.New("database is down")
+	s.publish = func(ctx context.Context, tx database.Store, draft database.Draft) (func(context.Context), error) {
+		return nil, fail
+	}
+	s.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if n, err := s.PublishDue(ctx); !errors.Is(err, fail) || n != 0 {
+		t.Fatalf("PublishDue() = %d, %v; want 0, %v", n, err, fail)
+	}
+	if _, err := db.GetDraft(ctx, database.GetDraftParams{ID: later.ID, UserID: user.ID}); err != nil {
+		t.Fatalf("GetDraft(later) after failed publish error: %v", err)
+	}
+}
+
+func TestPublishDueRetries(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemory()
+
+	user, err := db.CreateUser(ctx, database.CreateUserParams{Email: "author@example.com", HashedPassword: "x"})
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	now := time.Now().UTC()
+	draft := func(body string, publishAt time.Time) database.Draft {
+		t.Helper()
+		d, err := db.CreateDraft(ctx, database.CreateDraftParams{
+			UserID:    user.ID,
+			Body:      body,
+			PublishAt: sql.NullTime{Time: publishAt, Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("CreateDraft error: %v", err)
+		}
+		return d
+	}
+	failing := draft("failing", now.Add(-2*time.Minute))
+	draft("fine", now.Add(-time.Minute))
+
+	// the failing draft comes first, and the one after it is published anyway
+	fail := errors.New("thumbnail service is down")
+	var published []string
+	s := New(db, func(ctx context.Context, tx database.Store, draft database.Draft) (func(context.Context), error) {
+		if draft.Body == "failing" {
+			return nil, fail
+		}
+		published = append(published, draft.Body)
+		return nil, nil
+	})
+	s.now = func() time.Time { return now }
+	n, err := s.PublishDue(ctx)
+	if !errors.Is(err, fail) || n != 1 || len(published) != 1 {
+		t.Fatalf("PublishDue() = %d, %v, published %v; want 1, %v, [fine]", n, err, published, fail)
+	}
+
+	// the failed draft waits out its backoff
+	got, err := db.GetDraft(ctx, database.GetDraftParams{ID: failing.ID, UserID: user.ID})
+	if err != nil {
+		t.Fatalf("GetDraft(failing) error: %v", err)
+	}
+	if got.Attempts != 1 || !got.NextAttemptAt.Time.Equal(now.Add(backoff(1))) || !got.PublishAt.Valid {
+		t.Fatalf("failed draft = attempts %d, next attempt %v, publish_at %v", got.Attempts, got.NextAttemptAt, got.PublishAt)
+	}
+	if n, err := s.PublishDue(ctx); err != nil || n != 0 {
+		t.Fatalf("PublishDue() during the backoff = %d, %v; want 0, nil", n, err)
+	}
+
+	// and after MaxAttempts it's unscheduled
+	s.now = func() time.Time { return now.Add(time.Hour) }
+	s.backoff = func(int) time.Duration { return 0 }
+	if _, err := s.PublishDue(ctx); !errors.Is(err, fail) {
+		t.Fatalf("PublishDue() error = %v, want %v", err, fail)
+	}
+	got, err = db.GetDraft(ctx, database.GetDraftParams{ID: failing.ID, UserID: user.ID})
+	if err != nil {
+		t.Fatalf("GetDraft(failing) error: %v", err)
+	}
+	if got.PublishAt.Valid || got.LastError.String != "publishing failed 10 times" {
+		t.Fatalf("given up draft = publish_at %v, last_error %q", got.PublishAt, got.LastError.String)
+	}
+	if len(published) != 1 {
+		t.Fatalf("published %v, want only [fine]", published)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 9: 2560 * time.Second, 10: time.Hour, 40: time.Hour} {
+		if got := backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Package trash permanently removes chirps and users that have been in the
+// trash for longer than the retention window, along with their uploads and
+// any uploads never attached to a chirp. It also clears out outbox events
+// and webhook deliveries that are done with once they're old enough.
+package trash
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/media"
+)
+
+const (
+	// purgeInterval is how often Run purges. Rows are kept for at most this
+	// long past the retention window.
+	purgeInterval = time.Hour
+	// PendingMediaTTL is how long an upload can wait to be attached to a
+	// chirp before it's purged.
+	PendingMediaTTL = 24 * time.Hour
+	// DeliveredEventTTL is how long an outbox event is kept after it was
+	// delivered.
+	DeliveredEventTTL = 7 * 24 * time.Hour
+	// DeliveryLogTTL is how long a finished webhook delivery stays in its
+	// endpoint's log after the last attempt.
+	DeliveryLogTTL = 30 * 24 * time.Hour
+)
+
+// Store is the part of database.Store the Purger needs.
+type Store interface {
+	DeleteDeliveredOutboxEventsBefore(ctx context.Context, before time.Time) (int64, error)
+	DeleteFinishedWebhookDeliveriesBefore(ctx context.Context, before time.Time) (int64, error)
+	PurgeDeletedChirps(ctx context.Context, before time.Time) (int64, error)
+	PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error)
+	PurgeMedia(ctx context.Context, arg database.PurgeMediaParams) ([]database.Medium, error)
+}
+
+// Purger deletes soft-deleted rows for good once they are older than the
+// retention window. Any number of purgers can share one database and blob
+// store.
+type Purger struct {
+	db        Store
+	blobs     media.BlobStore
+	retention time.Duration
+
+	// now is swapped out in tests that need to be past the retention window.
+	now func() time.Time
+}
+
+func NewPurger(db Store, blobs media.BlobStore, retention time.Duration) *Purger {
+	return &Purger{
+		db:        db,
+		blobs:     blobs,
+		retention: retention,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Purge deletes every chirp and user deleted more than the retention window
+// ago, and every upload of theirs or left unattached for PendingMediaTTL, and
+// reports how many of each it deleted. A purged user's chirps go with them.
+func (p *Purger) Purge(ctx context.Context) (chirps, users, uploads int64, err error) {
+	now := p.now()
+	before := now.Add(-p.retention)
+
+	// the uploads go first, while the rows saying which blobs are theirs
+	// are still there to be found. Blobs are deleted after their rows, so
+	// a blob left behind by a failure is one nothing can reach anymore
+	purged, err := p.db.PurgeMedia(ctx, database.PurgeMediaParams{
+		Before:           before,
+		UnattachedBefore: now.Add(-PendingMediaTTL),
+	})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	for _, medium := range purged {
+		for _, key := range []string{media.Key(medium.ID, medium.ContentType), media.ThumbnailKey(medium.ID, medium.ContentType)} {
+			if err := p.blobs.Delete(ctx, key); err != nil {
+				log.Printf("Error deleting blob %s: %v", key, err)
+			}
+		}
+	}
+	uploads = int64(len(purged))
+
+	chirps, err = p.db.PurgeDeletedChirps(ctx, before)
+	if err != nil {
+		return 0, 0, uploads, err
+	}
+	users, err = p.db.PurgeDeletedUsers(ctx, before)
+	if err != nil {
+		return chirps, 0, uploads, err
+	}
+	return chirps, users, uploads, nil
+}
+
+// PurgeLogs deletes outbox events delivered more than DeliveredEventTTL ago
+// and webhook deliveries finished more than DeliveryLogTTL ago, and reports
+// how many of each it deleted. Pending ones are kept however old they are.
+func (p *Purger) PurgeLogs(ctx context.Context) (events, deliveries int64, err error) {
+	now := p.now()
+	events, err = p.db.DeleteDeliveredOutboxEventsBefore(ctx, now.Add(-DeliveredEventTTL))
+	if err != nil {
+		return 0, 0, err
+	}
+	deliveries, err = p.db.DeleteFinishedWebhookDeliveriesBefore(ctx, now.Add(-DeliveryLogTTL))
+	if err != nil {
+		return events, 0, err
+	}
+	return events, deliveries, nil
+}
+
+// Run purges once straight away and then every purgeInterval, until ctx is
+// cancelled.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		chirps, users, uploads, err := p.Purge(ctx)
+		if err != nil {
+			log.Printf("Error purging the trash: %v", err)
+		} else if chirps > 0 || users > 0 || uploads > 0 {
+			log.Printf("Purged %d chirps, %d users and %d uploads", chirps, users, uploads)
+		}
+		events, deliveries, err := p.PurgeLogs(ctx)
+		if err != nil {
+			log.Printf("Error purging delivered events: %v", err)
+		} else if events > 0 || deliveries > 0 {
+			log.Printf("Purged %d outbox events and %d webhook deliveries", events, deliveries)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
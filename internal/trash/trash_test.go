@@ -0,0 +1,187 @@
+package trash
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/media"
+	"github.com/google/uuid"
+)
+
+// upload records an upload by userID, attached to chirpID unless it's
+// uuid.Nil, and stores its blobs.
+func upload(t *testing.T, db *database.Memory, blobs media.BlobStore, userID, chirpID uuid.UUID) database.Medium {
+	t.Helper()
+	ctx := context.Background()
+	medium, err := db.CreateMedia(ctx, database.CreateMediaParams{ID: uuid.New(), UserID: userID, ContentType: "image/png", Width: 1, Height: 1, SizeBytes: 1})
+	if err != nil {
+		t.Fatalf("CreateMedia error: %v", err)
+	}
+	for _, key := range []string{media.Key(medium.ID, medium.ContentType), media.ThumbnailKey(medium.ID, medium.ContentType)} {
+		if err := blobs.Put(ctx, key, medium.ContentType, bytes.NewReader([]byte("pixels"))); err != nil {
+			t.Fatalf("Put error: %v", err)
+		}
+	}
+	if chirpID != uuid.Nil {
+		if _, err := db.AttachMedia(ctx, database.AttachMediaParams{ChirpID: chirpID, ID: medium.ID, UserID: userID}); err != nil {
+			t.Fatalf("AttachMedia error: %v", err)
+		}
+	}
+	return medium
+}
+
+// stored reports whether both blobs of medium are still in blobs.
+func stored(t *testing.T, blobs media.BlobStore, medium database.Medium) bool {
+	t.Helper()
+	found := 0
+	for _, key := range []string{media.Key(medium.ID, medium.ContentType), media.ThumbnailKey(medium.ID, medium.ContentType)} {
+		blob, err := blobs.Get(context.Background(), key)
+		if err == nil {
+			blob.Close()
+			found++
+		} else if !errors.Is(err, media.ErrNotFound) {
+			t.Fatalf("Get error: %v", err)
+		}
+	}
+	return found == 2
+}
+
+func TestPurge(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemory()
+	blobs, err := media.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFSStore error: %v", err)
+	}
+	p := NewPurger(db, blobs, 24*time.Hour)
+
+	author, err := db.CreateUser(ctx, database.CreateUserParams{Email: "author@example.com", HashedPassword: "x"})
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	leaving, err := db.CreateUser(ctx, database.CreateUserParams{Email: "leaving@example.com", HashedPassword: "x"})
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	kept, err := db.CreateChirp(ctx, database.CreateChirpParams{Body: "kept", UserID: author.ID})
+	if err != nil {
+		t.Fatalf("CreateChirp error: %v", err)
+	}
+	trashed, err := db.CreateChirp(ctx, database.CreateChirpParams{Body: "trashed", UserID: author.ID})
+	if err != nil {
+		t.Fatalf("CreateChirp error: %v", err)
+	}
+	keptMedia := upload(t, db, blobs, author.ID, kept.ID)
+	trashedMedia := upload(t, db, blobs, author.ID, trashed.ID)
+	pending := upload(t, db, blobs, author.ID, uuid.Nil)
+	leavingMedia := upload(t, db, blobs, leaving.ID, uuid.Nil)
+	if err := db.DeleteChirpByID(ctx, trashed.ID); err != nil {
+		t.Fatalf("DeleteChirpByID error: %v", err)
+	}
+	if _, err := db.DeleteUser(ctx, leaving.ID); err != nil {
+		t.Fatalf("DeleteUser error: %v", err)
+	}
+
+	// still within the retention window, everything can be restored
+	chirps, users, uploads, err := p.Purge(ctx)
+	if err != nil || chirps != 0 || users != 0 || uploads != 0 {
+		t.Fatalf("Purge() = %d, %d, %d, %v; want 0, 0, 0, nil", chirps, users, uploads, err)
+	}
+	if _, err := db.RestoreUser(ctx, leaving.ID); err != nil {
+		t.Fatalf("RestoreUser error: %v", err)
+	}
+	if _, err := db.DeleteUser(ctx, leaving.ID); err != nil {
+		t.Fatalf("DeleteUser error: %v", err)
+	}
+
+	// past the window, uploads go with what they belonged to, or with
+	// nothing if they waited too long for a chirp
+	p.now = func() time.Time { return time.Now().UTC().Add(25 * time.Hour) }
+	chirps, users, uploads, err = p.Purge(ctx)
+	if err != nil || chirps != 1 || users != 1 || uploads != 3 {
+		t.Fatalf("Purge() = %d, %d, %d, %v; want 1, 1, 3, nil", chirps, users, uploads, err)
+	}
+	for _, medium := range []database.Medium{trashedMedia, pending, leavingMedia} {
+		if _, err := db.GetMedia(ctx, medium.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("GetMedia(%s) after purge error = %v, want sql.ErrNoRows", medium.ID, err)
+		}
+		if stored(t, blobs, medium) {
+			t.Errorf("blobs of %s are still stored after purge", medium.ID)
+		}
+	}
+	if _, err := db.GetMedia(ctx, keptMedia.ID); err != nil || !stored(t, blobs, keptMedia) {
+		t.Fatalf("media of the kept chirp: %v, stored %v", err, stored(t, blobs, keptMedia))
+	}
+	if _, err := db.GetTrashedChirp(ctx, database.GetTrashedChirpParams{ID: trashed.ID, UserID: author.ID}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetTrashedChirp after purge error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := db.RestoreUser(ctx, leaving.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("RestoreUser after purge error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := db.GetChirpByID(ctx, kept.ID); err != nil {
+		t.Fatalf("GetChirpByID(kept) error: %v", err)
+	}
+
+	// nothing left to purge
+	chirps, users, uploads, err = p.Purge(ctx)
+	if err != nil || chirps != 0 || users != 0 || uploads != 0 {
+		t.Fatalf("Purge() = %d, %d, %d, %v; want 0, 0, 0, nil", chirps, users, uploads, err)
+	}
+}
+
+func TestPurgeLogs(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemory()
+	p := NewPurger(db, nil, 24*time.Hour)
+
+	delivered, err := db.CreateOutboxEvent(ctx, database.CreateOutboxEventParams{Type: "chirp.created", Payload: "{}"})
+	if err != nil {
+		t.Fatalf("CreateOutboxEvent error: %v", err)
+	}
+	if err := db.MarkOutboxEventDelivered(ctx, delivered.ID); err != nil {
+		t.Fatalf("MarkOutboxEventDelivered error: %v", err)
+	}
+	if _, err := db.CreateOutboxEvent(ctx, database.CreateOutboxEventParams{Type: "chirp.created", Payload: "{}"}); err != nil {
+		t.Fatalf("CreateOutboxEvent error: %v", err)
+	}
+
+	endpoint, err := db.CreateWebhookEndpoint(ctx, database.CreateWebhookEndpointParams{Url: "https://example.com", Secret: "x", Events: []string{"chirp.created"}})
+	if err != nil {
+		t.Fatalf("CreateWebhookEndpoint error: %v", err)
+	}
+	for range 2 {
+		params := database.CreateWebhookDeliveryParams{EndpointID: endpoint.ID, EventID: uuid.New(), EventType: "chirp.created", Payload: "{}"}
+		if _, err := db.CreateWebhookDelivery(ctx, params); err != nil {
+			t.Fatalf("CreateWebhookDelivery error: %v", err)
+		}
+	}
+	claimed, err := db.ClaimWebhookDeliveries(ctx, database.ClaimWebhookDeliveriesParams{LockedUntil: time.Now().UTC(), BatchSize: 1})
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("ClaimWebhookDeliveries() = %v, %v; want one", claimed, err)
+	}
+	if err := db.FinishWebhookDelivery(ctx, database.FinishWebhookDeliveryParams{ID: claimed[0].ID, Status: "succeeded"}); err != nil {
+		t.Fatalf("FinishWebhookDelivery error: %v", err)
+	}
+
+	// the undelivered event and the pending delivery outlive everything
+	for _, tt := range []struct {
+		after              time.Duration
+		events, deliveries int64
+	}{
+		{0, 0, 0},
+		{DeliveredEventTTL + time.Hour, 1, 0},
+		{DeliveryLogTTL + time.Hour, 0, 1},
+		{100 * DeliveryLogTTL, 0, 0},
+	} {
+		p.now = func() time.Time { return time.Now().UTC().Add(tt.after) }
+		events, deliveries, err := p.PurgeLogs(ctx)
+		if err != nil || events != tt.events || deliveries != tt.deliveries {
+			t.Fatalf("PurgeLogs() %v on = %d, %d, %v; want %d, %d, nil", tt.after, events, deliveries, err, tt.events, tt.deliveries)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package validation checks request fields before they reach the database and
+// reports every problem at once as structured field errors.
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/rivo/uniseg"
+)
+
+// FieldError is one problem with one request field. Code is stable and meant
+// for programs, Message is meant for people.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Codes used in FieldError.Code.
+const (
+	CodeRequired     = "required"
+	CodeTooLong      = "too_long"
+	CodeInvalidChars = "invalid_characters"
+	CodeInvalid      = "invalid"
+)
+
+// Errors is every FieldError found in a request. Validators return it as an
+// error only when it isn't empty.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Require records a CodeRequired error when value is blank.
+func (e *Errors) Require(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		*e = append(*e, FieldError{Field: field, Code: CodeRequired, Message: field + " is required"})
+	}
+}
+
+// MaxLength records a CodeTooLong error when value is more than max
+// characters, counted as grapheme clusters like chirp bodies, and a
+// CodeInvalidChars error when it contains control characters.
+func (e *Errors) MaxLength(field, value string, max int) {
+	if n := uniseg.GraphemeClusterCount(value); n > max {
+		*e = append(*e, FieldError{
+			Field:   field,
+			Code:    CodeTooLong,
+			Message: fmt.Sprintf("%s is too long: %d characters, the limit is %d", field, n, max),
+		})
+	}
+	if strings.ContainsFunc(value, isForbidden) {
+		*e = append(*e, FieldError{Field: field, Code: CodeInvalidChars, Message: field + " contains control characters"})
+	}
+}
+
+// HTTPURL records a CodeInvalid error unless value is empty or an absolute
+// http or https URL of at most maxURLLength bytes.
+func (e *Errors) HTTPURL(field, value string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(value) > maxURLLength {
+		*e = append(*e, FieldError{Field: field, Code: CodeInvalid, Message: field + " must be an http or https URL"})
+	}
+}
+
+// maxURLLength is the longest URL HTTPURL accepts.
+const maxURLLength = 2048
+
+// Err returns e as an error, or nil when it's empty.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// ChirpRules are the limits a chirp body is checked against.
+type ChirpRules struct {
+	// MaxLength is the most characters a body may have, as counted by Length.
+	MaxLength int
+	// URLWeight is how many characters a link counts for, however long it is.
+	URLWeight int
+}
+
+var (
+	// DefaultChirpRules apply to everyone.
+	DefaultChirpRules = ChirpRules{MaxLength: 140, URLWeight: 23}
+	// RedChirpRules apply to Chirpy Red users.
+	RedChirpRules = ChirpRules{MaxLength: 280, URLWeight: 23}
+)
+
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// Length counts body the way users see it: one per grapheme cluster, so an
+// emoji or a Devanagari syllable counts once, and every link counts as
+// URLWeight whatever its real length.
+func (r ChirpRules) Length(body string) int {
+	n, pos := 0, 0
+	for _, loc := range urlPattern.FindAllStringIndex(body, -1) {
+		n += uniseg.GraphemeClusterCount(body[pos:loc[0]]) + r.URLWeight
+		pos = loc[1]
+	}
+	return n + uniseg.GraphemeClusterCount(body[pos:])
+}
+
+// Chirp validates a chirp body. The returned error is an Errors.
+func (r ChirpRules) Chirp(body string) error {
+	var errs Errors
+	if strings.TrimSpace(body) == "" {
+		errs = append(errs, FieldError{Field: "body", Code: CodeRequired, Message: "Chirp is empty"})
+	}
+	if n := r.Length(body); n > r.MaxLength {
+		errs = append(errs, FieldError{
+			Field:   "body",
+			Code:    CodeTooLong,
+			Message: fmt.Sprintf("Chirp is too long: %d characters, the limit is %d", n, r.MaxLength),
+		})
+	}
+	if strings.ContainsFunc(body, isForbidden) {
+		errs = append(errs, FieldError{Field: "body", Code: CodeInvalidChars, Message: "Chirp contains control characters"})
+	}
+	return errs.Err()
+}
+
+// isForbidden reports control characters other than newlines and tabs, and
+// invisible formatting characters such as bidi overrides that can be used to
+// disguise text. Zero-width joiners are allowed since emoji sequences need them.
+func isForbidden(r rune) bool {
+	switch r {
+	case '\n', '\t', '\u200d':
+		return false
+	}
+	return unicode.IsControl(r) || unicode.Is(unicode.Cf, r)
+}
@@ -0,0 +1,91 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestChirpLength(t *testing.T) {
+	rules := DefaultChirpRules
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "ASCII", body: "hello", want: 5},
+		{name: "Devanagari", body: "नमस्ते", want: 4},
+		{name: "Emoji sequence", body: "👩‍👩‍👧 hi", want: 4},
+		{name: "Flag", body: "🇮🇳", want: 1},
+		{name: "Link", body: "see https://example.com/a/very/long/path?with=query", want: 4 + 23},
+		{name: "Bare www link", body: "www.example.com!", want: 23},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules.Length(tt.body); got != tt.want {
+				t.Errorf("Length(%q) = %d, want %d", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChirp(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules ChirpRules
+		body  string
+		codes []string
+	}{
+		{name: "Valid", rules: DefaultChirpRules, body: "hello\nworld"},
+		{name: "140 emoji", rules: DefaultChirpRules, body: strings.Repeat("😀", 140)},
+		{name: "141 characters", rules: DefaultChirpRules, body: strings.Repeat("a", 141), codes: []string{CodeTooLong}},
+		{name: "Red limit", rules: RedChirpRules, body: strings.Repeat("a", 200)},
+		{name: "Whitespace only", rules: DefaultChirpRules, body: " \n\t ", codes: []string{CodeRequired}},
+		{name: "Control character", rules: DefaultChirpRules, body: "bell\a", codes: []string{CodeInvalidChars}},
+		{name: "Bidi override", rules: DefaultChirpRules, body: "abc\u202edef", codes: []string{CodeInvalidChars}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.Chirp(tt.body)
+			if len(tt.codes) == 0 {
+				if err != nil {
+					t.Fatalf("Chirp(%q) error: %v", tt.body, err)
+				}
+				return
+			}
+			var errs Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("Chirp(%q) = %v, want Errors", tt.body, err)
+			}
+			if len(errs) != len(tt.codes) {
+				t.Fatalf("Chirp(%q) = %v, want codes %v", tt.body, errs, tt.codes)
+			}
+			for i, fe := range errs {
+				if fe.Code != tt.codes[i] || fe.Field != "body" {
+					t.Errorf("error %d = %+v, want code %s on body", i, fe, tt.codes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProfileFields(t *testing.T) {
+	var errs Errors
+	errs.MaxLength("display_name", "Cheems 🐕", 8)
+	errs.MaxLength("bio", strings.Repeat("a", 9), 8)
+	errs.MaxLength("bio", "tab\there\x00", 20)
+	errs.HTTPURL("avatar_url", "")
+	errs.HTTPURL("avatar_url", "https://example.com/me.png")
+	errs.HTTPURL("avatar_url", "javascript:alert(1)")
+	errs.HTTPURL("avatar_url", "/relative.png")
+
+	var codes []string
+	for _, fe := range errs {
+		codes = append(codes, fe.Field+":"+fe.Code)
+	}
+	want := []string{"bio:" + CodeTooLong, "bio:" + CodeInvalidChars, "avatar_url:" + CodeInvalid, "avatar_url:" + CodeInvalid}
+	if strings.Join(codes, " ") != strings.Join(want, " ") {
+		t.Fatalf("errors = %v, want %v", codes, want)
+	}
+}
@@ -0,0 +1,107 @@
+package webhooks
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// ErrForbiddenAddress is returned for endpoints that aren't on the public
+// internet, which deliveries never go to unless AllowPrivateNetworks was
+// called.
+var ErrForbiddenAddress = errors.New("webhooks: endpoint address is not allowed")
+
+// reserved are ranges that aren't public but that netip doesn't single out:
+// "this network", carrier-grade NAT, IETF protocol assignments, benchmarking,
+// the old class E, and NAT64, which can reach IPv4 addresses of any kind.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// publicAddr reports whether addr is on the public internet: not loopback,
+// private (RFC 1918 and IPv6 unique local), link-local, multicast or
+// otherwise reserved.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowPrivateNetworks lets deliveries go to any address, for trying webhooks
+// out against a receiver on the same machine or network. Call it before Run.
+func (d *Dispatcher) AllowPrivateNetworks() {
+	d.allowPrivate = true
+}
+
+// CheckURL rejects endpoint URLs deliveries could never go to: those whose
+// host is an address off the public internet, or localhost. Other names are
+// only resolved when delivering, where every address connected to is checked
+// again, so a name can't be pointed somewhere else after it was registered.
+func (d *Dispatcher) CheckURL(rawURL string) error {
+	if d.allowPrivate {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !publicAddr(addr) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// control is the dialer's Control function. It runs after the host name was
+// resolved, with the address actually being connected to.
+func (d *Dispatcher) control(network, address string, _ syscall.RawConn) error {
+	if d.allowPrivate {
+		return nil
+	}
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddr(addrPort.Addr()) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// failureReason describes a failed request for the delivery log. The log is
+// shown to the endpoint's owner, so it says what kind of failure it was and
+// nothing about the network the request was made from.
+func failureReason(status int, err error) string {
+	var netErr net.Error
+	switch {
+	case status != 0:
+		return err.Error()
+	case errors.Is(err, ErrForbiddenAddress):
+		return "endpoint address is not allowed"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "request timed out"
+	}
+	return "request failed"
+}
@@ -0,0 +1,385 @@
+// Package webhooks sends domain events to the HTTP endpoints users register
+// for them, signed with each endpoint's secret.
+//
+// Events come from the outbox: Dispatcher.Enqueue queues a delivery of each
+// one to every enabled endpoint subscribed to its type that belongs to the
+// user it is about, the chirp's author or the user whose Chirpy Red changed,
+// so users only ever hear about themselves. Endpoints the admins registered
+// belong to no user and hear about everyone. Dispatcher.Run sends the
+// deliveries, retrying failures with exponential backoff. An endpoint that
+// fails DisableAfter attempts in a row is disabled until its owner turns it
+// back on; events that happen while it is off aren't queued for it.
+//
+// Every request is a POST of a JSON Payload with these headers:
+//
+//	Chirpy-Event: chirp.created
+//	Chirpy-Delivery: <delivery ID>
+//	Chirpy-Signature: t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+//
+// Receivers check the signature with Verify. The same event may be delivered
+// more than once, Payload.ID tells repeats apart.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/outbox"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Events lists the event types an endpoint can subscribe to.
+var Events = []string{
+	outbox.ChirpCreated, outbox.ChirpUpdated, outbox.ChirpDeleted, outbox.ChirpRestored,
+	outbox.UserUpgraded, outbox.UserDowngraded,
+}
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Request headers.
+const (
+	HeaderEvent     = "Chirpy-Event"
+	HeaderDelivery  = "Chirpy-Delivery"
+	HeaderSignature = "Chirpy-Signature"
+)
+
+const (
+	// MaxAttempts is how often a delivery is tried before it is marked
+	// failed. It can still be redelivered by hand.
+	MaxAttempts = 8
+	// DisableAfter is how many failed attempts in a row, across all of an
+	// endpoint's deliveries, disable it.
+	DisableAfter = 15
+
+	pollInterval   = 5 * time.Second
+	batchSize      = 10
+	requestTimeout = 10 * time.Second
+	dialTimeout    = 5 * time.Second
+	// lease is how long claimed deliveries are hidden from other
+	// dispatchers; a whole batch of requests has to fit in it.
+	lease        = 5 * time.Minute
+	firstBackoff = 30 * time.Second
+	maxBackoff   = 6 * time.Hour
+)
+
+// Payload is the body of every request.
+type Payload struct {
+	// ID is the event's ID, the same for every delivery of it.
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Store is the part of database.Store the Dispatcher needs.
+type Store interface {
+	ClaimWebhookDeliveries(ctx context.Context, arg database.ClaimWebhookDeliveriesParams) ([]database.WebhookDelivery, error)
+	CreateWebhookDelivery(ctx context.Context, arg database.CreateWebhookDeliveryParams) (int64, error)
+	FinishWebhookDelivery(ctx context.Context, arg database.FinishWebhookDeliveryParams) error
+	GetWebhookEndpoint(ctx context.Context, id uuid.UUID) (database.WebhookEndpoint, error)
+	ListWebhookEndpointsForEvent(ctx context.Context, arg database.ListWebhookEndpointsForEventParams) ([]database.WebhookEndpoint, error)
+	RecordWebhookFailure(ctx context.Context, arg database.RecordWebhookFailureParams) (database.WebhookEndpoint, error)
+	RecordWebhookSuccess(ctx context.Context, id uuid.UUID) error
+}
+
+// Dispatcher queues and sends webhook deliveries. Like the outbox relay, any
+// number of them can share a database.
+type Dispatcher struct {
+	db     Store
+	client *http.Client
+	wake   chan struct{}
+
+	// allowPrivate turns off the checks keeping deliveries on the public
+	// internet, see AllowPrivateNetworks.
+	allowPrivate bool
+
+	// backoff is swapped out in tests that retry straight away.
+	backoff func(attempt int) time.Duration
+}
+
+func NewDispatcher(db Store) *Dispatcher {
+	d := &Dispatcher{
+		db:      db,
+		wake:    make(chan struct{}, 1),
+		backoff: backoff,
+	}
+	dialer := &net.Dialer{Timeout: dialTimeout, Control: d.control}
+	d.client = &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			// no proxy: its address would be checked instead of the
+			// endpoint's
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: dialTimeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		// a redirect is an answer from the endpoint, not a new one, so it
+		// can't send a delivery anywhere else
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return d
+}
+
+// NewSecret returns a random signing secret for a new endpoint.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign returns the Chirpy-Signature header for body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", t.Unix(), signature(secret, t.Unix(), body))
+}
+
+// Verify checks a Chirpy-Signature header against body, and that it was
+// made no more than tolerance before now, so old requests can't be replayed.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var timestamp int64
+	var sig string
+	for part := range strings.SplitSeq(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			sig = value
+		}
+	}
+	if timestamp == 0 || sig == "" {
+		return errors.New("webhooks: malformed signature header")
+	}
+	if now.Sub(time.Unix(timestamp, 0)).Abs() > tolerance {
+		return errors.New("webhooks: signature timestamp out of tolerance")
+	}
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, timestamp, body))) {
+		return errors.New("webhooks: signature mismatch")
+	}
+	return nil
+}
+
+func signature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Enqueue queues e for every enabled endpoint of the user it is about, or of
+// the admins, that is subscribed to its type. It is an outbox.Handler, and
+// queues each event for an endpoint only once however often the outbox hands
+// it over.
+func (d *Dispatcher) Enqueue(ctx context.Context, e outbox.Event) error {
+	if !slices.Contains(Events, e.Type) {
+		return nil
+	}
+	// every event there is to subscribe to is about one user: a chirp's
+	// author, or whoever's Chirpy Red changed
+	var subject struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+	err := json.Unmarshal(e.Payload, &subject)
+	if err != nil {
+		return err
+	}
+	endpoints, err := d.db.ListWebhookEndpointsForEvent(ctx, database.ListWebhookEndpointsForEventParams{
+		UserID:    subject.UserID,
+		EventType: e.Type,
+	})
+	if err != nil || len(endpoints) == 0 {
+		return err
+	}
+	body, err := json.Marshal(Payload{ID: e.ID, Type: e.Type, CreatedAt: e.CreatedAt, Data: e.Payload})
+	if err != nil {
+		return err
+	}
+	for _, endpoint := range endpoints {
+		_, err := d.db.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+			EndpointID: endpoint.ID,
+			EventID:    e.ID,
+			EventType:  e.Type,
+			Payload:    string(body),
+		})
+		// an endpoint deleted in the meantime doesn't need it any more
+		if err != nil && !isForeignKeyViolation(err) {
+			return err
+		}
+	}
+	d.Wake()
+	return nil
+}
+
+// isForeignKeyViolation reports whether err comes from a foreign key
+// constraint, from either store.
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23503"
+	}
+	return errors.Is(err, database.ErrForeignKeyViolation)
+}
+
+// Wake makes Run look for deliveries now rather than at the next poll.
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends deliveries until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		// keep going while there's a backlog
+		for {
+			n, err := d.Deliver(ctx)
+			if err != nil {
+				log.Printf("Error delivering webhooks: %v", err)
+			}
+			if err != nil || n < batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// Deliver claims one batch of due deliveries and sends them. It reports how
+// many it claimed.
+func (d *Dispatcher) Deliver(ctx context.Context) (int, error) {
+	deliveries, err := d.db.ClaimWebhookDeliveries(ctx, database.ClaimWebhookDeliveriesParams{
+		LockedUntil: time.Now().UTC().Add(lease),
+		BatchSize:   batchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, delivery := range deliveries {
+		endpoint, err := d.db.GetWebhookEndpoint(ctx, delivery.EndpointID)
+		if errors.Is(err, sql.ErrNoRows) {
+			// deleted since, and its deliveries with it
+			continue
+		}
+		if err != nil {
+			return len(deliveries), err
+		}
+		if err := d.attempt(ctx, endpoint, delivery); err != nil {
+			return len(deliveries), err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// attempt sends delivery to endpoint once and records the outcome. Only
+// database errors are returned, a failed request is part of the outcome.
+func (d *Dispatcher) attempt(ctx context.Context, endpoint database.WebhookEndpoint, delivery database.WebhookDelivery) error {
+	status, sendErr := d.send(ctx, endpoint, delivery)
+	params := database.FinishWebhookDeliveryParams{
+		ID:            delivery.ID,
+		Status:        StatusSucceeded,
+		NextAttemptAt: time.Now().UTC(),
+	}
+	if status != 0 {
+		params.ResponseStatus = sql.NullInt32{Int32: int32(status), Valid: true}
+	}
+	if sendErr == nil {
+		if err := d.db.FinishWebhookDelivery(ctx, params); err != nil {
+			return err
+		}
+		return d.db.RecordWebhookSuccess(ctx, endpoint.ID)
+	}
+
+	params.Status = StatusPending
+	if delivery.Attempts >= MaxAttempts {
+		params.Status = StatusFailed
+	}
+	params.NextAttemptAt = params.NextAttemptAt.Add(d.backoff(int(delivery.Attempts)))
+	params.LastError = sql.NullString{String: failureReason(status, sendErr), Valid: true}
+	if err := d.db.FinishWebhookDelivery(ctx, params); err != nil {
+		return err
+	}
+	updated, err := d.db.RecordWebhookFailure(ctx, database.RecordWebhookFailureParams{
+		DisableAfter: DisableAfter,
+		ID:           endpoint.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if endpoint.Enabled && !updated.Enabled {
+		log.Printf("Disabled webhook %s after %d failed attempts in a row", endpoint.ID, updated.FailureCount)
+	}
+	return nil
+}
+
+// send POSTs delivery to endpoint. Anything but a 2xx response is an error;
+// status is 0 when there was no response at all.
+func (d *Dispatcher) send(ctx context.Context, endpoint database.WebhookEndpoint, delivery database.WebhookDelivery) (status int, err error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, time.Now(), delivery.Payload))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	// drained so the connection can be reused, but nobody reads it
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("endpoint responded %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// backoff doubles the wait after every failed attempt, starting at
+// firstBackoff, up to maxBackoff.
+func backoff(attempt int) time.Duration {
+	if attempt > 12 {
+		return maxBackoff
+	}
+	return min(firstBackoff<<(attempt-1), maxBackoff)
+}
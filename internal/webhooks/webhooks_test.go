@@ -0,0 +1,355 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/outbox"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"1"}`)
+	header := Sign("secret", now, body)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    string
+		now     time.Time
+		wantErr bool
+	}{
+		{name: "valid", secret: "secret", header: header, body: string(body), now: now},
+		{name: "within tolerance", secret: "secret", header: header, body: string(body), now: now.Add(4 * time.Minute)},
+		{name: "too old", secret: "secret", header: header, body: string(body), now: now.Add(6 * time.Minute), wantErr: true},
+		{name: "wrong secret", secret: "other", header: header, body: string(body), now: now, wantErr: true},
+		{name: "tampered body", secret: "secret", header: header, body: `{"id":"2"}`, now: now, wantErr: true},
+		{name: "malformed", secret: "secret", header: "v1=abc", body: string(body), now: now, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, []byte(tt.body), 5*time.Minute, tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// receiver records the requests it gets and answers each with the next of
+// statuses, repeating the last one.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	status := rc.statuses[min(len(rc.requests), len(rc.statuses))-1]
+	w.WriteHeader(status)
+}
+
+// setup registers an endpoint for chirp.created pointing at rc.
+func setup(t *testing.T, rc *receiver) (*database.Memory, *Dispatcher, database.WebhookEndpoint) {
+	t.Helper()
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	db := database.NewMemory()
+	user, err := db.CreateUser(ctx, database.CreateUserParams{Email: "hooks@example.com", HashedPassword: "x"})
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	endpoint, err := db.CreateWebhookEndpoint(ctx, database.CreateWebhookEndpointParams{
+		UserID: uuid.NullUUID{UUID: user.ID, Valid: true},
+		Url:    srv.URL,
+		Secret: "secret",
+		Events: []string{outbox.ChirpCreated},
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhookEndpoint error: %v", err)
+	}
+	d := NewDispatcher(db)
+	d.backoff = func(int) time.Duration { return 0 }
+	// rc listens on loopback
+	d.AllowPrivateNetworks()
+	return db, d, endpoint
+}
+
+func TestDispatcher(t *testing.T) {
+	ctx := context.Background()
+	rc := &receiver{statuses: []int{500, 204}}
+	db, d, endpoint := setup(t, rc)
+
+	e := chirpEvent(endpoint.UserID.UUID)
+	// handed over twice, queued once
+	for range 2 {
+		if err := d.Enqueue(ctx, e); err != nil {
+			t.Fatalf("Enqueue error: %v", err)
+		}
+	}
+	// nobody subscribed to this one
+	if err := d.Enqueue(ctx, outbox.Event{ID: uuid.New(), Type: outbox.ChirpDeleted, Payload: e.Payload}); err != nil {
+		t.Fatalf("Enqueue error: %v", err)
+	}
+
+	for _, want := range []int{1, 1, 0} {
+		if n, err := d.Deliver(ctx); err != nil || n != want {
+			t.Fatalf("Deliver() = %d, %v; want %d", n, err, want)
+		}
+	}
+
+	if len(rc.requests) != 2 {
+		t.Fatalf("receiver got %d requests, want 2", len(rc.requests))
+	}
+	req, body := rc.requests[1], rc.bodies[1]
+	if got := req.Header.Get(HeaderEvent); got != outbox.ChirpCreated {
+		t.Errorf("%s = %q, want %q", HeaderEvent, got, outbox.ChirpCreated)
+	}
+	if err := Verify("secret", req.Header.Get(HeaderSignature), body, time.Minute, time.Now()); err != nil {
+		t.Errorf("Verify error: %v", err)
+	}
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("decoding payload: %v", err)
+	}
+	if payload.ID != e.ID || payload.Type != e.Type || string(payload.Data) != string(e.Payload) {
+		t.Errorf("payload = %+v, want event %+v", payload, e)
+	}
+
+	deliveries, err := db.ListWebhookDeliveries(ctx, database.ListWebhookDeliveriesParams{EndpointID: endpoint.ID, PageSize: 10})
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries error: %v", err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+	got := deliveries[0]
+	if got.ID.String() != req.Header.Get(HeaderDelivery) || got.Status != StatusSucceeded || got.Attempts != 2 || got.ResponseStatus.Int32 != 204 {
+		t.Errorf("delivery = %+v, want succeeded on attempt 2 with 204", got)
+	}
+}
+
+func TestDispatcherDisables(t *testing.T) {
+	ctx := context.Background()
+	rc := &receiver{statuses: []int{500}}
+	db, d, endpoint := setup(t, rc)
+
+	for range 2 {
+		if err := d.Enqueue(ctx, chirpEvent(endpoint.UserID.UUID)); err != nil {
+			t.Fatalf("Enqueue error: %v", err)
+		}
+	}
+	for {
+		n, err := d.Deliver(ctx)
+		if err != nil {
+			t.Fatalf("Deliver error: %v", err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+
+	// both deliveries use up their attempts, the last ones after the
+	// endpoint was disabled because they were already claimed
+	if len(rc.requests) != 2*MaxAttempts {
+		t.Errorf("receiver got %d requests, want %d", len(rc.requests), 2*MaxAttempts)
+	}
+	endpoint, err := db.GetWebhookEndpoint(ctx, endpoint.ID)
+	if err != nil {
+		t.Fatalf("GetWebhookEndpoint error: %v", err)
+	}
+	if endpoint.Enabled || !endpoint.DisabledAt.Valid {
+		t.Errorf("endpoint = %+v, want disabled", endpoint)
+	}
+
+	// nothing new is queued for a disabled endpoint
+	if err := d.Enqueue(ctx, chirpEvent(endpoint.UserID.UUID)); err != nil {
+		t.Fatalf("Enqueue error: %v", err)
+	}
+	deliveries, err := db.ListWebhookDeliveries(ctx, database.ListWebhookDeliveriesParams{EndpointID: endpoint.ID, PageSize: 10})
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries error: %v", err)
+	}
+	if len(deliveries) != 2 {
+		t.Fatalf("got %d deliveries, want 2", len(deliveries))
+	}
+	for _, got := range deliveries {
+		if got.Status != StatusFailed || got.Attempts != MaxAttempts {
+			t.Errorf("delivery = %+v, want failed after %d attempts", got, MaxAttempts)
+		}
+	}
+}
+
+func TestDispatcherOwnEventsOnly(t *testing.T) {
+	ctx := context.Background()
+	rc := &receiver{statuses: []int{204}}
+	db, d, mine := setup(t, rc)
+
+	other, err := db.CreateUser(ctx, database.CreateUserParams{Email: "other@example.com", HashedPassword: "x"})
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	register := func(owner uuid.NullUUID) database.WebhookEndpoint {
+		t.Helper()
+		endpoint, err := db.CreateWebhookEndpoint(ctx, database.CreateWebhookEndpointParams{
+			UserID: owner,
+			Url:    mine.Url,
+			Secret: "other-secret",
+			Events: []string{outbox.ChirpCreated, outbox.UserUpgraded},
+		})
+		if err != nil {
+			t.Fatalf("CreateWebhookEndpoint error: %v", err)
+		}
+		return endpoint
+	}
+	theirs := register(uuid.NullUUID{UUID: other.ID, Valid: true})
+	admins := register(uuid.NullUUID{})
+
+	// a chirp of the first user's, and both users upgrading
+	for _, e := range []outbox.Event{
+		chirpEvent(mine.UserID.UUID),
+		{ID: uuid.New(), Type: outbox.UserUpgraded, Payload: json.RawMessage(`{"user_id":"` + mine.UserID.UUID.String() + `"}`)},
+		{ID: uuid.New(), Type: outbox.UserUpgraded, Payload: json.RawMessage(`{"user_id":"` + other.ID.String() + `"}`)},
+	} {
+		if err := d.Enqueue(ctx, e); err != nil {
+			t.Fatalf("Enqueue %s error: %v", e.Type, err)
+		}
+	}
+
+	// the first endpoint only subscribed to chirp.created
+	for endpoint, want := range map[uuid.UUID]int{mine.ID: 1, theirs.ID: 1, admins.ID: 3} {
+		deliveries, err := db.ListWebhookDeliveries(ctx, database.ListWebhookDeliveriesParams{EndpointID: endpoint, PageSize: 10})
+		if err != nil {
+			t.Fatalf("ListWebhookDeliveries error: %v", err)
+		}
+		if len(deliveries) != want {
+			t.Errorf("endpoint %s got %d deliveries, want %d", endpoint, len(deliveries), want)
+		}
+	}
+}
+
+// chirpEvent is a chirp.created event for a chirp of userID's.
+func chirpEvent(userID uuid.UUID) outbox.Event {
+	payload, _ := json.Marshal(database.Chirp{ID: uuid.New(), Body: "hello", UserID: userID})
+	return outbox.Event{ID: uuid.New(), Type: outbox.ChirpCreated, Payload: payload, CreatedAt: time.Now().UTC()}
+}
+
+func TestDispatcherPrivateAddresses(t *testing.T) {
+	ctx := context.Background()
+	rc := &receiver{statuses: []int{204}}
+	db, _, endpoint := setup(t, rc)
+	d := NewDispatcher(db)
+
+	// registered by name, which only turns out to be loopback when dialing
+	port := endpoint.Url[strings.LastIndex(endpoint.Url, ":"):]
+	if err := db.DeleteWebhookEndpoint(ctx, endpoint.ID); err != nil {
+		t.Fatalf("DeleteWebhookEndpoint error: %v", err)
+	}
+	endpoint, err := db.CreateWebhookEndpoint(ctx, database.CreateWebhookEndpointParams{
+		UserID: endpoint.UserID,
+		Url:    "http://localhost" + port,
+		Secret: "secret",
+		Events: []string{outbox.ChirpCreated},
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhookEndpoint error: %v", err)
+	}
+	if err := d.Enqueue(ctx, chirpEvent(endpoint.UserID.UUID)); err != nil {
+		t.Fatalf("Enqueue error: %v", err)
+	}
+	if n, err := d.Deliver(ctx); err != nil || n != 1 {
+		t.Fatalf("Deliver() = %d, %v; want 1", n, err)
+	}
+
+	if len(rc.requests) != 0 {
+		t.Fatalf("receiver got %d requests, want none", len(rc.requests))
+	}
+	deliveries, err := db.ListWebhookDeliveries(ctx, database.ListWebhookDeliveriesParams{EndpointID: endpoint.ID, PageSize: 10})
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries error: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].LastError.String != "endpoint address is not allowed" {
+		t.Fatalf("deliveries = %+v, want one blocked", deliveries)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	d := NewDispatcher(nil)
+	tests := map[string]bool{
+		"https://example.com/hook":                 true,
+		"http://93.184.216.34/hook":                true,
+		"http://[2606:2800:220:1::1]/hook":         true,
+		"http://127.0.0.1:8080/":                   false,
+		"http://localhost/":                        false,
+		"http://api.localhost./":                   false,
+		"http://10.1.2.3/":                         false,
+		"http://172.16.0.1/":                       false,
+		"http://192.168.1.1/":                      false,
+		"http://169.254.169.254/latest/meta-data/": false,
+		"http://100.64.0.1/":                       false,
+		"http://0.0.0.0/":                          false,
+		"http://[::1]/":                            false,
+		"http://[fd00::1]/":                        false,
+		"http://[fe80::1%25eth0]/":                 false,
+		"http://[::ffff:127.0.0.1]/":               false,
+		"http://[64:ff9b::a9fe:a9fe]/":             false,
+		"http://224.0.0.1/":                        false,
+	}
+	for url, ok := range tests {
+		if err := d.CheckURL(url); (err == nil) != ok {
+			t.Errorf("CheckURL(%q) = %v, want ok %v", url, err, ok)
+		}
+	}
+	d.AllowPrivateNetworks()
+	if err := d.CheckURL("http://127.0.0.1:8080/"); err != nil {
+		t.Errorf("CheckURL with private networks allowed = %v", err)
+	}
+}
+
+func TestIsForeignKeyViolation(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: &pq.Error{Code: "23503"}, want: true},
+		{err: fmt.Errorf("creating delivery: %w", &pq.Error{Code: "23503"}), want: true},
+		{err: database.ErrForeignKeyViolation, want: true},
+		{err: &pq.Error{Code: "23505"}, want: false},
+		{err: errors.New("connection refused"), want: false},
+	}
+	for _, tt := range tests {
+		if got := isForeignKeyViolation(tt.err); got != tt.want {
+			t.Errorf("isForeignKeyViolation(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 5: 8 * time.Minute, 13: 6 * time.Hour, 100: 6 * time.Hour}
+	for attempt, want := range tests {
+		if got := backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/notifications"
+	"github.com/Cheemx/chirpy/internal/pagination"
+)
+
+func (cfg *apiConfig) handleLikeChirp(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// parse the chirpID to uuid format
+	chirpID, err := pathUUID(r, "chirpID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// make sure the chirp exists
+	chirp, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "Chirp"))
+		return
+	}
+
+	// liking twice is a no-op, PUT is idempotent
+	liked, err := cfg.db.LikeChirp(r.Context(), database.LikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	if liked > 0 {
+		cfg.notify(r.Context(), notifications.Event{
+			Type:      notifications.Like,
+			Recipient: chirp.UserID,
+			Actor:     userID,
+			ChirpID:   chirp.ID,
+		})
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handleUnlikeChirp(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// parse the chirpID to uuid format
+	chirpID, err := pathUUID(r, "chirpID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	_, err = cfg.db.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handleGetUserLikes(w http.ResponseWriter, r *http.Request) {
+	// parse the userID to uuid format
+	userID, err := pathUUID(r, "userID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// make sure the user exists
+	_, err = cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "User"))
+		return
+	}
+
+	// most recently liked first
+	params := database.ListUserLikesParams{UserID: userID, PageSize: page.FetchSize()}
+	params.AfterCreatedAt, params.AfterID = page.After()
+	rows, err := cfg.db.ListUserLikes(r.Context(), params)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Creating response Body
+	res := struct {
+		Chirps     []chirpResponse `json:"chirps"`
+		NextCursor string          `json:"next_cursor,omitempty"`
+	}{}
+	rows, res.NextCursor = pagination.Trim(page, rows, func(row database.ListUserLikesRow) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: row.LikedAt, ID: row.Chirp.ID}
+	})
+	if res.NextCursor != "" {
+		w.Header().Set("Link", pagination.NextLink(r.URL, res.NextCursor))
+	}
+
+	chirps := make([]database.Chirp, len(rows))
+	for i, row := range rows {
+		chirps[i] = row.Chirp
+	}
+	res.Chirps, err = cfg.presentChirps(r.Context(), cfg.viewerID(r), chirps)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestLikes(t *testing.T) {
+	srv := newTestServer(t)
+	cheems := createTestUser(t, srv, "cheems@example.com")
+	doge := createTestUser(t, srv, "doge@example.com")
+
+	chirp := postChirp(t, srv, cheems.Token, map[string]string{"body": "like me"}, 201)
+	like := "/api/chirps/" + chirp.ID.String() + "/like"
+
+	for _, user := range []testUser{cheems, doge, doge} {
+		if res := doJSON(t, srv, "PUT", like, user.Token, nil); res.StatusCode != 204 {
+			t.Fatalf("like: got %d want 204", res.StatusCode)
+		}
+	}
+	if res := doJSON(t, srv, "PUT", "/api/chirps/"+uuid.NewString()+"/like", doge.Token, nil); res.StatusCode != 404 {
+		t.Fatalf("like missing chirp: got %d want 404", res.StatusCode)
+	}
+
+	getChirp := func(token string) chirpResponse {
+		t.Helper()
+		res := doJSON(t, srv, "GET", "/api/chirps/"+chirp.ID.String(), token, nil)
+		var got chirpResponse
+		decodeBody(t, res, &got)
+		return got
+	}
+	if got := getChirp(doge.Token); got.LikeCount != 2 || !got.LikedByMe {
+		t.Fatalf("as doge: like_count %d liked_by_me %v", got.LikeCount, got.LikedByMe)
+	}
+	if got := getChirp(""); got.LikedByMe {
+		t.Fatalf("anonymous viewer reported as liking the chirp")
+	}
+
+	page, _ := getChirpsPage(t, srv, "/api/users/"+doge.ID.String()+"/likes")
+	if len(page.Chirps) != 1 || page.Chirps[0].ID != chirp.ID {
+		t.Fatalf("doge likes: %+v", page.Chirps)
+	}
+
+	if res := doJSON(t, srv, "DELETE", like, doge.Token, nil); res.StatusCode != 204 {
+		t.Fatalf("unlike: got %d want 204", res.StatusCode)
+	}
+	if got := getChirp(doge.Token); got.LikeCount != 1 || got.LikedByMe {
+		t.Fatalf("after unlike: like_count %d liked_by_me %v", got.LikeCount, got.LikedByMe)
+	}
+}
@@ -1,13 +1,28 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"sync/atomic"
+	"time"
 
+	"github.com/Cheemx/chirpy/internal/broker"
 	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/entitlements"
+	"github.com/Cheemx/chirpy/internal/eventbus"
+	"github.com/Cheemx/chirpy/internal/media"
+	"github.com/Cheemx/chirpy/internal/moderation"
+	"github.com/Cheemx/chirpy/internal/notifications"
+	"github.com/Cheemx/chirpy/internal/outbox"
+	"github.com/Cheemx/chirpy/internal/ratelimit"
+	"github.com/Cheemx/chirpy/internal/scheduler"
+	"github.com/Cheemx/chirpy/internal/trash"
+	"github.com/Cheemx/chirpy/internal/webhooks"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
@@ -17,10 +32,50 @@ import (
 // across multiple goroutines (HTTP requests).
 type apiConfig struct {
 	fileServerHits atomic.Int32
-	db             *database.Queries
+	db             database.Store
 	user           *database.User
 	jwtSecret      string
 	polkaKey       string
+	polkaSecret    string
+	adminKey       string
+
+	// moderator screens chirp bodies; bannedWords is its word list, kept
+	// here so the admin endpoints can edit it at runtime.
+	moderator   *moderation.Pipeline
+	bannedWords *moderation.WordList
+
+	notifier *notifications.Notifier
+
+	// entitlements decides what each user's plan lets them do.
+	entitlements *entitlements.Entitlements
+	// limiter counts each user's writes, see middlewareRateLimit.
+	limiter *ratelimit.Limiter
+
+	// editWindow is how long after posting a chirp its author may edit it.
+	editWindow time.Duration
+
+	// trashRetention is how long deleted chirps and accounts can be
+	// restored before purger removes them for good.
+	trashRetention time.Duration
+	purger         *trash.Purger
+
+	// scheduler publishes drafts when their publish_at comes round.
+	scheduler *scheduler.Scheduler
+
+	// blobs keeps uploaded media, mediaSigner signs the URLs they're served
+	// from.
+	blobs       media.BlobStore
+	mediaSigner *media.Signer
+
+	// events reaches every instance of the server, chirpEvents the streams
+	// of this one. See relayEvents.
+	events      eventbus.Bus
+	chirpEvents *broker.Broker[chirpEvent]
+
+	// outbox delivers the events recorded with database changes, webhooks
+	// pass them on to the endpoints users registered.
+	outbox   *outbox.Relay
+	webhooks *webhooks.Dispatcher
 }
 
 const (
@@ -31,29 +86,135 @@ const (
 func main() {
 	godotenv.Load()
 
+	var store database.Store
+	var events eventbus.Bus
 	dbURL := os.Getenv("DB_URL")
-	if dbURL == "" {
+	switch {
+	case dbURL != "":
+		db, err := sql.Open("postgres", dbURL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		store = database.New(db)
+		// every instance on the same database shares events through it
+		events, err = eventbus.NewPostgres(db, dbURL)
+		if err != nil {
+			log.Fatalf("Error listening for events: %v", err)
+		}
+	case os.Getenv("PLATFORM") == "dev":
+		// no database configured, keep everything in memory for local demos
+		log.Print("DB_URL not set, using in-memory store")
+		store = database.NewMemory()
+		events = eventbus.NewLocal()
+	default:
 		log.Fatal("Can't get DB_URL from .env")
 	}
 
-	db, err := sql.Open("postgres", dbURL)
+	cfg, err := newAPIConfig(context.Background(), store, events)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("Error %v", err)
 	}
-
-	dbQueries := database.New(db)
-
-	mux := &http.ServeMux{}
+	cfg.start(context.Background())
 	server := &http.Server{
 		Addr:    ":" + port,
-		Handler: mux,
+		Handler: cfg.routes(),
+	}
+
+	// Starting the Server
+	log.Printf("Serving files from %s on port: %s\n", filePathRoot, port)
+	log.Fatal(server.ListenAndServe())
+}
+
+// newAPIConfig sets up the server around store and events, reading the rest
+// of its configuration from the environment. Tests build theirs with it too.
+func newAPIConfig(ctx context.Context, store database.Store, events eventbus.Bus) (*apiConfig, error) {
+	// Polka's requests change what users pay for, they must be signed
+	if os.Getenv("POLKA_SIGNING_SECRET") == "" {
+		return nil, errors.New("reading Polka settings: POLKA_SIGNING_SECRET is required")
 	}
+	moderator, bannedWords, err := newModerator(ctx, store)
+	if err != nil {
+		return nil, fmt.Errorf("setting up moderation: %w", err)
+	}
+	plans, err := newEntitlements()
+	if err != nil {
+		return nil, fmt.Errorf("reading plans: %w", err)
+	}
+	editWindow, err := chirpEditWindow()
+	if err != nil {
+		return nil, fmt.Errorf("reading edit window: %w", err)
+	}
+	retention, err := trashRetention()
+	if err != nil {
+		return nil, fmt.Errorf("reading trash retention: %w", err)
+	}
+	blobs, err := newBlobStore()
+	if err != nil {
+		return nil, fmt.Errorf("setting up media storage: %w", err)
+	}
+	// media URLs have a secret of their own, derived from the JWT secret
+	// unless one is set. Without either, the key would be one anyone can
+	// derive too
+	var mediaSigner *media.Signer
+	switch {
+	case os.Getenv("MEDIA_SIGNING_SECRET") != "":
+		mediaSigner = media.NewSigner(os.Getenv("MEDIA_SIGNING_SECRET"))
+	case os.Getenv("SECRET") != "":
+		mediaSigner, err = media.DerivedSigner(os.Getenv("SECRET"))
+		if err != nil {
+			return nil, fmt.Errorf("deriving the media signing secret: %w", err)
+		}
+	default:
+		return nil, errors.New("reading media settings: MEDIA_SIGNING_SECRET or SECRET is required")
+	}
+
+	dispatcher := webhooks.NewDispatcher(store)
+	// for trying webhooks out against a receiver on this machine
+	if os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS") == "true" {
+		dispatcher.AllowPrivateNetworks()
+	}
+
 	cfg := &apiConfig{
 		fileServerHits: atomic.Int32{},
-		db:             dbQueries,
+		db:             store,
 		jwtSecret:      os.Getenv("SECRET"),
 		polkaKey:       os.Getenv("POLKA_KEY"),
+		polkaSecret:    os.Getenv("POLKA_SIGNING_SECRET"),
+		adminKey:       os.Getenv("ADMIN_KEY"),
+		moderator:      moderator,
+		bannedWords:    bannedWords,
+		notifier:       notifications.NewNotifier(store),
+		entitlements:   plans,
+		limiter:        ratelimit.New(),
+		editWindow:     editWindow,
+		trashRetention: retention,
+		purger:         trash.NewPurger(store, blobs, retention),
+		events:         events,
+		chirpEvents:    newChirpBroker(),
+		outbox:         outbox.NewRelay(store),
+		webhooks:       dispatcher,
+		blobs:          blobs,
+		mediaSigner:    mediaSigner,
 	}
+	cfg.scheduler = scheduler.New(store, cfg.publishScheduled)
+	return cfg, nil
+}
+
+// start wires the event plumbing together and runs the background workers
+// until ctx is cancelled.
+func (cfg *apiConfig) start(ctx context.Context) {
+	cfg.relayEvents()
+	cfg.outbox.Subscribe("webhooks", cfg.webhooks.Enqueue)
+	go cfg.outbox.Run(ctx)
+	go cfg.webhooks.Run(ctx)
+	go cfg.purger.Run(ctx)
+	go cfg.scheduler.Run(ctx)
+}
+
+// routes registers every endpoint on a fresh mux, so tests can serve the
+// whole API from an apiConfig backed by any database.Store.
+func (cfg *apiConfig) routes() *http.ServeMux {
+	mux := &http.ServeMux{}
 
 	// /app route handler to increment hits
 	appHandler := http.StripPrefix("/app", http.FileServer(http.Dir(filePathRoot)))
@@ -61,6 +222,9 @@ func main() {
 	mux.Handle("/app/", wrapped)
 	mux.Handle("/app", wrapped)
 
+	// Uploaded media, through the signed URLs Chirps carry
+	mux.HandleFunc("GET /media/{key}", cfg.handleServeMedia)
+
 	// API health checker
 	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(200)
@@ -74,14 +238,29 @@ func main() {
 	// reset fileServerHits in cfg
 	mux.HandleFunc("POST /admin/reset", cfg.handleReset)
 
+	// Manage the banned word list and review flagged Chirps
+	mux.HandleFunc("GET /admin/moderation/words", cfg.handleGetBannedWords)
+	mux.HandleFunc("POST /admin/moderation/words", cfg.handleAddBannedWord)
+	mux.HandleFunc("DELETE /admin/moderation/words/{word}", cfg.handleDeleteBannedWord)
+	mux.HandleFunc("GET /admin/moderation/flags", cfg.handleGetModerationFlags)
+
+	// A User's Chirpy Red history
+	mux.HandleFunc("GET /admin/users/{userID}/red-status", cfg.handleGetRedStatusHistory)
+
 	// Create Chirp endpoint
-	mux.HandleFunc("POST /api/chirps", cfg.handleCreateChirp)
+	mux.HandleFunc("POST /api/chirps", cfg.middlewareRateLimit(cfg.handleCreateChirp))
+
+	// Upload an image to attach to a Chirp
+	mux.HandleFunc("POST /api/media", cfg.middlewareRateLimit(cfg.handleUploadMedia))
 
 	// Create User endpoint
 	mux.HandleFunc("POST /api/users", cfg.handleCreateUser)
 
 	// Update User endpoint
-	mux.HandleFunc("PUT /api/users", cfg.handleUpdateUser)
+	mux.HandleFunc("PUT /api/users", cfg.middlewareRateLimit(cfg.handleUpdateUser))
+
+	// Delete the authenticated User, until they log in again
+	mux.HandleFunc("DELETE /api/users", cfg.middlewareRateLimit(cfg.handleDeleteUser))
 
 	// Login User endpoint
 	mux.HandleFunc("POST /api/login", cfg.handleLoginUser)
@@ -92,8 +271,75 @@ func main() {
 	// Revoke the Refresh Token
 	mux.HandleFunc("POST /api/revoke", cfg.handleRevokeRefreshToken)
 
-	// Update User to Red Endpoint
-	mux.HandleFunc("POST /api/polka/webhooks", cfg.handleUpdateUserToRed)
+	// Public profile of a User
+	mux.HandleFunc("GET /api/users/{username}", cfg.handleGetUserProfile)
+
+	// Follow and Unfollow a User
+	mux.HandleFunc("POST /api/users/{userID}/follow", cfg.middlewareRateLimit(cfg.handleFollowUser))
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", cfg.middlewareRateLimit(cfg.handleUnfollowUser))
+
+	// List who follows a User and who they follow
+	mux.HandleFunc("GET /api/users/{userID}/followers", cfg.handleGetFollowers)
+	mux.HandleFunc("GET /api/users/{userID}/following", cfg.handleGetFollowing)
+
+	// Chirps a User has liked
+	mux.HandleFunc("GET /api/users/{userID}/likes", cfg.handleGetUserLikes)
+
+	// Browse Chirps by hashtag and see what's trending
+	mux.HandleFunc("GET /api/tags/trending", cfg.handleGetTrendingTags)
+	mux.HandleFunc("GET /api/tags/{tag}/chirps", cfg.handleGetTagChirps)
+
+	// Chirps that @mention the caller
+	mux.HandleFunc("GET /api/mentions", cfg.handleGetMentions)
+
+	// Notification inbox of the authenticated User
+	mux.HandleFunc("GET /api/notifications", cfg.handleGetNotifications)
+	mux.HandleFunc("POST /api/notifications/read", cfg.middlewareRateLimit(cfg.handleMarkNotificationsRead))
+	mux.HandleFunc("GET /api/notifications/preferences", cfg.handleGetNotificationPrefs)
+	mux.HandleFunc("PUT /api/notifications/preferences", cfg.middlewareRateLimit(cfg.handleUpdateNotificationPrefs))
+
+	// Live stream of created and deleted Chirps
+	mux.HandleFunc("GET /api/stream/chirps", cfg.handleStreamChirps)
+
+	// WebSocket for the live timeline, notifications and threads
+	mux.HandleFunc("GET /api/ws", cfg.handleWebSocket)
+
+	// Home timeline of the authenticated User
+	mux.HandleFunc("GET /api/timeline", cfg.handleGetTimeline)
+
+	// What the authenticated User's plan includes
+	mux.HandleFunc("GET /api/entitlements", cfg.handleGetEntitlements)
+
+	// Polka webhooks, upgrading users to Red and back
+	mux.HandleFunc("POST /api/polka/webhooks", cfg.handlePolkaWebhook)
+
+	// Outbound webhooks of the authenticated User
+	mux.HandleFunc("POST /api/webhooks", cfg.middlewareRateLimit(cfg.handleCreateWebhook))
+	mux.HandleFunc("GET /api/webhooks", cfg.handleGetWebhooks)
+	mux.HandleFunc("GET /api/webhooks/{webhookID}", cfg.handleGetWebhook)
+	mux.HandleFunc("PUT /api/webhooks/{webhookID}", cfg.middlewareRateLimit(cfg.handleUpdateWebhook))
+	mux.HandleFunc("DELETE /api/webhooks/{webhookID}", cfg.middlewareRateLimit(cfg.handleDeleteWebhook))
+
+	// Delivery log of a webhook, and redelivering one
+	mux.HandleFunc("GET /api/webhooks/{webhookID}/deliveries", cfg.handleGetWebhookDeliveries)
+	mux.HandleFunc("POST /api/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", cfg.middlewareRateLimit(cfg.handleRedeliverWebhook))
+
+	// Outbound webhooks of the admins, hearing about every User
+	mux.HandleFunc("POST /admin/webhooks", cfg.handleCreateWebhook)
+	mux.HandleFunc("GET /admin/webhooks", cfg.handleGetWebhooks)
+	mux.HandleFunc("GET /admin/webhooks/{webhookID}", cfg.handleGetWebhook)
+	mux.HandleFunc("PUT /admin/webhooks/{webhookID}", cfg.handleUpdateWebhook)
+	mux.HandleFunc("DELETE /admin/webhooks/{webhookID}", cfg.handleDeleteWebhook)
+	mux.HandleFunc("GET /admin/webhooks/{webhookID}/deliveries", cfg.handleGetWebhookDeliveries)
+	mux.HandleFunc("POST /admin/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", cfg.handleRedeliverWebhook)
+
+	// Drafts of the authenticated User, scheduling and publishing them
+	mux.HandleFunc("POST /api/drafts", cfg.middlewareRateLimit(cfg.handleCreateDraft))
+	mux.HandleFunc("GET /api/drafts", cfg.handleGetDrafts)
+	mux.HandleFunc("GET /api/drafts/{draftID}", cfg.handleGetDraft)
+	mux.HandleFunc("PUT /api/drafts/{draftID}", cfg.middlewareRateLimit(cfg.handleUpdateDraft))
+	mux.HandleFunc("DELETE /api/drafts/{draftID}", cfg.middlewareRateLimit(cfg.handleDeleteDraft))
+	mux.HandleFunc("POST /api/drafts/{draftID}/publish", cfg.middlewareRateLimit(cfg.handlePublishDraft))
 
 	// Get AllChirps endpoint
 	mux.HandleFunc("GET /api/chirps", cfg.handleGetChirps)
@@ -101,10 +347,29 @@ func main() {
 	// Get Chirp by ID
 	mux.HandleFunc("GET /api/chirps/{chirpID}", cfg.handleGetChirpByID)
 
+	// Edit a Chirp, and see what it said before
+	mux.HandleFunc("PATCH /api/chirps/{chirpID}", cfg.middlewareRateLimit(cfg.handleEditChirp))
+	mux.HandleFunc("GET /api/chirps/{chirpID}/history", cfg.handleGetChirpHistory)
+
+	// Get direct replies to a Chirp
+	mux.HandleFunc("GET /api/chirps/{chirpID}/replies", cfg.handleGetChirpReplies)
+
+	// Get the conversation tree below a Chirp
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", cfg.handleGetChirpThread)
+
+	// Vote in a Chirp's poll
+	mux.HandleFunc("POST /api/chirps/{chirpID}/poll/votes", cfg.middlewareRateLimit(cfg.handleVotePoll))
+
+	// Like and Unlike a Chirp
+	mux.HandleFunc("PUT /api/chirps/{chirpID}/like", cfg.middlewareRateLimit(cfg.handleLikeChirp))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", cfg.middlewareRateLimit(cfg.handleUnlikeChirp))
+
 	// Delete Chirp by ID
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", cfg.handleDeleteChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", cfg.middlewareRateLimit(cfg.handleDeleteChirp))
 
-	// Starting the Server
-	log.Printf("Serving files from %s on port: %s\n", filePathRoot, port)
-	log.Fatal(server.ListenAndServe())
+	// Deleted Chirps of the authenticated User, and restoring one
+	mux.HandleFunc("GET /api/trash", cfg.handleGetTrash)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/restore", cfg.middlewareRateLimit(cfg.handleRestoreChirp))
+
+	return mux
 }
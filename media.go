@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/media"
+	"github.com/Cheemx/chirpy/internal/validation"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxMediaBytes caps an uploaded file.
+	maxMediaBytes = 5 << 20
+	// maxChirpMedia caps how many uploads one chirp can attach.
+	maxChirpMedia = 4
+	// maxPendingMedia caps how many uploads one user can have waiting to be
+	// attached to a chirp. The trash purger deletes those left waiting for
+	// trash.PendingMediaTTL.
+	maxPendingMedia = 20
+	// mediaURLLifetime is the least time a signed media URL works for.
+	mediaURLLifetime = time.Hour
+)
+
+// mediaResponse is an upload, with signed URLs to fetch it from.
+type mediaResponse struct {
+	ID           uuid.UUID `json:"id"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	SizeBytes    int64     `json:"size_bytes"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// newBlobStore reads where uploads are kept from MEDIA_DIR. Without it they
+// go to a temporary directory, which is fine for local demos only. It
+// shouldn't be inside filePathRoot, or /app would serve uploads unsigned.
+func newBlobStore() (media.BlobStore, error) {
+	dir := os.Getenv("MEDIA_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "chirpy-media")
+		log.Printf("MEDIA_DIR not set, keeping uploads in %s", dir)
+	}
+	return media.NewFSStore(dir)
+}
+
+// mediaURL signs a URL for the blob under key. URLs expire at the end of the
+// next whole mediaURLLifetime, so they stay the same for a while and clients
+// can cache what they point at.
+func (cfg *apiConfig) mediaURL(key string) string {
+	expires := time.Now().Truncate(mediaURLLifetime).Add(2 * mediaURLLifetime)
+	return "/media/" + key + "?" + cfg.mediaSigner.Sign(key, expires).Encode()
+}
+
+func (cfg *apiConfig) newMediaResponse(medium database.Medium) mediaResponse {
+	return mediaResponse{
+		ID:           medium.ID,
+		ContentType:  medium.ContentType,
+		Width:        medium.Width,
+		Height:       medium.Height,
+		SizeBytes:    medium.SizeBytes,
+		URL:          cfg.mediaURL(media.Key(medium.ID, medium.ContentType)),
+		ThumbnailURL: cfg.mediaURL(media.ThumbnailKey(medium.ID, medium.ContentType)),
+		CreatedAt:    medium.CreatedAt,
+	}
+}
+
+// chirpMedia loads the media attached to a batch of chirps, keyed by chirp
+// ID, in the order they were attached.
+func (cfg *apiConfig) chirpMedia(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]mediaResponse, error) {
+	rows, err := cfg.db.ListChirpMedia(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := map[uuid.UUID][]mediaResponse{}
+	for _, medium := range rows {
+		res[medium.ChirpID.UUID] = append(res[medium.ChirpID.UUID], cfg.newMediaResponse(medium))
+	}
+	return res, nil
+}
+
+// checkChirpMedia makes sure a new chirp of userID's can attach the media
+// listed in media_ids: their own uploads, not attached to anything yet.
+func (cfg *apiConfig) checkChirpMedia(ctx context.Context, db database.Store, userID uuid.UUID, ids []string) ([]uuid.UUID, error) {
+	if len(ids) > maxChirpMedia {
+		return nil, httpapi.ValidationFailed(validation.Errors{{
+			Field:   "media_ids",
+			Code:    validation.CodeInvalid,
+			Message: fmt.Sprintf("A Chirp can have at most %d media", maxChirpMedia),
+		}})
+	}
+	var mediaIDs []uuid.UUID
+	for _, s := range ids {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, httpapi.BadRequest(httpapi.CodeInvalidParameter, "media_ids has an invalid ID")
+		}
+		for _, seen := range mediaIDs {
+			if seen == id {
+				return nil, httpapi.BadRequest(httpapi.CodeInvalidParameter, "media_ids lists the same media twice")
+			}
+		}
+		// other users' uploads are as good as missing
+		medium, err := db.GetMedia(ctx, id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if err != nil || medium.UserID != userID {
+			return nil, httpapi.BadRequest(httpapi.CodeInvalidParameter, "media_ids refers to media that doesn't exist")
+		}
+		if medium.ChirpID.Valid {
+			return nil, httpapi.Conflict("Media is already attached to a Chirp")
+		}
+		mediaIDs = append(mediaIDs, id)
+	}
+	return mediaIDs, nil
+}
+
+// attachMedia attaches a new chirp's media to it, in order. tx should be a
+// transaction.
+func attachMedia(ctx context.Context, tx database.Store, chirp database.Chirp, mediaIDs []uuid.UUID) error {
+	for i, id := range mediaIDs {
+		n, err := tx.AttachMedia(ctx, database.AttachMediaParams{
+			ChirpID:  chirp.ID,
+			Position: int32(i),
+			ID:       id,
+			UserID:   chirp.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		// another chirp got there first
+		if n == 0 {
+			return httpapi.Conflict("Media is already attached to a Chirp")
+		}
+	}
+	return nil
+}
+
+// readUpload reads the file in the "file" field of a multipart upload.
+func readUpload(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return nil, &httpapi.Error{Status: 415, Code: httpapi.CodeUnsupportedMediaType, Message: "Content-Type must be multipart/form-data"}
+	}
+
+	// room for the rest of the form around the file
+	r.Body = http.MaxBytesReader(w, r.Body, maxMediaBytes+httpapi.MaxBodyBytes)
+	tooLarge := &httpapi.Error{Status: 413, Code: httpapi.CodeBodyTooLarge, Message: fmt.Sprintf("Uploads must be at most %d bytes", maxMediaBytes)}
+	formError := func(err error) error {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return tooLarge
+		}
+		return httpapi.BadRequest(httpapi.CodeInvalidParameter, "Request body is not a valid multipart form")
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, formError(err)
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, formError(err)
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(part, maxMediaBytes+1))
+		if err != nil {
+			return nil, formError(err)
+		}
+		if len(data) > maxMediaBytes {
+			return nil, tooLarge
+		}
+		return data, nil
+	}
+	return nil, httpapi.ValidationFailed(validation.Errors{{Field: "file", Code: validation.CodeRequired, Message: "file is required"}})
+}
+
+func (cfg *apiConfig) handleUploadMedia(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	pending, err := cfg.db.CountPendingMedia(r.Context(), userID)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	if pending >= maxPendingMedia {
+		httpapi.RespondWithError(w, httpapi.Conflict(fmt.Sprintf("You can have at most %d uploads not attached to a chirp", maxPendingMedia)))
+		return
+	}
+
+	data, err := readUpload(w, r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// whatever the client says, the bytes decide what the file is; it's
+	// re-encoded without its metadata
+	img, err := media.Process(data)
+	switch {
+	case errors.Is(err, media.ErrUnsupportedType):
+		httpapi.RespondWithError(w, &httpapi.Error{Status: 415, Code: httpapi.CodeUnsupportedMediaType, Message: "Only JPEG, PNG and GIF images can be uploaded"})
+		return
+	case errors.Is(err, media.ErrTooManyPixels):
+		httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, fmt.Sprintf("Images can have at most %d pixels", media.MaxPixels)))
+		return
+	case errors.Is(err, media.ErrInvalidImage):
+		httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, "file is not a valid image"))
+		return
+	case err != nil:
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Storing the image and its thumbnail before recording them, and
+	// cleaning up if that fails
+	id := uuid.New()
+	key := media.Key(id, img.ContentType)
+	thumbKey := media.ThumbnailKey(id, img.ContentType)
+	err = cfg.blobs.Put(r.Context(), key, img.ContentType, bytes.NewReader(img.Data))
+	if err == nil {
+		err = cfg.blobs.Put(r.Context(), thumbKey, img.ThumbnailType, bytes.NewReader(img.Thumbnail))
+	}
+	var medium database.Medium
+	if err == nil {
+		medium, err = cfg.db.CreateMedia(r.Context(), database.CreateMediaParams{
+			ID:          id,
+			UserID:      userID,
+			ContentType: img.ContentType,
+			Width:       int32(img.Width),
+			Height:      int32(img.Height),
+			SizeBytes:   int64(len(img.Data)),
+		})
+	}
+	if err != nil {
+		for _, k := range []string{key, thumbKey} {
+			if err := cfg.blobs.Delete(r.Context(), k); err != nil {
+				log.Printf("Error deleting blob %s: %v", k, err)
+			}
+		}
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 201, cfg.newMediaResponse(medium))
+}
+
+func (cfg *apiConfig) handleServeMedia(w http.ResponseWriter, r *http.Request) {
+	// the signature is all the authorization there is
+	key := r.PathValue("key")
+	query := r.URL.Query()
+	err := cfg.mediaSigner.Verify(key, query, time.Now())
+	if err != nil {
+		httpapi.RespondWithError(w, &httpapi.Error{Status: 403, Code: httpapi.CodeForbidden, Message: "Media URL is invalid or expired", Err: err})
+		return
+	}
+
+	blob, err := cfg.blobs.Get(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, media.ErrNotFound) {
+			httpapi.RespondWithError(w, httpapi.NotFound("Media not found"))
+			return
+		}
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	defer blob.Close()
+
+	// cacheable for as long as the URL works
+	expires, _ := strconv.ParseInt(query.Get("expires"), 10, 64)
+	maxAge := int(time.Until(time.Unix(expires, 0)).Seconds())
+	w.Header().Set("Content-Type", blob.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(blob.Size, 10))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+	w.WriteHeader(200)
+	_, err = io.Copy(w, blob)
+	if err != nil {
+		log.Printf("Error serving media %s: %v", key, err)
+	}
+}
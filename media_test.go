@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/eventbus"
+	"github.com/Cheemx/chirpy/internal/media"
+	"github.com/google/uuid"
+)
+
+func TestMedia(t *testing.T) {
+	srv := newTestServer(t)
+	cheems := createTestUser(t, srv, "cheems@example.com")
+	doge := createTestUser(t, srv, "doge@example.com")
+
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 640, 480)))
+	if err != nil {
+		t.Fatalf("png.Encode error: %v", err)
+	}
+	pic := buf.Bytes()
+
+	upload := func(user testUser, data []byte, want int) mediaResponse {
+		t.Helper()
+		res := doUpload(t, srv, user.Token, "picture.png", data)
+		var uploaded mediaResponse
+		if res.StatusCode != want {
+			res.Body.Close()
+			t.Fatalf("upload %d bytes as %s: got %d want %d", len(data), user.Email, res.StatusCode, want)
+		}
+		if want == 201 {
+			decodeBody(t, res, &uploaded)
+		} else {
+			res.Body.Close()
+		}
+		return uploaded
+	}
+	// the bytes decide the type, not the file name
+	upload(cheems, []byte("not a picture at all"), 415)
+	upload(cheems, append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...), 400)
+	upload(cheems, append(bytes.Clone(pic), make([]byte, maxMediaBytes)...), 413)
+	res := doJSON(t, srv, "POST", "/api/media", cheems.Token, map[string]string{"file": "picture.png"})
+	res.Body.Close()
+	if res.StatusCode != 415 {
+		t.Fatalf("upload as JSON: got %d want 415", res.StatusCode)
+	}
+	res = doUpload(t, srv, "", "picture.png", pic)
+	res.Body.Close()
+	if res.StatusCode != 401 {
+		t.Fatalf("upload without a token: got %d want 401", res.StatusCode)
+	}
+
+	uploaded := upload(cheems, pic, 201)
+	if uploaded.ContentType != "image/png" || uploaded.Width != 640 || uploaded.Height != 480 {
+		t.Fatalf("uploaded = %+v", uploaded)
+	}
+	fetch := func(url string, want int) *http.Response {
+		t.Helper()
+		res, err := srv.Client().Get(srv.URL + url)
+		if err != nil {
+			t.Fatalf("GET %s: %v", url, err)
+		}
+		t.Cleanup(func() { res.Body.Close() })
+		if res.StatusCode != want {
+			t.Fatalf("GET %s: got %d want %d", url, res.StatusCode, want)
+		}
+		return res
+	}
+	res = fetch(uploaded.ThumbnailURL, 200)
+	thumb, err := png.DecodeConfig(res.Body)
+	if err != nil || res.Header.Get("Content-Type") != "image/png" || thumb.Width != media.ThumbnailSize || thumb.Height != 240 {
+		t.Fatalf("thumbnail = %+v (%v), Content-Type %q", thumb, err, res.Header.Get("Content-Type"))
+	}
+	fetch(strings.Replace(uploaded.URL, "sig=", "sig=0", 1), 403)
+	fetch("/media/"+uploaded.ID.String()+".png", 403)
+
+	id := uploaded.ID.String()
+	postChirp(t, srv, doge.Token, map[string]any{"body": "mine now", "media_ids": []string{id}}, 400)
+	postChirp(t, srv, cheems.Token, map[string]any{"body": "twice", "media_ids": []string{id, id}}, 400)
+	postChirp(t, srv, cheems.Token, map[string]any{"body": "missing", "media_ids": []string{uuid.NewString()}}, 400)
+	postChirp(t, srv, cheems.Token, map[string]any{"body": "too many", "media_ids": []string{id, uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString()}}, 400)
+
+	second := upload(cheems, pic, 201)
+	chirp := postChirp(t, srv, cheems.Token, map[string]any{"body": "look", "media_ids": []string{second.ID.String(), id}}, 201)
+	if len(chirp.Media) != 2 || chirp.Media[0].ID != second.ID || chirp.Media[1].ID != uploaded.ID {
+		t.Fatalf("chirp media = %+v", chirp.Media)
+	}
+	postChirp(t, srv, cheems.Token, map[string]any{"body": "again", "media_ids": []string{id}}, 409)
+
+	res = doJSON(t, srv, "GET", "/api/chirps/"+chirp.ID.String(), "", nil)
+	var got chirpResponse
+	decodeBody(t, res, &got)
+	if len(got.Media) != 2 {
+		t.Fatalf("fetched chirp media = %+v", got.Media)
+	}
+	res = fetch(got.Media[1].URL, 200)
+	data, err := io.ReadAll(res.Body)
+	if err != nil || res.Header.Get("Content-Type") != "image/png" || res.Header.Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("media: %v, headers %v", err, res.Header)
+	}
+	if config, err := png.DecodeConfig(bytes.NewReader(data)); err != nil || config.Width != 640 {
+		t.Fatalf("media = %+v (%v)", config, err)
+	}
+
+	plain := postChirp(t, srv, doge.Token, map[string]any{"body": "no pictures"}, 201)
+	if plain.Media == nil || len(plain.Media) != 0 {
+		t.Fatalf("plain chirp media = %#v, want []", plain.Media)
+	}
+
+	// uploads waiting for a chirp are capped, and attaching one makes room
+	buf.Reset()
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatalf("png.Encode error: %v", err)
+	}
+	small := buf.Bytes()
+	var pending mediaResponse
+	for range maxPendingMedia {
+		pending = upload(doge, small, 201)
+	}
+	upload(doge, small, 409)
+	upload(cheems, small, 201)
+	postChirp(t, srv, doge.Token, map[string]any{"body": "one of many", "media_ids": []string{pending.ID.String()}}, 201)
+	upload(doge, small, 201)
+}
+
+func TestMediaRequiresSecret(t *testing.T) {
+	t.Setenv("MEDIA_DIR", t.TempDir())
+	t.Setenv("POLKA_SIGNING_SECRET", "polka-secret")
+	t.Setenv("MEDIA_SIGNING_SECRET", "")
+	t.Setenv("SECRET", "")
+	_, err := newAPIConfig(context.Background(), database.NewMemory(), eventbus.NewLocal())
+	if err == nil || !strings.Contains(err.Error(), "MEDIA_SIGNING_SECRET") {
+		t.Fatalf("newAPIConfig without any secret = %v, want an error about MEDIA_SIGNING_SECRET", err)
+	}
+
+	// the JWT secret is enough to derive one from
+	t.Setenv("SECRET", "Cheems")
+	if _, err := newAPIConfig(context.Background(), database.NewMemory(), eventbus.NewLocal()); err != nil {
+		t.Fatalf("newAPIConfig with SECRET error: %v", err)
+	}
+}
+
+// doUpload uploads data as the file of a multipart form to /api/media.
+func doUpload(t *testing.T, srv *httptest.Server, token, filename string, data []byte) *http.Response {
+	t.Helper()
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("CreateFormFile error: %v", err)
+	}
+	part.Write(data)
+	form.Close()
+	req, err := http.NewRequest("POST", srv.URL+"/api/media", &buf)
+	if err != nil {
+		t.Fatalf("NewRequest error: %v", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("POST /api/media: %v", err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
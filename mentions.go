@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/entities"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/notifications"
+	"github.com/Cheemx/chirpy/internal/pagination"
+	"github.com/Cheemx/chirpy/internal/validation"
+	"github.com/google/uuid"
+)
+
+// mentionEntity is an @mention as rendered in chirp JSON. Start and End are
+// character (code point) offsets into the body, End exclusive.
+type mentionEntity struct {
+	Start    int32     `json:"start"`
+	End      int32     `json:"end"`
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+}
+
+// checkUsername validates an optional username from a request body. Usernames
+// are what @mentions resolve against.
+func checkUsername(errs *validation.Errors, username *string) {
+	if username == nil || entities.ValidUsername(*username) {
+		return
+	}
+	*errs = append(*errs, validation.FieldError{
+		Field:   "username",
+		Code:    validation.CodeInvalidChars,
+		Message: fmt.Sprintf("username must be 1 to %d letters, digits or underscores", entities.MaxUsernameLength),
+	})
+}
+
+// nullString maps an omitted request field to NULL.
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+// mentionChirp records the @mentions in a chirp. Handles that don't belong
+// to anyone are left as plain text, and users in notified aren't notified
+// again.
+func (cfg *apiConfig) mentionChirp(ctx context.Context, chirp database.Chirp, notified map[uuid.UUID]bool) error {
+	mentions := entities.Mentions(chirp.Body)
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	var names []string
+	for _, m := range mentions {
+		names = append(names, strings.ToLower(m.Username))
+	}
+	users, err := cfg.db.GetUsersByUsernames(ctx, names)
+	if err != nil {
+		return err
+	}
+	byName := map[string]uuid.UUID{}
+	for _, user := range users {
+		byName[strings.ToLower(user.Username.String)] = user.ID
+	}
+
+	for _, m := range mentions {
+		userID, ok := byName[strings.ToLower(m.Username)]
+		if !ok {
+			continue
+		}
+		start := utf8.RuneCountInString(chirp.Body[:m.Start])
+		err = cfg.db.CreateMention(ctx, database.CreateMentionParams{
+			ChirpID:    chirp.ID,
+			UserID:     userID,
+			StartIndex: int32(start),
+			EndIndex:   int32(start + utf8.RuneCountInString(chirp.Body[m.Start:m.End])),
+		})
+		if err != nil {
+			return err
+		}
+		if notified[userID] {
+			continue
+		}
+		cfg.notify(ctx, notifications.Event{
+			Type:      notifications.Mention,
+			Recipient: userID,
+			Actor:     chirp.UserID,
+			ChirpID:   chirp.ID,
+		})
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handleGetMentions(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// newest first, leaving out the caller mentioning themselves
+	params := database.ListUserMentionsParams{UserID: userID, PageSize: page.FetchSize()}
+	params.AfterCreatedAt, params.AfterID = page.After()
+	chirps, err := cfg.db.ListUserMentions(r.Context(), params)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Creating response Body
+	res := struct {
+		Chirps     []chirpResponse `json:"chirps"`
+		NextCursor string          `json:"next_cursor,omitempty"`
+	}{}
+	chirps, res.NextCursor = pagination.Trim(page, chirps, chirpCursor)
+	if res.NextCursor != "" {
+		w.Header().Set("Link", pagination.NextLink(r.URL, res.NextCursor))
+	}
+	res.Chirps, err = cfg.presentChirps(r.Context(), userID, chirps)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	srv := newTestServer(t)
+	alice := createTestUser(t, srv, "alice@example.com")
+	bob := createTestUser(t, srv, "bob@example.com")
+
+	setUsername := func(user testUser, email, username string, want int) {
+		t.Helper()
+		res := doJSON(t, srv, "PUT", "/api/users", user.Token, map[string]string{
+			"email": email, "password": "password", "username": username,
+		})
+		if res.StatusCode != want {
+			t.Fatalf("set username %q: got %d want %d", username, res.StatusCode, want)
+		}
+	}
+	setUsername(alice, "alice@example.com", "Alice", 200)
+	setUsername(bob, "bob@example.com", "bob_b", 200)
+	setUsername(bob, "bob@example.com", "ALICE", 409)
+	setUsername(bob, "bob@example.com", "bob b", 400)
+
+	chirp := postChirp(t, srv, bob.Token, map[string]string{"body": "héllo @alice and @nobody, cc @bob_b"}, 201)
+	want := []mentionEntity{
+		{Start: 6, End: 12, UserID: alice.ID, Username: "Alice"},
+		{Start: 29, End: 35, UserID: bob.ID, Username: "bob_b"},
+	}
+	if fmt.Sprint(chirp.Mentions) != fmt.Sprint(want) {
+		t.Fatalf("mentions = %+v, want %+v", chirp.Mentions, want)
+	}
+
+	// alice sees the chirp, bob doesn't see his own mention of himself
+	var page chirpsPage
+	decodeBody(t, doJSON(t, srv, "GET", "/api/mentions", alice.Token, nil), &page)
+	if len(page.Chirps) != 1 || page.Chirps[0].ID != chirp.ID {
+		t.Fatalf("alice's mentions = %+v", page.Chirps)
+	}
+	page = chirpsPage{}
+	decodeBody(t, doJSON(t, srv, "GET", "/api/mentions", bob.Token, nil), &page)
+	if len(page.Chirps) != 0 {
+		t.Fatalf("bob's mentions = %+v", page.Chirps)
+	}
+
+	if res := doJSON(t, srv, "GET", "/api/mentions", "", nil); res.StatusCode != 401 {
+		t.Fatalf("anonymous mentions: got %d want 401", res.StatusCode)
+	}
+}
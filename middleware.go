@@ -3,8 +3,23 @@ package main
 import (
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/auth"
+	"github.com/Cheemx/chirpy/internal/entitlements"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/ratelimit"
+)
+
+var (
+	// writeRate is how many writes a user can make a minute, higherWriteRate
+	// the same for plans with entitlements.HigherRateLimits.
+	writeRate       = ratelimit.Rate{Requests: 60, Period: time.Minute}
+	higherWriteRate = ratelimit.Rate{Requests: 600, Period: time.Minute}
 )
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -16,6 +31,36 @@ func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	)
 }
 
+// middlewareRateLimit turns users away from next once they've made too many
+// writes in a row. Requests without a working token go straight through for
+// next to turn away.
+func (cfg *apiConfig) middlewareRateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			next(w, r)
+			return
+		}
+		user, _, err := cfg.tokenUser(r.Context(), token)
+		if err != nil {
+			next(w, r)
+			return
+		}
+
+		rate := writeRate
+		if cfg.entitlements.Has(user, entitlements.HigherRateLimits) {
+			rate = higherWriteRate
+		}
+		ok, wait := cfg.limiter.Allow(user.ID, rate)
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			httpapi.RespondWithError(w, &httpapi.Error{Status: 429, Code: httpapi.CodeRateLimited, Message: "Too many requests, try again later"})
+			return
+		}
+		next(w, r)
+	}
+}
+
 func (cfg *apiConfig) handleMetrics(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(200)
 	w.Header().Add("Content-Type", "text/html")
@@ -30,13 +75,12 @@ func (cfg *apiConfig) handleMetrics(w http.ResponseWriter, r *http.Request) {
 
 func (cfg *apiConfig) handleReset(w http.ResponseWriter, r *http.Request) {
 	if os.Getenv("PLATFORM") != "dev" {
-		w.WriteHeader(403)
+		httpapi.RespondWithError(w, httpapi.Forbidden("Reset is only allowed in dev"))
 		return
 	}
 	err := cfg.db.DeleteAllUsers(r.Context())
 	if err != nil {
-		log.Printf("Error Deleting all Users: %v", err)
-		w.WriteHeader(500)
+		httpapi.RespondWithError(w, err)
 		return
 	}
 	w.WriteHeader(200)
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/moderation"
+	"github.com/Cheemx/chirpy/internal/pagination"
+	"github.com/Cheemx/chirpy/internal/validation"
+)
+
+// newModerator builds the moderation pipeline from the banned_words table and
+// the MODERATION_* environment variables. The returned word list is the one
+// the pipeline checks, so the admin endpoints can edit it in place.
+func newModerator(ctx context.Context, db database.Store) (*moderation.Pipeline, *moderation.WordList, error) {
+	words, err := db.ListBannedWords(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if path := os.Getenv("MODERATION_WORDS_FILE"); path != "" {
+		extra, err := readLines(path, moderation.ReadWords)
+		if err != nil {
+			return nil, nil, err
+		}
+		words = append(words, extra...)
+	}
+	wordList := moderation.NewWordList(words...)
+
+	wordAction, err := envAction("MODERATION_WORD_ACTION", moderation.Mask)
+	if err != nil {
+		return nil, nil, err
+	}
+	rules := []moderation.Rule{{Filter: wordList, Action: wordAction}}
+
+	if path := os.Getenv("MODERATION_PATTERNS_FILE"); path != "" {
+		patterns, err := readLines(path, moderation.ReadWords)
+		if err != nil {
+			return nil, nil, err
+		}
+		filter, err := moderation.NewRegexFilter("matched pattern", patterns...)
+		if err != nil {
+			return nil, nil, err
+		}
+		action, err := envAction("MODERATION_PATTERN_ACTION", moderation.Flag)
+		if err != nil {
+			return nil, nil, err
+		}
+		rules = append(rules, moderation.Rule{Filter: filter, Action: action})
+	}
+
+	if domains := os.Getenv("MODERATION_BLOCKED_DOMAINS"); domains != "" {
+		action, err := envAction("MODERATION_LINK_ACTION", moderation.Reject)
+		if err != nil {
+			return nil, nil, err
+		}
+		rules = append(rules, moderation.Rule{
+			Filter: moderation.NewLinkBlocklist(strings.Split(domains, ",")...),
+			Action: action,
+		})
+	}
+
+	return moderation.NewPipeline(rules...), wordList, nil
+}
+
+// envAction reads a moderation.Action from the environment, falling back to
+// def when the variable is unset.
+func envAction(key string, def moderation.Action) (moderation.Action, error) {
+	s := os.Getenv(key)
+	if s == "" {
+		return def, nil
+	}
+	return moderation.ParseAction(s)
+}
+
+// readLines opens path and hands it to parse.
+func readLines(path string, parse func(io.Reader) ([]string, error)) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parse(f)
+}
+
+// checkAdminKey guards the admin endpoints for moderation, Chirpy Red and
+// webhooks. They're disabled unless ADMIN_KEY is set.
+func (cfg *apiConfig) checkAdminKey(r *http.Request) error {
+	if cfg.adminKey == "" {
+		return httpapi.Forbidden("Admin endpoints are disabled")
+	}
+	return checkAPIKey(r, cfg.adminKey)
+}
+
+func (cfg *apiConfig) handleGetBannedWords(w http.ResponseWriter, r *http.Request) {
+	err := cfg.checkAdminKey(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	res := struct {
+		Words []string `json:"words"`
+	}{
+		Words: cfg.bannedWords.Words(),
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
+
+func (cfg *apiConfig) handleAddBannedWord(w http.ResponseWriter, r *http.Request) {
+	err := cfg.checkAdminKey(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	req := struct {
+		Word string `json:"word"`
+	}{}
+	err = httpapi.DecodeJSON(w, r, &req)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// only single words can be matched, "two words" would never hit
+	word, err := moderation.NormalizeWord(req.Word)
+	if err != nil {
+		httpapi.RespondWithError(w, httpapi.ValidationFailed(validation.Errors{{
+			Field:   "word",
+			Code:    validation.CodeInvalid,
+			Message: "word must be a single word",
+		}}))
+		return
+	}
+
+	// persist first so the word survives a restart, then start matching it
+	_, err = cfg.db.AddBannedWord(r.Context(), word)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	cfg.bannedWords.Add(word)
+
+	res := struct {
+		Word string `json:"word"`
+	}{
+		Word: word,
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 201, res)
+}
+
+func (cfg *apiConfig) handleDeleteBannedWord(w http.ResponseWriter, r *http.Request) {
+	err := cfg.checkAdminKey(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// removing a word that isn't banned is a no-op
+	word := strings.ToLower(r.PathValue("word"))
+	_, err = cfg.db.DeleteBannedWord(r.Context(), word)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	cfg.bannedWords.Remove(word)
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handleGetModerationFlags(w http.ResponseWriter, r *http.Request) {
+	err := cfg.checkAdminKey(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// newest flags first
+	params := database.ListModerationFlagsParams{PageSize: page.FetchSize()}
+	params.AfterCreatedAt, params.AfterID = page.After()
+	flags, err := cfg.db.ListModerationFlags(r.Context(), params)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Creating response Body
+	res := struct {
+		Flags      []database.ModerationFlag `json:"flags"`
+		NextCursor string                    `json:"next_cursor,omitempty"`
+	}{
+		Flags: []database.ModerationFlag{},
+	}
+	flags, res.NextCursor = pagination.Trim(page, flags, func(f database.ModerationFlag) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: f.CreatedAt, ID: f.ID}
+	})
+	if res.NextCursor != "" {
+		w.Header().Set("Link", pagination.NextLink(r.URL, res.NextCursor))
+	}
+	res.Flags = append(res.Flags, flags...)
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
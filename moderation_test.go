@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestModeration(t *testing.T) {
+	t.Setenv("MODERATION_BLOCKED_DOMAINS", "spam.example")
+	srv := newTestServer(t)
+	user := createTestUser(t, srv, "cheems@example.com")
+
+	post := func(body string, want int) chirpResponse {
+		t.Helper()
+		return postChirp(t, srv, user.Token, map[string]string{"body": body}, want)
+	}
+
+	if got := post("Kerfuffle! What a SHARBERT.", 201).Body; got != "****! What a ****." {
+		t.Fatalf("masked body = %q", got)
+	}
+	post("buy now at https://deals.spam.example/x", 400)
+
+	// words added at runtime apply to the next chirp, and can be removed again
+	res := doAdmin(t, srv, "POST", "/admin/moderation/words", map[string]string{"word": "Bonk"})
+	if res.StatusCode != 201 {
+		t.Fatalf("add word: got %d want 201", res.StatusCode)
+	}
+	if got := post("bonk, bonk", 201).Body; got != "****, ****" {
+		t.Fatalf("masked body = %q", got)
+	}
+	if res := doAdmin(t, srv, "DELETE", "/admin/moderation/words/bonk", nil); res.StatusCode != 204 {
+		t.Fatalf("delete word: got %d want 204", res.StatusCode)
+	}
+	if got := post("bonk", 201).Body; got != "bonk" {
+		t.Fatalf("removed word still masked: %q", got)
+	}
+
+	res = doAdmin(t, srv, "GET", "/admin/moderation/words", nil)
+	var words struct {
+		Words []string `json:"words"`
+	}
+	decodeBody(t, res, &words)
+	if fmt.Sprint(words.Words) != "[fornax kerfuffle sharbert]" {
+		t.Fatalf("words = %v", words.Words)
+	}
+
+	if res := doAdmin(t, srv, "POST", "/admin/moderation/words", map[string]string{"word": "two words"}); res.StatusCode != 400 {
+		t.Fatalf("add phrase: got %d want 400", res.StatusCode)
+	}
+	if res := doJSON(t, srv, "GET", "/admin/moderation/words", user.Token, nil); res.StatusCode != 401 {
+		t.Fatalf("words without admin key: got %d want 401", res.StatusCode)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/notifications"
+	"github.com/Cheemx/chirpy/internal/pagination"
+	"github.com/google/uuid"
+)
+
+// maxNotificationActors is how many of the most recent actors are listed on
+// an aggregated notification, actor_count has the full number.
+const maxNotificationActors = 3
+
+// notificationResponse is one entry in the inbox.
+type notificationResponse struct {
+	ID         uuid.UUID          `json:"id"`
+	Type       notifications.Type `json:"type"`
+	ChirpID    uuid.NullUUID      `json:"chirp_id"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+	Read       bool               `json:"read"`
+	ActorCount int                `json:"actor_count"`
+	Actors     []uuid.UUID        `json:"actors"`
+	// Cursor is this notification's position, for marking it and everything
+	// older read.
+	Cursor string `json:"cursor"`
+}
+
+// notificationPrefs is the preferences request and response body. Fields
+// left out of a request keep their current setting.
+type notificationPrefs struct {
+	Reply   *bool `json:"reply,omitempty"`
+	Like    *bool `json:"like,omitempty"`
+	Follow  *bool `json:"follow,omitempty"`
+	Mention *bool `json:"mention,omitempty"`
+}
+
+func (p notificationPrefs) byType() map[notifications.Type]*bool {
+	return map[notifications.Type]*bool{
+		notifications.Reply:   p.Reply,
+		notifications.Like:    p.Like,
+		notifications.Follow:  p.Follow,
+		notifications.Mention: p.Mention,
+	}
+}
+
+func newNotificationPrefs(prefs map[notifications.Type]bool) notificationPrefs {
+	reply, like, follow, mention := prefs[notifications.Reply], prefs[notifications.Like], prefs[notifications.Follow], prefs[notifications.Mention]
+	return notificationPrefs{Reply: &reply, Like: &like, Follow: &follow, Mention: &mention}
+}
+
+// notify records e for its recipient. The action behind it has already
+// happened, so a lost notification is logged rather than failing the request.
+func (cfg *apiConfig) notify(ctx context.Context, e notifications.Event) {
+	err := cfg.notifier.Notify(ctx, e)
+	if err != nil {
+		log.Printf("Error notifying %s of %s: %v", e.Recipient, e.Type, err)
+	}
+}
+
+// notificationCursor is the keyset position of a notification in
+// updated_at, id order.
+func notificationCursor(n database.Notification) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: n.UpdatedAt, ID: n.ID}
+}
+
+func (cfg *apiConfig) handleGetNotifications(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// most recently active first
+	params := database.ListNotificationsParams{UserID: userID, PageSize: page.FetchSize()}
+	params.AfterUpdatedAt, params.AfterID = page.After()
+	items, err := cfg.db.ListNotifications(r.Context(), params)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Creating response Body
+	res := struct {
+		Notifications []notificationResponse `json:"notifications"`
+		UnreadCount   int64                  `json:"unread_count"`
+		NextCursor    string                 `json:"next_cursor,omitempty"`
+	}{}
+	items, res.NextCursor = pagination.Trim(page, items, notificationCursor)
+	if res.NextCursor != "" {
+		w.Header().Set("Link", pagination.NextLink(r.URL, res.NextCursor))
+	}
+
+	ids := make([]uuid.UUID, len(items))
+	for i, n := range items {
+		ids[i] = n.ID
+	}
+	actorRows, err := cfg.db.ListNotificationActors(r.Context(), ids)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	actors := map[uuid.UUID][]uuid.UUID{}
+	for _, row := range actorRows {
+		actors[row.NotificationID] = append(actors[row.NotificationID], row.ActorID)
+	}
+
+	res.Notifications = make([]notificationResponse, len(items))
+	for i, n := range items {
+		res.Notifications[i] = notificationResponse{
+			ID:         n.ID,
+			Type:       notifications.Type(n.Type),
+			ChirpID:    n.ChirpID,
+			CreatedAt:  n.CreatedAt,
+			UpdatedAt:  n.UpdatedAt,
+			Read:       n.ReadAt.Valid,
+			ActorCount: len(actors[n.ID]),
+			Actors:     actors[n.ID][:min(len(actors[n.ID]), maxNotificationActors)],
+			Cursor:     notificationCursor(n).Encode(),
+		}
+	}
+
+	res.UnreadCount, err = cfg.db.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
+
+func (cfg *apiConfig) handleMarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// without a cursor everything is marked read
+	req := struct {
+		Cursor string `json:"cursor,omitempty"`
+	}{}
+	err = httpapi.DecodeJSON(w, r, &req)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	cursor, err := pagination.DecodeCursor(req.Cursor)
+	if err != nil {
+		httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, "cursor is not a valid cursor"))
+		return
+	}
+	upTo := pagination.Page{Cursor: cursor}
+
+	params := database.MarkNotificationsReadParams{UserID: userID}
+	params.UpToUpdatedAt, params.UpToID = upTo.After()
+	marked, err := cfg.db.MarkNotificationsRead(r.Context(), params)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	unread, err := cfg.db.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, struct {
+		MarkedRead  int64 `json:"marked_read"`
+		UnreadCount int64 `json:"unread_count"`
+	}{
+		MarkedRead:  marked,
+		UnreadCount: unread,
+	})
+}
+
+func (cfg *apiConfig) handleGetNotificationPrefs(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	prefs, err := cfg.notifier.Preferences(r.Context(), userID)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, newNotificationPrefs(prefs))
+}
+
+func (cfg *apiConfig) handleUpdateNotificationPrefs(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	var req notificationPrefs
+	err = httpapi.DecodeJSON(w, r, &req)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	for t, enabled := range req.byType() {
+		if enabled == nil {
+			continue
+		}
+		err = cfg.db.SetNotificationPreference(r.Context(), database.SetNotificationPreferenceParams{
+			UserID:  userID,
+			Type:    string(t),
+			Enabled: *enabled,
+		})
+		if err != nil {
+			httpapi.RespondWithError(w, err)
+			return
+		}
+	}
+
+	prefs, err := cfg.notifier.Preferences(r.Context(), userID)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, newNotificationPrefs(prefs))
+}
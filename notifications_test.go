@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Cheemx/chirpy/internal/notifications"
+)
+
+func TestNotifications(t *testing.T) {
+	srv := newTestServer(t)
+	owner := createTestUser(t, srv, "owner@example.com")
+	alice := createTestUser(t, srv, "alice@example.com")
+	bob := createTestUser(t, srv, "bob@example.com")
+
+	chirp := postChirp(t, srv, owner.Token, map[string]string{"body": "hello"}, 201)
+
+	for _, user := range []testUser{alice, bob} {
+		if res := doJSON(t, srv, "PUT", "/api/chirps/"+chirp.ID.String()+"/like", user.Token, nil); res.StatusCode != 204 {
+			t.Fatalf("like: got %d want 204", res.StatusCode)
+		}
+	}
+	postChirp(t, srv, alice.Token, map[string]string{"body": "hi back", "in_reply_to": chirp.ID.String()}, 201)
+	if res := doJSON(t, srv, "POST", "/api/users/"+owner.ID.String()+"/follow", bob.Token, nil); res.StatusCode != 204 {
+		t.Fatalf("follow: got %d want 204", res.StatusCode)
+	}
+
+	type inbox struct {
+		Notifications []notificationResponse `json:"notifications"`
+		UnreadCount   int64                  `json:"unread_count"`
+		NextCursor    string                 `json:"next_cursor"`
+	}
+	getInbox := func(path string) inbox {
+		t.Helper()
+		res := doJSON(t, srv, "GET", path, owner.Token, nil)
+		if res.StatusCode != 200 {
+			t.Fatalf("GET %s: got %d want 200", path, res.StatusCode)
+		}
+		var got inbox
+		decodeBody(t, res, &got)
+		return got
+	}
+
+	got := getInbox("/api/notifications")
+	var types []notifications.Type
+	for _, n := range got.Notifications {
+		types = append(types, n.Type)
+	}
+	if fmt.Sprint(types) != "[follow reply like]" || got.UnreadCount != 3 {
+		t.Fatalf("inbox = %v with %d unread, want [follow reply like] with 3", types, got.UnreadCount)
+	}
+	like := got.Notifications[2]
+	if like.ActorCount != 2 || like.ChirpID.UUID != chirp.ID {
+		t.Fatalf("like notification = %+v, want 2 actors on the chirp", like)
+	}
+
+	// pages follow the same order
+	page := getInbox("/api/notifications?limit=2")
+	if len(page.Notifications) != 2 || page.NextCursor == "" {
+		t.Fatalf("first page = %+v", page)
+	}
+	page = getInbox("/api/notifications?limit=2&cursor=" + page.NextCursor)
+	if len(page.Notifications) != 1 || page.Notifications[0].ID != like.ID {
+		t.Fatalf("second page = %+v", page)
+	}
+
+	// marking read up to the reply leaves only the newer follow unread
+	res := doJSON(t, srv, "POST", "/api/notifications/read", owner.Token, map[string]string{"cursor": got.Notifications[1].Cursor})
+	var marked struct {
+		MarkedRead  int64 `json:"marked_read"`
+		UnreadCount int64 `json:"unread_count"`
+	}
+	decodeBody(t, res, &marked)
+	if marked.MarkedRead != 2 || marked.UnreadCount != 1 {
+		t.Fatalf("mark read = %+v, want 2 marked and 1 unread", marked)
+	}
+
+	// with likes turned off, new likes don't show up
+	res = doJSON(t, srv, "PUT", "/api/notifications/preferences", owner.Token, map[string]bool{"like": false})
+	var prefs map[string]bool
+	decodeBody(t, res, &prefs)
+	if prefs["like"] || !prefs["reply"] || !prefs["follow"] || !prefs["mention"] {
+		t.Fatalf("preferences = %v, want only like off", prefs)
+	}
+	second := postChirp(t, srv, owner.Token, map[string]string{"body": "again"}, 201)
+	doJSON(t, srv, "PUT", "/api/chirps/"+second.ID.String()+"/like", alice.Token, nil)
+	if got := getInbox("/api/notifications"); len(got.Notifications) != 3 {
+		t.Fatalf("inbox after disabling likes = %+v", got.Notifications)
+	}
+
+	if res := doJSON(t, srv, "PUT", "/api/notifications/preferences", owner.Token, map[string]bool{"rechirp": true}); res.StatusCode != 400 {
+		t.Fatalf("unknown preference: got %d want 400", res.StatusCode)
+	}
+	if res := doJSON(t, srv, "POST", "/api/notifications/read", owner.Token, map[string]string{"cursor": "nope"}); res.StatusCode != 400 {
+		t.Fatalf("bad cursor: got %d want 400", res.StatusCode)
+	}
+}
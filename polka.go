@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/outbox"
+	"github.com/Cheemx/chirpy/internal/validation"
+	"github.com/Cheemx/chirpy/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+// Polka webhook events. Both of the last two end a user's Chirpy Red.
+const (
+	polkaUserUpgraded        = "user.upgraded"
+	polkaUserDowngraded      = "user.downgraded"
+	polkaSubscriptionExpired = "subscription.expired"
+)
+
+const (
+	// polkaSignatureHeader carries Polka's signature of the request body,
+	// in the same format as the signatures on our own webhooks.
+	polkaSignatureHeader = "Polka-Signature"
+	// polkaTolerance is how far a signature's timestamp, or an event's
+	// effective date, may be from now.
+	polkaTolerance = 5 * time.Minute
+)
+
+// redStatusResponse is one entry in a user's Chirpy Red history.
+type redStatusResponse struct {
+	IsChirpyRed bool       `json:"is_chirpy_red"`
+	Reason      string     `json:"reason"`
+	EventID     string     `json:"event_id,omitempty"`
+	EffectiveAt time.Time  `json:"effective_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	RecordedAt  time.Time  `json:"recorded_at"`
+}
+
+// checkPolkaSignature verifies the body's signature. It reads the whole body,
+// and leaves a copy behind for httpapi.DecodeJSON. Like an unset key, an unset
+// secret matches nothing.
+func (cfg *apiConfig) checkPolkaSignature(w http.ResponseWriter, r *http.Request) error {
+	if cfg.polkaSecret == "" {
+		return httpapi.Unauthorized(errors.New("POLKA_SIGNING_SECRET is not set"))
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, httpapi.MaxBodyBytes))
+	if err != nil {
+		return httpapi.BadRequest(httpapi.CodeInvalidJSON, "Request body could not be read")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	err = webhooks.Verify(cfg.polkaSecret, r.Header.Get(polkaSignatureHeader), body, polkaTolerance, time.Now())
+	if err != nil {
+		return httpapi.Unauthorized(err)
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlePolkaWebhook(w http.ResponseWriter, r *http.Request) {
+	// Validate the request for polka key header, and its signature
+	err := checkAPIKey(r, cfg.polkaKey)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	err = cfg.checkPolkaSignature(w, r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// request struct to parse; id is Polka's ID for the event, the same on
+	// every retry of it
+	req := struct {
+		ID    string `json:"id"`
+		Event string `json:"event"`
+		Data  struct {
+			UserID      string `json:"user_id"`
+			EffectiveAt string `json:"effective_at,omitempty"`
+			ExpiresAt   string `json:"expires_at,omitempty"`
+		} `json:"data"`
+	}{}
+
+	// Unmarshall the request
+	err = httpapi.DecodeJSON(w, r, &req)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// without an id a retry can't be told from a new event
+	if req.ID == "" {
+		httpapi.RespondWithError(w, httpapi.ValidationFailed(validation.Errors{{Field: "id", Code: validation.CodeRequired, Message: "id is required"}}))
+		return
+	}
+
+	// events we don't handle are acknowledged all the same
+	var red bool
+	switch req.Event {
+	case polkaUserUpgraded:
+		red = true
+	case polkaUserDowngraded, polkaSubscriptionExpired:
+		red = false
+	default:
+		w.WriteHeader(204)
+		return
+	}
+
+	// get userID from req
+	id, err := uuid.Parse(req.Data.UserID)
+	if err != nil {
+		httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, "data.user_id is not a valid ID"))
+		return
+	}
+
+	// the change takes effect now unless Polka says otherwise
+	effectiveAt := time.Now().UTC()
+	if req.Data.EffectiveAt != "" {
+		effectiveAt, err = time.Parse(time.RFC3339, req.Data.EffectiveAt)
+		if err != nil {
+			httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, "data.effective_at is not an RFC 3339 timestamp"))
+			return
+		}
+		if effectiveAt.After(time.Now().Add(polkaTolerance)) {
+			httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, "data.effective_at is in the future"))
+			return
+		}
+		effectiveAt = effectiveAt.UTC()
+	}
+
+	// an upgrade may end by itself, when the subscription runs out
+	var expiresAt sql.NullTime
+	if red && req.Data.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, req.Data.ExpiresAt)
+		if err != nil {
+			httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, "data.expires_at is not an RFC 3339 timestamp"))
+			return
+		}
+		if !t.After(effectiveAt) {
+			httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, "data.expires_at must be after data.effective_at"))
+			return
+		}
+		expiresAt = sql.NullTime{Time: t.UTC(), Valid: true}
+	}
+
+	// record the change in the user's history and apply whichever change
+	// is now the latest in effect, so events arriving out of order can't
+	// undo a later one. An event already handled is skipped, and changes
+	// of status are recorded for every instance and integration.
+	err = cfg.db.InTx(r.Context(), func(tx database.Store) error {
+		user, err := tx.GetUserByID(r.Context(), id)
+		if err != nil {
+			return lookupError(err, "User")
+		}
+
+		recorded, err := tx.RecordWebhookEvent(r.Context(), database.RecordWebhookEventParams{
+			ID:     req.ID,
+			Source: "polka",
+			Type:   req.Event,
+		})
+		if err != nil {
+			return err
+		}
+		if recorded == 0 {
+			return nil
+		}
+
+		_, err = tx.CreateRedStatusChange(r.Context(), database.CreateRedStatusChangeParams{
+			UserID:         id,
+			IsChirpyRed:    red,
+			Reason:         req.Event,
+			WebhookEventID: sql.NullString{String: req.ID, Valid: true},
+			EffectiveAt:    effectiveAt,
+			ExpiresAt:      expiresAt,
+		})
+		if err != nil {
+			return err
+		}
+		latest, err := tx.GetLatestRedStatusChange(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if latest.IsChirpyRed == user.IsChirpyRed && sameTime(latest.ExpiresAt, user.RedExpiresAt) {
+			return nil
+		}
+
+		updated, err := tx.UpdateUserChirpyRed(r.Context(), database.UpdateUserChirpyRedParams{
+			ID:           id,
+			IsChirpyRed:  latest.IsChirpyRed,
+			RedExpiresAt: latest.ExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+		// a renewal only moves the expiry, nobody needs telling
+		if cfg.isChirpyRed(updated) == cfg.isChirpyRed(user) {
+			return nil
+		}
+		eventType := outbox.UserDowngraded
+		if cfg.isChirpyRed(updated) {
+			eventType = outbox.UserUpgraded
+		}
+		return outbox.Record(r.Context(), tx, eventType, struct {
+			UserID      uuid.UUID  `json:"user_id"`
+			Reason      string     `json:"reason"`
+			EffectiveAt time.Time  `json:"effective_at"`
+			ExpiresAt   *time.Time `json:"expires_at"`
+		}{
+			UserID:      id,
+			Reason:      latest.Reason,
+			EffectiveAt: latest.EffectiveAt,
+			ExpiresAt:   nullTime(latest.ExpiresAt),
+		})
+	})
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	cfg.outbox.Wake()
+
+	// return with 204 code
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handleGetRedStatusHistory(w http.ResponseWriter, r *http.Request) {
+	err := cfg.checkAdminKey(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	id, err := pathUUID(r, "userID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), id)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "User"))
+		return
+	}
+
+	// latest in effect first
+	changes, err := cfg.db.ListRedStatusChanges(r.Context(), id)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	res := struct {
+		IsChirpyRed bool                `json:"is_chirpy_red"`
+		Changes     []redStatusResponse `json:"changes"`
+	}{
+		IsChirpyRed: cfg.isChirpyRed(user),
+		Changes:     make([]redStatusResponse, len(changes)),
+	}
+	for i, change := range changes {
+		res.Changes[i] = redStatusResponse{
+			IsChirpyRed: change.IsChirpyRed,
+			Reason:      change.Reason,
+			EventID:     change.WebhookEventID.String,
+			EffectiveAt: change.EffectiveAt,
+			ExpiresAt:   nullTime(change.ExpiresAt),
+			RecordedAt:  change.CreatedAt,
+		}
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
+
+// sameTime reports whether two nullable times are both NULL or both the
+// same time.
+func sameTime(a, b sql.NullTime) bool {
+	return a.Valid == b.Valid && a.Time.Equal(b.Time)
+}
+
+// nullTime maps NULL to a JSON null.
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/eventbus"
+	"github.com/google/uuid"
+)
+
+func TestPolka(t *testing.T) {
+	srv := newTestServer(t)
+	user := createTestUser(t, srv, "cheems@example.com")
+
+	event := func(id, eventType string, effectiveAt time.Time) map[string]any {
+		data := map[string]string{"user_id": user.ID.String()}
+		if !effectiveAt.IsZero() {
+			data["effective_at"] = effectiveAt.Format(time.RFC3339)
+		}
+		return map[string]any{"id": id, "event": eventType, "data": data}
+	}
+	type history struct {
+		IsChirpyRed bool                `json:"is_chirpy_red"`
+		Changes     []redStatusResponse `json:"changes"`
+	}
+	getHistory := func() history {
+		t.Helper()
+		res := doAdmin(t, srv, "GET", "/admin/users/"+user.ID.String()+"/red-status", nil)
+		if res.StatusCode != 200 {
+			t.Fatalf("GET red status: got %d want 200", res.StatusCode)
+		}
+		var h history
+		decodeBody(t, res, &h)
+		return h
+	}
+	send := func(body map[string]any, want int) {
+		t.Helper()
+		if res := doPolka(t, srv, "polka", body); res.StatusCode != want {
+			t.Fatalf("polka %v: got %d want %d", body, res.StatusCode, want)
+		}
+	}
+
+	// the API key and a signature are both required. How old a signature may
+	// be is up to webhooks.Verify
+	upgrade := event("evt_1", "user.upgraded", time.Time{})
+	if res := doPolka(t, srv, "polkb", upgrade); res.StatusCode != 401 {
+		t.Fatalf("wrong API key: got %d want 401", res.StatusCode)
+	}
+	if res := doRequest(t, srv, "POST", "/api/polka/webhooks", "ApiKey polka", upgrade); res.StatusCode != 401 {
+		t.Fatalf("unsigned: got %d want 401", res.StatusCode)
+	}
+
+	// a retried event is only applied once
+	send(upgrade, 204)
+	send(upgrade, 204)
+	if h := getHistory(); !h.IsChirpyRed || len(h.Changes) != 1 || h.Changes[0].EventID != "evt_1" {
+		t.Fatalf("after upgrade = %+v", h)
+	}
+
+	send(event("evt_2", "subscription.expired", time.Time{}), 204)
+	if h := getHistory(); h.IsChirpyRed || len(h.Changes) != 2 || h.Changes[0].Reason != "subscription.expired" {
+		t.Fatalf("after expiry = %+v", h)
+	}
+
+	// an upgrade that took effect before the expiry, delivered late, is
+	// recorded without undoing it
+	send(event("evt_3", "user.upgraded", time.Now().Add(-time.Hour)), 204)
+	h := getHistory()
+	if h.IsChirpyRed || len(h.Changes) != 3 || h.Changes[0].EventID != "evt_2" || h.Changes[2].EventID != "evt_3" {
+		t.Fatalf("after late upgrade = %+v", h)
+	}
+
+	send(event("evt_4", "user.upgraded", time.Time{}), 204)
+	send(event("evt_5", "user.downgraded", time.Time{}), 204)
+	if h := getHistory(); h.IsChirpyRed || len(h.Changes) != 5 {
+		t.Fatalf("after downgrade = %+v", h)
+	}
+
+	send(event("evt_6", "user.upgraded", time.Now().Add(time.Hour)), 400)
+	send(map[string]any{"id": "evt_7", "event": "user.downgraded", "data": map[string]string{"user_id": uuid.NewString()}}, 404)
+	send(map[string]any{"id": "evt_8", "event": "user.renamed", "data": map[string]string{}}, 204)
+
+	// events without an id can't be deduplicated, so they aren't taken
+	send(map[string]any{"event": "user.upgraded", "data": map[string]string{"user_id": user.ID.String()}}, 400)
+	send(map[string]any{"id": "", "event": "user.renamed", "data": map[string]string{}}, 400)
+	if h := getHistory(); h.IsChirpyRed || len(h.Changes) != 5 {
+		t.Fatalf("after events without an id = %+v", h)
+	}
+
+	if res := doJSON(t, srv, "GET", "/admin/users/"+user.ID.String()+"/red-status", user.Token, nil); res.StatusCode != 401 {
+		t.Fatalf("red status without admin key: got %d want 401", res.StatusCode)
+	}
+}
+
+func TestPolkaRequiresSecret(t *testing.T) {
+	t.Setenv("MEDIA_DIR", t.TempDir())
+	t.Setenv("POLKA_SIGNING_SECRET", "")
+	_, err := newAPIConfig(context.Background(), database.NewMemory(), eventbus.NewLocal())
+	if err == nil || !strings.Contains(err.Error(), "POLKA_SIGNING_SECRET") {
+		t.Fatalf("newAPIConfig without POLKA_SIGNING_SECRET = %v, want an error about it", err)
+	}
+
+	// and should one be running without it anyway, nothing gets through
+	srv := httptest.NewServer((&apiConfig{polkaKey: "polka"}).routes())
+	defer srv.Close()
+	res := doPolka(t, srv, "polka", map[string]any{"id": "evt_1", "event": "user.renamed"})
+	if res.StatusCode != 401 {
+		t.Fatalf("polka without a secret: got %d want 401", res.StatusCode)
+	}
+}
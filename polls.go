@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/validation"
+	"github.com/google/uuid"
+)
+
+// Limits on the polls chirps can carry.
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 4
+	maxPollOptionLength = 25
+	maxPollDuration     = 7 * 24 * time.Hour
+)
+
+// pollRequest is the poll a new chirp carries, as sent to POST /api/chirps.
+type pollRequest struct {
+	Options  []string  `json:"options"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+// pollResponse is a chirp's poll as one viewer sees it. The tallies are nil
+// until the viewer has voted or the poll has closed, so nobody votes
+// knowing how it's going.
+type pollResponse struct {
+	Options  []pollOptionResponse `json:"options"`
+	ClosesAt time.Time            `json:"closes_at"`
+	Closed   bool                 `json:"closed"`
+	// MyVote is the index of the option the viewer voted for.
+	MyVote     *int32 `json:"my_vote"`
+	TotalVotes *int64 `json:"total_votes"`
+}
+
+type pollOptionResponse struct {
+	Text  string `json:"text"`
+	Votes *int64 `json:"votes"`
+}
+
+// checkPoll validates the poll of a new chirp, trimming its options.
+func checkPoll(poll *pollRequest) error {
+	var errs validation.Errors
+	if len(poll.Options) < minPollOptions || len(poll.Options) > maxPollOptions {
+		errs = append(errs, validation.FieldError{
+			Field:   "poll.options",
+			Code:    validation.CodeInvalid,
+			Message: fmt.Sprintf("A poll needs %d to %d options", minPollOptions, maxPollOptions),
+		})
+	}
+	seen := map[string]bool{}
+	for i, option := range poll.Options {
+		option = strings.TrimSpace(option)
+		poll.Options[i] = option
+		errs.Require("poll.options", option)
+		errs.MaxLength("poll.options", option, maxPollOptionLength)
+		if option != "" && seen[strings.ToLower(option)] {
+			errs = append(errs, validation.FieldError{
+				Field:   "poll.options",
+				Code:    validation.CodeInvalid,
+				Message: fmt.Sprintf("Poll option %q is there twice", option),
+			})
+		}
+		seen[strings.ToLower(option)] = true
+	}
+	now := time.Now()
+	if !poll.ClosesAt.After(now) || poll.ClosesAt.After(now.Add(maxPollDuration)) {
+		errs = append(errs, validation.FieldError{
+			Field:   "poll.closes_at",
+			Code:    validation.CodeInvalid,
+			Message: fmt.Sprintf("A poll must close in the future, within %v", maxPollDuration),
+		})
+	}
+	if len(errs) > 0 {
+		return httpapi.ValidationFailed(errs)
+	}
+	poll.ClosesAt = poll.ClosesAt.UTC()
+	return nil
+}
+
+// chirpPolls loads the polls of a batch of chirps, keyed by chirp ID, as
+// viewerID sees them. viewerID is uuid.Nil for anonymous requests, who only
+// see the results of closed polls.
+func (cfg *apiConfig) chirpPolls(ctx context.Context, viewerID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]*pollResponse, error) {
+	polls, err := cfg.db.ListPolls(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := map[uuid.UUID]*pollResponse{}
+	if len(polls) == 0 {
+		return res, nil
+	}
+	pollIDs := make([]uuid.UUID, len(polls))
+	for i, poll := range polls {
+		pollIDs[i] = poll.ChirpID
+	}
+
+	counts, err := cfg.db.CountPollVotes(ctx, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+	votes := map[uuid.UUID]map[int32]int64{}
+	for _, row := range counts {
+		if votes[row.ChirpID] == nil {
+			votes[row.ChirpID] = map[int32]int64{}
+		}
+		votes[row.ChirpID][row.OptionIndex] = row.VoteCount
+	}
+
+	myVotes := map[uuid.UUID]int32{}
+	if viewerID != uuid.Nil {
+		rows, err := cfg.db.ListUserPollVotes(ctx, database.ListUserPollVotesParams{
+			UserID:   viewerID,
+			ChirpIds: pollIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			myVotes[row.ChirpID] = row.OptionIndex
+		}
+	}
+
+	now := time.Now()
+	for _, poll := range polls {
+		p := &pollResponse{
+			Options:  make([]pollOptionResponse, len(poll.Options)),
+			ClosesAt: poll.ClosesAt,
+			Closed:   !now.Before(poll.ClosesAt),
+		}
+		if vote, ok := myVotes[poll.ChirpID]; ok {
+			p.MyVote = &vote
+		}
+		// the tallies only once the viewer can no longer be swayed by them
+		visible := p.Closed || p.MyVote != nil
+		var total int64
+		for i, text := range poll.Options {
+			p.Options[i].Text = text
+			if visible {
+				n := votes[poll.ChirpID][int32(i)]
+				p.Options[i].Votes = &n
+				total += n
+			}
+		}
+		if visible {
+			p.TotalVotes = &total
+		}
+		res[poll.ChirpID] = p
+	}
+	return res, nil
+}
+
+func (cfg *apiConfig) handleVotePoll(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// parse the chirpID to uuid format
+	id, err := pathUUID(r, "chirpID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	req := struct {
+		Option *int32 `json:"option"`
+	}{}
+	err = httpapi.DecodeJSON(w, r, &req)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// voting through a rechirp votes in the original's poll
+	chirp, err := cfg.db.GetChirpByID(r.Context(), id)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "Chirp"))
+		return
+	}
+	if chirp.RechirpOfID.Valid {
+		chirp, err = cfg.db.GetChirpByID(r.Context(), chirp.RechirpOfID.UUID)
+		if err != nil {
+			httpapi.RespondWithError(w, lookupError(err, "Chirp"))
+			return
+		}
+	}
+	poll, err := cfg.db.GetPoll(r.Context(), chirp.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpapi.RespondWithError(w, httpapi.NotFound("Chirp has no poll"))
+			return
+		}
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	if !time.Now().Before(poll.ClosesAt) {
+		httpapi.RespondWithError(w, httpapi.Conflict("Poll is closed"))
+		return
+	}
+	if req.Option == nil || *req.Option < 0 || int(*req.Option) >= len(poll.Options) {
+		httpapi.RespondWithError(w, httpapi.ValidationFailed(validation.Errors{{
+			Field:   "option",
+			Code:    validation.CodeInvalid,
+			Message: fmt.Sprintf("option must be the index of one of the %d options", len(poll.Options)),
+		}}))
+		return
+	}
+
+	// one vote per user, for good
+	err = cfg.db.CreatePollVote(r.Context(), database.CreatePollVoteParams{
+		ChirpID:     chirp.ID,
+		UserID:      userID,
+		OptionIndex: *req.Option,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			httpapi.RespondWithError(w, httpapi.Conflict("You already voted in this poll"))
+			return
+		}
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// the chirp, now with the results
+	res, err := cfg.presentChirp(r.Context(), userID, chirp)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 201, res)
+}
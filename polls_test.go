@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPolls(t *testing.T) {
+	srv := newTestServer(t)
+	cheems := createTestUser(t, srv, "cheems@example.com")
+	doge := createTestUser(t, srv, "doge@example.com")
+	walter := createTestUser(t, srv, "walter@example.com")
+
+	post := func(poll map[string]any, want int) chirpResponse {
+		t.Helper()
+		return postChirp(t, srv, cheems.Token, map[string]any{"body": "tabs or spaces?", "poll": poll}, want)
+	}
+	later := time.Now().Add(time.Hour)
+	post(map[string]any{"options": []string{"tabs"}, "closes_at": later}, 400)
+	post(map[string]any{"options": []string{"a", "b", "c", "d", "e"}, "closes_at": later}, 400)
+	post(map[string]any{"options": []string{"tabs", " Tabs "}, "closes_at": later}, 400)
+	post(map[string]any{"options": []string{"tabs", ""}, "closes_at": later}, 400)
+	post(map[string]any{"options": []string{"tabs", strings.Repeat("s", maxPollOptionLength+1)}, "closes_at": later}, 400)
+	post(map[string]any{"options": []string{"tabs", "spaces"}, "closes_at": time.Now().Add(-time.Minute)}, 400)
+	post(map[string]any{"options": []string{"tabs", "spaces"}, "closes_at": time.Now().Add(maxPollDuration + time.Hour)}, 400)
+	postChirp(t, srv, cheems.Token, map[string]any{
+		"rechirp_of": uuid.NewString(),
+		"poll":       map[string]any{"options": []string{"tabs", "spaces"}, "closes_at": later},
+	}, 400)
+
+	chirp := post(map[string]any{"options": []string{" tabs", "spaces", "kerfuffle"}, "closes_at": later}, 201)
+	if chirp.Poll == nil || len(chirp.Poll.Options) != 3 || chirp.Poll.Options[0].Text != "tabs" || chirp.Poll.Options[2].Text != "****" {
+		t.Fatalf("poll = %+v", chirp.Poll)
+	}
+	if chirp.Poll.Closed || chirp.Poll.TotalVotes != nil || chirp.Poll.Options[0].Votes != nil {
+		t.Fatalf("poll before voting = %+v, want open with hidden results", chirp.Poll)
+	}
+	votes := "/api/chirps/" + chirp.ID.String() + "/poll/votes"
+
+	vote := func(user testUser, path string, option any, want int) chirpResponse {
+		t.Helper()
+		res := doJSON(t, srv, "POST", path, user.Token, map[string]any{"option": option})
+		var voted chirpResponse
+		if res.StatusCode != want {
+			res.Body.Close()
+			t.Fatalf("vote %v as %s: got %d want %d", option, user.Email, res.StatusCode, want)
+		}
+		if want == 201 {
+			decodeBody(t, res, &voted)
+		} else {
+			res.Body.Close()
+		}
+		return voted
+	}
+	vote(doge, votes, 3, 400)
+	vote(doge, votes, -1, 400)
+	vote(doge, votes, nil, 400)
+	vote(doge, "/api/chirps/"+uuid.NewString()+"/poll/votes", 0, 404)
+
+	plain := post(nil, 201)
+	vote(doge, "/api/chirps/"+plain.ID.String()+"/poll/votes", 0, 404)
+
+	voted := vote(doge, votes, 1, 201)
+	if p := voted.Poll; p == nil || p.MyVote == nil || *p.MyVote != 1 || p.TotalVotes == nil || *p.TotalVotes != 1 || *p.Options[1].Votes != 1 || *p.Options[0].Votes != 0 {
+		t.Fatalf("poll after voting = %+v", voted.Poll)
+	}
+	vote(doge, votes, 0, 409)
+
+	// voting through a rechirp votes in the original
+	rechirp := postChirp(t, srv, walter.Token, map[string]string{"rechirp_of": chirp.ID.String()}, 201)
+	voted = vote(walter, "/api/chirps/"+rechirp.ID.String()+"/poll/votes", 1, 201)
+	if voted.ID != chirp.ID || *voted.Poll.TotalVotes != 2 {
+		t.Fatalf("voting through a rechirp = %+v", voted)
+	}
+
+	// only those who voted see the tallies while the poll is open
+	getPoll := func(token string) *pollResponse {
+		t.Helper()
+		res := doJSON(t, srv, "GET", "/api/chirps/"+chirp.ID.String(), token, nil)
+		var got chirpResponse
+		decodeBody(t, res, &got)
+		return got.Poll
+	}
+	if p := getPoll(cheems.Token); p.TotalVotes != nil || p.MyVote != nil {
+		t.Fatalf("poll as the author, who didn't vote = %+v", p)
+	}
+	if p := getPoll(""); p.TotalVotes != nil {
+		t.Fatalf("poll as an anonymous viewer = %+v", p)
+	}
+	if p := getPoll(doge.Token); p.TotalVotes == nil || *p.Options[1].Votes != 2 {
+		t.Fatalf("poll as doge = %+v", p)
+	}
+
+	// and everyone once it closes
+	closing := post(map[string]any{"options": []string{"tabs", "spaces"}, "closes_at": time.Now().Add(time.Second)}, 201)
+	vote(doge, "/api/chirps/"+closing.ID.String()+"/poll/votes", 0, 201)
+	time.Sleep(time.Until(closing.Poll.ClosesAt))
+	vote(walter, "/api/chirps/"+closing.ID.String()+"/poll/votes", 0, 409)
+	page, _ := getChirpsPage(t, srv, "/api/chirps?sort=desc")
+	if len(page.Chirps) == 0 || page.Chirps[0].ID != closing.ID {
+		t.Fatalf("chirps = %+v, want the closed poll first", page.Chirps)
+	}
+	if p := page.Chirps[0].Poll; !p.Closed || p.TotalVotes == nil || *p.Options[0].Votes != 1 {
+		t.Fatalf("closed poll as an anonymous viewer = %+v", p)
+	}
+}
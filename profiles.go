@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/entities"
+	"github.com/Cheemx/chirpy/internal/entitlements"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/validation"
+	"github.com/google/uuid"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+)
+
+// userResponse is the signed-in user's own account. It's the only place
+// the email address is ever sent.
+type userResponse struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Email       string    `json:"email"`
+	Username    string    `json:"username,omitempty"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+	// RedExpiresAt is when Chirpy Red ends, null while it's open ended
+	// or the user isn't on it.
+	RedExpiresAt *time.Time `json:"red_expires_at"`
+}
+
+func (cfg *apiConfig) newUserResponse(user database.User) userResponse {
+	res := userResponse{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		Username:    user.Username.String,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarUrl,
+		IsChirpyRed: cfg.isChirpyRed(user),
+	}
+	if res.IsChirpyRed && user.RedExpiresAt.Valid {
+		res.RedExpiresAt = &user.RedExpiresAt.Time
+	}
+	return res
+}
+
+// authorResponse is the public face of a user, as shown next to their
+// chirps.
+type authorResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Username    string    `json:"username,omitempty"`
+	DisplayName string    `json:"display_name"`
+	AvatarURL   string    `json:"avatar_url"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+func (cfg *apiConfig) newAuthorResponse(user database.User) authorResponse {
+	return authorResponse{
+		ID:          user.ID,
+		Username:    user.Username.String,
+		DisplayName: user.DisplayName,
+		AvatarURL:   user.AvatarUrl,
+		IsChirpyRed: cfg.isChirpyRed(user),
+	}
+}
+
+// isChirpyRed reports whether user's Chirpy Red is active, i.e. hasn't
+// expired yet, going by the same clock as their entitlements.
+func (cfg *apiConfig) isChirpyRed(user database.User) bool {
+	return cfg.entitlements.Plan(user) == entitlements.Red
+}
+
+// profileResponse is a user's public profile.
+type profileResponse struct {
+	authorResponse
+	Bio            string    `json:"bio"`
+	CreatedAt      time.Time `json:"created_at"`
+	ChirpCount     int64     `json:"chirp_count"`
+	FollowerCount  int64     `json:"follower_count"`
+	FollowingCount int64     `json:"following_count"`
+}
+
+// checkProfile validates the optional profile fields of a user update.
+func checkProfile(errs *validation.Errors, displayName, bio, avatarURL *string) {
+	if displayName != nil {
+		errs.MaxLength("display_name", *displayName, maxDisplayNameLength)
+	}
+	if bio != nil {
+		errs.MaxLength("bio", *bio, maxBioLength)
+	}
+	if avatarURL != nil {
+		errs.HTTPURL("avatar_url", *avatarURL)
+	}
+}
+
+// chirpAuthors loads the authors of chirps, keyed by user ID.
+func (cfg *apiConfig) chirpAuthors(ctx context.Context, chirps []database.Chirp) (map[uuid.UUID]authorResponse, error) {
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.UserID
+	}
+	users, err := cfg.db.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	authors := map[uuid.UUID]authorResponse{}
+	for _, user := range users {
+		authors[user.ID] = cfg.newAuthorResponse(user)
+	}
+	return authors, nil
+}
+
+func (cfg *apiConfig) handleGetUserProfile(w http.ResponseWriter, r *http.Request) {
+	// anything that can't be a username can't be anyone's profile
+	username := r.PathValue("username")
+	if !entities.ValidUsername(username) {
+		httpapi.RespondWithError(w, httpapi.NotFound("User not found"))
+		return
+	}
+
+	user, err := cfg.db.GetUserByUsername(r.Context(), username)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "User"))
+		return
+	}
+
+	counts, err := cfg.db.GetUserCounts(r.Context(), user.ID)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, profileResponse{
+		authorResponse: cfg.newAuthorResponse(user),
+		Bio:            user.Bio,
+		CreatedAt:      user.CreatedAt,
+		ChirpCount:     counts.ChirpCount,
+		FollowerCount:  counts.FollowerCount,
+		FollowingCount: counts.FollowingCount,
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	srv := newTestServer(t)
+	cheems := createTestUser(t, srv, "cheems@example.com")
+	fan := createTestUser(t, srv, "fan@example.com")
+
+	update := func(fields map[string]string, want int) {
+		t.Helper()
+		body := map[string]string{"email": "cheems@example.com", "password": "password"}
+		maps.Copy(body, fields)
+		if res := doJSON(t, srv, "PUT", "/api/users", cheems.Token, body); res.StatusCode != want {
+			t.Fatalf("update %v: got %d want %d", fields, res.StatusCode, want)
+		}
+	}
+	update(map[string]string{
+		"username":     "Cheems",
+		"display_name": "Cheems 🐕",
+		"bio":          "much bio",
+		"avatar_url":   "https://example.com/cheems.png",
+	}, 200)
+	update(map[string]string{"avatar_url": "javascript:alert(1)"}, 400)
+	update(map[string]string{"display_name": strings.Repeat("a", 51)}, 400)
+	// fields left out keep their value
+	update(map[string]string{"bio": "new bio"}, 200)
+
+	postChirp(t, srv, cheems.Token, map[string]string{"body": "hello"}, 201)
+	doJSON(t, srv, "POST", "/api/users/"+cheems.ID.String()+"/follow", fan.Token, nil)
+
+	res := doJSON(t, srv, "GET", "/api/users/cHEEMS", "", nil)
+	if res.StatusCode != 200 {
+		t.Fatalf("get profile: got %d want 200", res.StatusCode)
+	}
+	var profile map[string]any
+	decodeBody(t, res, &profile)
+	want := map[string]any{
+		"id":              cheems.ID.String(),
+		"username":        "Cheems",
+		"display_name":    "Cheems 🐕",
+		"bio":             "new bio",
+		"avatar_url":      "https://example.com/cheems.png",
+		"is_chirpy_red":   false,
+		"chirp_count":     1.0,
+		"follower_count":  1.0,
+		"following_count": 0.0,
+	}
+	for k, v := range want {
+		if profile[k] != v {
+			t.Errorf("profile[%q] = %v, want %v", k, profile[k], v)
+		}
+	}
+	if _, ok := profile["email"]; ok {
+		t.Errorf("profile leaks the email: %v", profile)
+	}
+
+	page, _ := getChirpsPage(t, srv, "/api/chirps")
+	if len(page.Chirps) != 1 || page.Chirps[0].Author.Username != "Cheems" || page.Chirps[0].Author.DisplayName != "Cheems 🐕" {
+		t.Fatalf("chirp author = %+v", page.Chirps)
+	}
+
+	for _, path := range []string{"/api/users/nobody", "/api/users/not-a-name"} {
+		if res := doJSON(t, srv, "GET", path, "", nil); res.StatusCode != 404 {
+			t.Fatalf("GET %s: got %d want 404", path, res.StatusCode)
+		}
+	}
+}
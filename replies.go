@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/pagination"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultThreadDepth = 3
+	maxThreadDepth     = 10
+)
+
+// threadNode is a chirp in a conversation tree together with its replies.
+type threadNode struct {
+	chirpResponse
+	Replies []*threadNode `json:"replies"`
+}
+
+func (cfg *apiConfig) handleGetChirpReplies(w http.ResponseWriter, r *http.Request) {
+	// parse the chirpID to uuid format
+	id, err := pathUUID(r, "chirpID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// make sure the chirp being replied to exists
+	_, err = cfg.db.GetChirpByID(r.Context(), id)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "Chirp"))
+		return
+	}
+
+	params := database.ListChirpRepliesParams{
+		ParentID: id,
+		PageSize: page.FetchSize(),
+	}
+	params.AfterCreatedAt, params.AfterID = page.After()
+	replies, err := cfg.db.ListChirpReplies(r.Context(), params)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Creating response Body
+	res := struct {
+		Chirps     []chirpResponse `json:"chirps"`
+		NextCursor string          `json:"next_cursor,omitempty"`
+	}{}
+	replies, res.NextCursor = pagination.Trim(page, replies, chirpCursor)
+	if res.NextCursor != "" {
+		w.Header().Set("Link", pagination.NextLink(r.URL, res.NextCursor))
+	}
+	res.Chirps, err = cfg.presentChirps(r.Context(), cfg.viewerID(r), replies)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
+
+func (cfg *apiConfig) handleGetChirpThread(w http.ResponseWriter, r *http.Request) {
+	// parse the chirpID to uuid format
+	id, err := pathUUID(r, "chirpID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// how many levels of replies to include below the chirp
+	depth := defaultThreadDepth
+	if d := r.URL.Query().Get("depth"); d != "" {
+		depth, err = strconv.Atoi(d)
+		if err != nil || depth < 0 || depth > maxThreadDepth {
+			httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, fmt.Sprintf("depth must be between 0 and %d", maxThreadDepth)))
+			return
+		}
+	}
+
+	rows, err := cfg.db.GetChirpThread(r.Context(), database.GetChirpThreadParams{
+		ID:       id,
+		MaxDepth: int32(depth),
+	})
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	if len(rows) == 0 {
+		httpapi.RespondWithError(w, httpapi.NotFound("Chirp not found"))
+		return
+	}
+
+	chirps := make([]database.Chirp, len(rows))
+	for i, row := range rows {
+		chirps[i] = database.Chirp{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+			Body:        row.Body,
+			UserID:      row.UserID,
+			ParentID:    row.ParentID,
+			RootID:      row.RootID,
+			RechirpOfID: row.RechirpOfID,
+			QuoteOfID:   row.QuoteOfID,
+		}
+	}
+	presented, err := cfg.presentChirps(r.Context(), cfg.viewerID(r), chirps)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// rows come ordered by depth, so every parent is seen before its replies
+	nodes := map[uuid.UUID]*threadNode{}
+	for _, chirp := range presented {
+		node := &threadNode{chirpResponse: chirp, Replies: []*threadNode{}}
+		nodes[chirp.ID] = node
+		if parent, ok := nodes[chirp.ParentID.UUID]; ok && chirp.ID != id {
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, nodes[id])
+}
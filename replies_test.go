@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestChirpThreads(t *testing.T) {
+	srv := newTestServer(t)
+	user := createTestUser(t, srv, "cheems@example.com")
+
+	reply := func(to chirpResponse, body string) chirpResponse {
+		t.Helper()
+		return postChirp(t, srv, user.Token, map[string]string{"body": body, "in_reply_to": to.ID.String()}, 201)
+	}
+
+	root := postChirp(t, srv, user.Token, map[string]string{"body": "root"}, 201)
+	a := reply(root, "a")
+	reply(root, "b")
+	a1 := reply(a, "a1")
+	reply(a1, "a1x")
+
+	if a1.RootID.UUID != root.ID || a1.ParentID.UUID != a.ID {
+		t.Fatalf("a1 parent %v root %v", a1.ParentID, a1.RootID)
+	}
+
+	postChirp(t, srv, user.Token, map[string]string{"body": "x", "in_reply_to": uuid.NewString()}, 400)
+
+	res := doJSON(t, srv, "GET", "/api/chirps/"+root.ID.String(), "", nil)
+	var got chirpResponse
+	decodeBody(t, res, &got)
+	if got.ReplyCount != 2 {
+		t.Fatalf("root reply_count = %d want 2", got.ReplyCount)
+	}
+
+	page, _ := getChirpsPage(t, srv, "/api/chirps/"+root.ID.String()+"/replies?limit=1")
+	if len(page.Chirps) != 1 || page.Chirps[0].Body != "a" || page.NextCursor == "" {
+		t.Fatalf("first replies page: %+v", page)
+	}
+
+	res = doJSON(t, srv, "GET", "/api/chirps/"+root.ID.String()+"/thread?depth=2", "", nil)
+	if res.StatusCode != 200 {
+		t.Fatalf("get thread: got %d want 200", res.StatusCode)
+	}
+	var thread threadNode
+	decodeBody(t, res, &thread)
+	if len(thread.Replies) != 2 || len(thread.Replies[0].Replies) != 1 {
+		t.Fatalf("unexpected thread shape: %+v", thread)
+	}
+	if leaf := thread.Replies[0].Replies[0]; len(leaf.Replies) != 0 || leaf.ReplyCount != 1 {
+		t.Fatalf("depth limit not applied: %d replies, reply_count %d", len(leaf.Replies), leaf.ReplyCount)
+	}
+
+	res = doJSON(t, srv, "GET", "/api/chirps/"+uuid.NewString()+"/thread", "", nil)
+	if res.StatusCode != 404 {
+		t.Fatalf("thread of missing chirp: got %d want 404", res.StatusCode)
+	}
+}
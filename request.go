@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/auth"
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/pagination"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// authenticate returns the user behind the request's access token.
+func (cfg *apiConfig) authenticate(r *http.Request) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, httpapi.Unauthorized(err)
+	}
+	user, _, err := cfg.tokenUser(r.Context(), token)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return user.ID, nil
+}
+
+// tokenUser returns the user an access token was issued for, and when the
+// token expires, as long as their account hasn't been deleted since.
+func (cfg *apiConfig) tokenUser(ctx context.Context, token string) (database.User, time.Time, error) {
+	userID, expiresAt, err := auth.ParseJWT(token, cfg.jwtSecret)
+	if err != nil {
+		return database.User{}, time.Time{}, httpapi.Unauthorized(err)
+	}
+
+	// access tokens outlive the account they were issued for
+	user, err := cfg.db.GetUserByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, time.Time{}, httpapi.Unauthorized(errors.New("account deleted"))
+	}
+	if err != nil {
+		return database.User{}, time.Time{}, err
+	}
+	return user, expiresAt, nil
+}
+
+// checkAPIKey makes sure the request carries "Authorization: ApiKey <want>".
+// The comparison takes the same time wherever the keys differ, so the key
+// can't be guessed a byte at a time, and an unset key matches nothing.
+func checkAPIKey(r *http.Request, want string) error {
+	apiKey, err := auth.GetAPIKey(r.Header)
+	if err != nil {
+		return httpapi.Unauthorized(err)
+	}
+	if want == "" || subtle.ConstantTimeCompare([]byte(apiKey), []byte(want)) != 1 {
+		return httpapi.Unauthorized(errors.New("wrong API key"))
+	}
+	return nil
+}
+
+// pathUUID parses the named path wildcard as a UUID.
+func pathUUID(r *http.Request, name string) (uuid.UUID, error) {
+	id, err := uuid.Parse(r.PathValue(name))
+	if err != nil {
+		return uuid.Nil, httpapi.BadRequest(httpapi.CodeInvalidParameter, name+" is not a valid ID")
+	}
+	return id, nil
+}
+
+// parsePage reads the limit and cursor query parameters.
+func parsePage(r *http.Request) (pagination.Page, error) {
+	page, err := pagination.Parse(r.URL.Query())
+	if err != nil {
+		return pagination.Page{}, httpapi.BadRequest(httpapi.CodeInvalidParameter, err.Error())
+	}
+	return page, nil
+}
+
+// lookupError turns a failed single-row lookup into a 404 when the row is
+// missing and a 500 otherwise.
+func lookupError(err error, what string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return httpapi.NotFound(what + " not found")
+	}
+	return httpapi.InternalError(err)
+}
+
+// isUniqueViolation reports whether err comes from a unique constraint, from
+// either store.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	return errors.Is(err, database.ErrUniqueViolation)
+}
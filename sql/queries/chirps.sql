@@ -1,22 +1,99 @@
 -- name: CreateChirp :one
-INSERT INTO chirps(id, created_at, updated_at, body, user_id)
+INSERT INTO chirps(id, created_at, updated_at, body, user_id, parent_id, root_id, rechirp_of_id, quote_of_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
 )
 RETURNING *;
 
--- name: DeleteAllChirps :exec
-TRUNCATE TABLE chirps;
-
 -- name: DeleteChirpByID :exec
-DELETE FROM chirps WHERE id = $1;
+UPDATE chirps SET deleted_at = NOW()
+WHERE (id = $1 OR rechirp_of_id = $1) AND deleted_at IS NULL;
 
 -- name: GetAllChirps :many
-SELECT * FROM chirps ORDER BY created_at;
+SELECT * FROM chirps WHERE deleted_at IS NULL ORDER BY created_at;
 
 -- name: GetChirpByID :one
-SELECT * FROM chirps WHERE id = $1;
\ No newline at end of file
+SELECT * FROM chirps WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: ListChirpsAsc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND (
+    sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY created_at, id
+LIMIT sqlc.arg('page_size');
+
+-- name: ListChirpsDesc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+AND (
+    sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+
+-- name: ListChirpReplies :many
+SELECT * FROM chirps
+WHERE parent_id = sqlc.arg('parent_id')::uuid
+AND deleted_at IS NULL
+AND (
+    sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY created_at, id
+LIMIT sqlc.arg('page_size');
+
+-- name: CountChirpReplies :many
+SELECT parent_id, COUNT(*) AS reply_count
+FROM chirps
+WHERE parent_id = ANY(sqlc.arg('parent_ids')::uuid[])
+AND deleted_at IS NULL
+GROUP BY parent_id;
+
+-- name: GetChirpThread :many
+WITH RECURSIVE thread AS (
+    SELECT chirps.*, 0 AS depth
+    FROM chirps
+    WHERE chirps.id = sqlc.arg('id') AND chirps.deleted_at IS NULL
+    UNION ALL
+    SELECT replies.*, thread.depth + 1
+    FROM chirps replies
+    JOIN thread ON replies.parent_id = thread.id
+    WHERE thread.depth < sqlc.arg('max_depth')::int AND replies.deleted_at IS NULL
+)
+SELECT * FROM thread
+ORDER BY depth, created_at, id;
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[]) AND deleted_at IS NULL;
+
+-- name: GetUserRechirp :one
+SELECT * FROM chirps
+WHERE user_id = $1 AND rechirp_of_id = $2 AND deleted_at IS NULL;
+
+-- name: CountRechirps :many
+SELECT rechirp_of_id, COUNT(*) AS rechirp_count
+FROM chirps
+WHERE rechirp_of_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+AND deleted_at IS NULL
+GROUP BY rechirp_of_id;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps SET body = $2, updated_at = NOW()
+WHERE id = $1 AND updated_at = $3 AND deleted_at IS NULL
+RETURNING *;
@@ -0,0 +1,68 @@
+-- name: CreateDraft :one
+INSERT INTO drafts(id, user_id, body, parent_id, quote_of_id, publish_at, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: GetDraft :one
+SELECT * FROM drafts
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListDrafts :many
+SELECT * FROM drafts
+WHERE user_id = sqlc.arg('user_id')
+AND (
+    sqlc.narg('after_created_at')::timestamptz IS NULL
+    OR (created_at, id) < (sqlc.narg('after_created_at')::timestamptz, sqlc.narg('after_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: UpdateDraft :one
+UPDATE drafts
+SET body = $3,
+    parent_id = $4,
+    quote_of_id = $5,
+    publish_at = $6,
+    last_error = NULL,
+    attempts = 0,
+    next_attempt_at = NULL,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteDraft :execrows
+DELETE FROM drafts
+WHERE id = $1 AND user_id = $2;
+
+-- name: LockDraft :one
+SELECT * FROM drafts
+WHERE id = $1 AND user_id = $2
+FOR UPDATE SKIP LOCKED;
+
+-- name: ClaimDueDraft :one
+SELECT * FROM drafts
+WHERE publish_at <= sqlc.arg('now')::timestamptz
+AND (next_attempt_at IS NULL OR next_attempt_at <= sqlc.arg('now')::timestamptz)
+AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+ORDER BY publish_at, id
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: FailDraft :exec
+UPDATE drafts
+SET publish_at = NULL, last_error = $2, attempts = 0, next_attempt_at = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: RetryDraft :exec
+UPDATE drafts
+SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3
+WHERE id = $1;
@@ -0,0 +1,55 @@
+-- name: FollowUser :execrows
+INSERT INTO follows(follower_id, followee_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :execrows
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: ListFollowers :many
+SELECT follower_id AS user_id, created_at
+FROM follows
+WHERE followee_id = sqlc.arg('user_id')
+AND follower_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+AND (
+    sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (created_at, follower_id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY created_at DESC, follower_id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: ListFollowing :many
+SELECT followee_id AS user_id, created_at
+FROM follows
+WHERE follower_id = sqlc.arg('user_id')
+AND followee_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+AND (
+    sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (created_at, followee_id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY created_at DESC, followee_id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: ListFolloweeIDs :many
+SELECT followee_id
+FROM follows
+WHERE follower_id = $1;
+
+-- name: ListTimeline :many
+SELECT * FROM chirps
+WHERE (
+    user_id = sqlc.arg('user_id')
+    OR user_id IN (SELECT followee_id FROM follows WHERE follower_id = sqlc.arg('user_id'))
+)
+AND deleted_at IS NULL
+AND (
+    sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
@@ -0,0 +1,38 @@
+-- name: LikeChirp :execrows
+INSERT INTO likes(user_id, chirp_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: UnlikeChirp :execrows
+DELETE FROM likes
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: CountChirpLikes :many
+SELECT chirp_id, COUNT(*) AS like_count
+FROM likes
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+GROUP BY chirp_id;
+
+-- name: ListLikedChirpIDs :many
+SELECT chirp_id
+FROM likes
+WHERE user_id = sqlc.arg('user_id')
+AND chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: ListUserLikes :many
+SELECT sqlc.embed(chirps), likes.created_at AS liked_at
+FROM likes
+JOIN chirps ON chirps.id = likes.chirp_id
+WHERE likes.user_id = sqlc.arg('user_id')
+AND chirps.deleted_at IS NULL
+AND (
+    sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (likes.created_at, chirps.id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY likes.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_size');
@@ -0,0 +1,38 @@
+-- name: CreateMedia :one
+INSERT INTO media(id, user_id, content_type, width, height, size_bytes, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetMedia :one
+SELECT * FROM media
+WHERE id = $1;
+
+-- name: AttachMedia :execrows
+UPDATE media
+SET chirp_id = sqlc.arg('chirp_id')::uuid, position = sqlc.arg('position')
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND chirp_id IS NULL;
+
+-- name: ListChirpMedia :many
+SELECT * FROM media
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_id, position;
+
+-- name: CountPendingMedia :one
+SELECT COUNT(*)
+FROM media
+WHERE user_id = $1 AND chirp_id IS NULL;
+
+-- name: PurgeMedia :many
+DELETE FROM media
+WHERE chirp_id IN (SELECT id FROM chirps WHERE deleted_at < sqlc.arg('before')::timestamp)
+OR user_id IN (SELECT id FROM users WHERE deleted_at < sqlc.arg('before')::timestamp)
+OR (chirp_id IS NULL AND created_at < sqlc.arg('unattached_before')::timestamp)
+RETURNING *;
@@ -0,0 +1,33 @@
+-- name: CreateMention :exec
+INSERT INTO mentions(chirp_id, user_id, start_index, end_index, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: ListChirpMentions :many
+SELECT mentions.chirp_id, mentions.user_id, mentions.start_index, mentions.end_index, users.username
+FROM mentions
+JOIN users ON users.id = mentions.user_id
+WHERE mentions.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+AND users.deleted_at IS NULL
+ORDER BY mentions.chirp_id, mentions.start_index;
+
+-- name: ListUserMentions :many
+SELECT * FROM chirps
+WHERE id IN (SELECT chirp_id FROM mentions WHERE mentions.user_id = sqlc.arg('user_id'))
+AND user_id <> sqlc.arg('user_id')
+AND deleted_at IS NULL
+AND (
+    sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM mentions WHERE chirp_id = $1;
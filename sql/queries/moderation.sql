@@ -0,0 +1,34 @@
+-- name: ListBannedWords :many
+SELECT word FROM banned_words
+ORDER BY word;
+
+-- name: AddBannedWord :execrows
+INSERT INTO banned_words(word, created_at)
+VALUES (
+    $1,
+    NOW()
+)
+ON CONFLICT (word) DO NOTHING;
+
+-- name: DeleteBannedWord :execrows
+DELETE FROM banned_words
+WHERE word = $1;
+
+-- name: CreateModerationFlag :one
+INSERT INTO moderation_flags(id, chirp_id, reason, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    NOW()
+)
+RETURNING *;
+
+-- name: ListModerationFlags :many
+SELECT * FROM moderation_flags
+WHERE (
+    sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
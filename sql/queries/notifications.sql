@@ -0,0 +1,74 @@
+-- name: UpsertNotification :one
+INSERT INTO notifications(id, user_id, type, chirp_id, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    NOW(),
+    NOW()
+)
+ON CONFLICT (user_id, type, chirp_id) WHERE read_at IS NULL
+DO UPDATE SET user_id = EXCLUDED.user_id
+RETURNING *;
+
+-- name: AddNotificationActor :execrows
+INSERT INTO notification_actors(notification_id, actor_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: TouchNotification :exec
+UPDATE notifications
+SET updated_at = NOW()
+WHERE id = $1;
+
+-- name: ListNotifications :many
+SELECT * FROM notifications
+WHERE user_id = sqlc.arg('user_id')
+AND (
+    sqlc.narg('after_updated_at')::timestamp IS NULL
+    OR (updated_at, id) < (sqlc.narg('after_updated_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY updated_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: ListNotificationActors :many
+SELECT *
+FROM notification_actors
+WHERE notification_id = ANY(sqlc.arg('notification_ids')::uuid[])
+ORDER BY notification_id, created_at DESC, actor_id;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*)
+FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = sqlc.arg('user_id')
+AND read_at IS NULL
+AND (
+    sqlc.narg('up_to_updated_at')::timestamp IS NULL
+    OR (updated_at, id) <= (sqlc.narg('up_to_updated_at')::timestamp, sqlc.narg('up_to_id')::uuid)
+);
+
+-- name: ListNotificationPreferences :many
+SELECT *
+FROM notification_preferences
+WHERE user_id = $1
+ORDER BY type;
+
+-- name: SetNotificationPreference :exec
+INSERT INTO notification_preferences(user_id, type, enabled, updated_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = NOW();
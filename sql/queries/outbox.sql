@@ -0,0 +1,38 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox_events(id, type, payload, created_at, next_attempt_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    -- lib/pq would send a []byte as bytea, so the JSON goes in as text
+    sqlc.arg('payload')::text::jsonb,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: ClaimOutboxEvents :many
+UPDATE outbox_events
+SET attempts = attempts + 1, next_attempt_at = sqlc.arg('locked_until')
+WHERE id IN (
+    SELECT id FROM outbox_events
+    WHERE delivered_at IS NULL
+    AND next_attempt_at <= NOW()
+    AND attempts < sqlc.arg('max_attempts')
+    ORDER BY created_at, id
+    LIMIT sqlc.arg('batch_size')
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: DeleteDeliveredOutboxEventsBefore :execrows
+DELETE FROM outbox_events WHERE delivered_at < sqlc.arg('before')::timestamptz;
+
+-- name: MarkOutboxEventDelivered :exec
+UPDATE outbox_events
+SET delivered_at = NOW(), last_error = NULL
+WHERE id = $1;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox_events
+SET next_attempt_at = $2, last_error = $3
+WHERE id = $1;
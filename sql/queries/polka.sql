@@ -0,0 +1,34 @@
+-- name: RecordWebhookEvent :execrows
+INSERT INTO webhook_events(id, source, type, received_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: CreateRedStatusChange :one
+INSERT INTO red_status_changes(id, user_id, is_chirpy_red, reason, webhook_event_id, effective_at, expires_at, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetLatestRedStatusChange :one
+SELECT * FROM red_status_changes
+WHERE user_id = $1
+ORDER BY effective_at DESC, created_at DESC
+LIMIT 1;
+
+-- name: ListRedStatusChanges :many
+SELECT * FROM red_status_changes
+WHERE user_id = $1
+ORDER BY effective_at DESC, created_at DESC;
@@ -0,0 +1,39 @@
+-- name: CreatePoll :one
+INSERT INTO polls(chirp_id, options, closes_at, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetPoll :one
+SELECT * FROM polls
+WHERE chirp_id = $1;
+
+-- name: ListPolls :many
+SELECT * FROM polls
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: CreatePollVote :exec
+INSERT INTO poll_votes(chirp_id, user_id, option_index, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+);
+
+-- name: CountPollVotes :many
+SELECT chirp_id, option_index, COUNT(*) AS vote_count
+FROM poll_votes
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+GROUP BY chirp_id, option_index;
+
+-- name: ListUserPollVotes :many
+SELECT chirp_id, option_index
+FROM poll_votes
+WHERE user_id = sqlc.arg('user_id')
+AND chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
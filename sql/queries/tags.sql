@@ -0,0 +1,47 @@
+-- name: UpsertTag :one
+INSERT INTO tags(id, name, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    NOW()
+)
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: TagChirp :exec
+INSERT INTO chirp_tags(chirp_id, tag_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: ListTagChirps :many
+SELECT chirps.* FROM chirps
+JOIN chirp_tags ON chirp_tags.chirp_id = chirps.id
+JOIN tags ON tags.id = chirp_tags.tag_id
+WHERE tags.name = sqlc.arg('tag')
+AND chirps.deleted_at IS NULL
+AND (
+    sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: ListTrendingTags :many
+SELECT tags.name, COUNT(*) AS chirp_count
+FROM chirp_tags
+JOIN tags ON tags.id = chirp_tags.tag_id
+JOIN chirps ON chirps.id = chirp_tags.chirp_id
+WHERE chirp_tags.created_at >= sqlc.arg('since')::timestamp
+AND chirps.deleted_at IS NULL
+GROUP BY tags.name
+ORDER BY chirp_count DESC, tags.name
+LIMIT sqlc.arg('max_tags');
+
+-- name: UntagChirp :exec
+DELETE FROM chirp_tags
+WHERE chirp_id = sqlc.arg('chirp_id')
+AND tag_id NOT IN (SELECT id FROM tags WHERE name = ANY(sqlc.arg('keep')::text[]));
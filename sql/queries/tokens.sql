@@ -20,9 +20,14 @@ SELECT users.*
 FROM refresh_tokens
 JOIN users
 ON users.id = refresh_tokens.user_id
-WHERE token = $1;
+WHERE token = $1 AND users.deleted_at IS NULL;
 
 -- name: SetTokenTimestamps :execrows
 UPDATE refresh_tokens
 SET revoked_at = $1, updated_at = $2
-WHERE token = $3 AND revoked_at IS NULL;
\ No newline at end of file
+WHERE token = $3 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
\ No newline at end of file
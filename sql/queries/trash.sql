@@ -0,0 +1,38 @@
+-- name: ListTrash :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg('user_id')
+AND deleted_at > sqlc.arg('since')::timestamp
+AND (rechirp_of_id IS NULL OR rechirp_of_id IN (SELECT originals.id FROM chirps originals WHERE originals.deleted_at IS NULL))
+AND (
+    sqlc.narg('after_deleted_at')::timestamp IS NULL
+    OR (deleted_at, id) < (sqlc.narg('after_deleted_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY deleted_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: GetTrashedChirp :one
+SELECT * FROM chirps
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id')
+AND deleted_at > sqlc.arg('since')::timestamp
+AND (rechirp_of_id IS NULL OR rechirp_of_id IN (SELECT originals.id FROM chirps originals WHERE originals.deleted_at IS NULL));
+
+-- name: RestoreChirp :exec
+UPDATE chirps SET deleted_at = NULL
+WHERE (id = sqlc.arg('id') OR rechirp_of_id = sqlc.arg('id'))
+AND deleted_at = sqlc.arg('deleted_at')::timestamp;
+
+-- name: DeleteUserChirps :exec
+UPDATE chirps SET deleted_at = sqlc.arg('deleted_at')::timestamp
+WHERE (user_id = sqlc.arg('user_id') OR rechirp_of_id IN (SELECT own.id FROM chirps own WHERE own.user_id = sqlc.arg('user_id')))
+AND deleted_at IS NULL;
+
+-- name: RestoreUserChirps :exec
+UPDATE chirps SET deleted_at = NULL
+WHERE (user_id = sqlc.arg('user_id') OR rechirp_of_id IN (SELECT own.id FROM chirps own WHERE own.user_id = sqlc.arg('user_id')))
+AND deleted_at = sqlc.arg('deleted_at')::timestamp;
+
+-- name: PurgeDeletedChirps :execrows
+DELETE FROM chirps WHERE deleted_at < sqlc.arg('before')::timestamp;
+
+-- name: PurgeDeletedUsers :execrows
+DELETE FROM users WHERE deleted_at < sqlc.arg('before')::timestamp;
@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users(id, created_at, updated_at, email, hashed_password)
+INSERT INTO users(id, created_at, updated_at, email, hashed_password, username)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
@@ -19,12 +20,53 @@ WHERE email = $1;
 
 -- name: UpdateUser :one
 UPDATE users
-SET email = $1, hashed_password = $2
-WHERE id = $3
+SET email = sqlc.arg('email'),
+    hashed_password = sqlc.arg('hashed_password'),
+    username = COALESCE(sqlc.narg('username'), username),
+    display_name = COALESCE(sqlc.narg('display_name'), display_name),
+    bio = COALESCE(sqlc.narg('bio'), bio),
+    avatar_url = COALESCE(sqlc.narg('avatar_url'), avatar_url)
+WHERE id = sqlc.arg('id') AND deleted_at IS NULL
 RETURNING *;
 
--- name: UpdateUserToRedByID :one
+-- name: UpdateUserChirpyRed :one
 UPDATE users
-SET is_chirpy_red = true 
+SET is_chirpy_red = $2, red_expires_at = $3
 WHERE id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetUserByID :one
+SELECT *
+FROM users
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: GetUserByUsername :one
+SELECT *
+FROM users
+WHERE LOWER(username) = LOWER($1) AND deleted_at IS NULL;
+
+-- name: GetUsersByIDs :many
+SELECT *
+FROM users
+WHERE id = ANY(sqlc.arg('ids')::uuid[]) AND deleted_at IS NULL;
+
+-- name: GetUserCounts :one
+SELECT
+    (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = $1 AND chirps.deleted_at IS NULL) AS chirp_count,
+    (SELECT COUNT(*) FROM follows JOIN users ON users.id = follows.follower_id WHERE follows.followee_id = $1 AND users.deleted_at IS NULL) AS follower_count,
+    (SELECT COUNT(*) FROM follows JOIN users ON users.id = follows.followee_id WHERE follows.follower_id = $1 AND users.deleted_at IS NULL) AS following_count;
+
+-- name: GetUsersByUsernames :many
+SELECT *
+FROM users
+WHERE LOWER(username) = ANY(sqlc.arg('usernames')::text[]) AND deleted_at IS NULL;
+
+-- name: DeleteUser :one
+UPDATE users SET deleted_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RestoreUser :one
+UPDATE users SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING *;
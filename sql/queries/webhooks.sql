@@ -0,0 +1,128 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints(id, user_id, url, secret, events, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: GetWebhookEndpoint :one
+SELECT * FROM webhook_endpoints
+WHERE id = $1;
+
+-- name: ListWebhookEndpoints :many
+SELECT * FROM webhook_endpoints
+WHERE user_id = $1
+ORDER BY created_at, id;
+
+-- name: ListAdminWebhookEndpoints :many
+SELECT * FROM webhook_endpoints
+WHERE user_id IS NULL
+ORDER BY created_at, id;
+
+-- name: ListWebhookEndpointsForEvent :many
+SELECT * FROM webhook_endpoints
+WHERE enabled AND sqlc.arg('event_type')::text = ANY(events)
+AND (
+    user_id IS NULL
+    OR user_id = sqlc.arg('user_id') AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+);
+
+-- name: UpdateWebhookEndpoint :one
+UPDATE webhook_endpoints
+SET url = COALESCE(sqlc.narg('url'), url),
+    events = COALESCE(sqlc.narg('events'), events),
+    enabled = COALESCE(sqlc.narg('enabled'), enabled),
+    failure_count = CASE WHEN sqlc.narg('enabled')::boolean THEN 0 ELSE failure_count END,
+    disabled_at = CASE
+        WHEN sqlc.narg('enabled')::boolean IS NULL THEN disabled_at
+        WHEN sqlc.narg('enabled')::boolean THEN NULL
+        ELSE COALESCE(disabled_at, NOW())
+    END,
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: DeleteWebhookEndpoint :exec
+DELETE FROM webhook_endpoints
+WHERE id = $1;
+
+-- name: RecordWebhookSuccess :exec
+UPDATE webhook_endpoints
+SET failure_count = 0
+WHERE id = $1;
+
+-- name: RecordWebhookFailure :one
+UPDATE webhook_endpoints
+SET failure_count = failure_count + 1,
+    enabled = enabled AND failure_count + 1 < sqlc.arg('disable_after'),
+    disabled_at = CASE
+        WHEN enabled AND failure_count + 1 >= sqlc.arg('disable_after') THEN NOW()
+        ELSE disabled_at
+    END
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: CreateWebhookDelivery :execrows
+INSERT INTO webhook_deliveries(id, endpoint_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    -- lib/pq would send a []byte as bytea, so the JSON goes in as text
+    sqlc.arg('payload')::text::jsonb,
+    'pending',
+    NOW(),
+    NOW()
+)
+ON CONFLICT (endpoint_id, event_id) DO NOTHING;
+
+-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET attempts = attempts + 1, next_attempt_at = sqlc.arg('locked_until'), last_attempt_at = NOW()
+WHERE id IN (
+    SELECT webhook_deliveries.id FROM webhook_deliveries
+    JOIN webhook_endpoints ON webhook_endpoints.id = webhook_deliveries.endpoint_id
+    WHERE webhook_deliveries.status = 'pending'
+    AND webhook_deliveries.next_attempt_at <= NOW()
+    AND webhook_endpoints.enabled
+    ORDER BY webhook_deliveries.created_at, webhook_deliveries.id
+    LIMIT sqlc.arg('batch_size')
+    FOR UPDATE OF webhook_deliveries SKIP LOCKED
+)
+RETURNING *;
+
+-- name: DeleteFinishedWebhookDeliveriesBefore :execrows
+DELETE FROM webhook_deliveries
+WHERE status <> 'pending' AND COALESCE(last_attempt_at, created_at) < sqlc.arg('before')::timestamptz;
+
+-- name: FinishWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status = $2, next_attempt_at = $3, response_status = $4, last_error = $5
+WHERE id = $1;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE endpoint_id = sqlc.arg('endpoint_id')
+AND (
+    sqlc.narg('after_created_at')::timestamptz IS NULL
+    OR (created_at, id) < (sqlc.narg('after_created_at')::timestamptz, sqlc.narg('after_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: RedeliverWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE likes(
+    user_id UUID NOT NULL,
+    chirp_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT likes_user_id_chirp_id_key
+        UNIQUE (user_id, chirp_id),
+    CONSTRAINT fk_users
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_chirps
+        FOREIGN KEY (chirp_id)
+        REFERENCES chirps(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX likes_chirp_id_idx ON likes (chirp_id);
+CREATE INDEX likes_user_id_created_at_idx ON likes (user_id, created_at);
+
+-- +goose Down
+DROP TABLE likes;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- A rechirp goes away with the chirp it reposts. A quote keeps its own text,
+-- so quote_of_id is deliberately not a foreign key: it keeps pointing at the
+-- original after it is deleted and the API reports the original as missing.
+ALTER TABLE chirps
+ADD COLUMN rechirp_of_id UUID
+    REFERENCES chirps(id) ON DELETE CASCADE,
+ADD COLUMN quote_of_id UUID;
+
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_id_key ON chirps (user_id, rechirp_of_id)
+WHERE rechirp_of_id IS NOT NULL;
+CREATE INDEX chirps_rechirp_of_id_idx ON chirps (rechirp_of_id);
+
+-- +goose Down
+DROP INDEX chirps_rechirp_of_id_idx;
+DROP INDEX chirps_user_id_rechirp_of_id_key;
+ALTER TABLE chirps
+DROP COLUMN quote_of_id,
+DROP COLUMN rechirp_of_id;
@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE banned_words(
+    word TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- the words handleCreateChirp used to hardcode
+INSERT INTO banned_words(word, created_at)
+VALUES ('kerfuffle', NOW()), ('sharbert', NOW()), ('fornax', NOW());
+
+CREATE TABLE moderation_flags(
+    id UUID PRIMARY KEY,
+    chirp_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_chirps
+        FOREIGN KEY (chirp_id)
+        REFERENCES chirps(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX moderation_flags_created_at_idx ON moderation_flags (created_at);
+
+-- +goose Down
+DROP TABLE moderation_flags;
+DROP TABLE banned_words;
@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE tags(
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE chirp_tags(
+    chirp_id UUID NOT NULL,
+    tag_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, tag_id),
+    CONSTRAINT fk_chirps
+        FOREIGN KEY (chirp_id)
+        REFERENCES chirps(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_tags
+        FOREIGN KEY (tag_id)
+        REFERENCES tags(id)
+        ON DELETE CASCADE
+);
+
+-- browsing a tag and counting recent uses both go by tag, then time
+CREATE INDEX chirp_tags_tag_id_created_at_idx ON chirp_tags (tag_id, created_at);
+CREATE INDEX chirp_tags_created_at_idx ON chirp_tags (created_at);
+
+-- +goose Down
+DROP TABLE chirp_tags;
+DROP TABLE tags;
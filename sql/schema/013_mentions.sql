@@ -0,0 +1,31 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN username TEXT;
+
+-- usernames are unique regardless of case, @Cheems and @cheems are one user
+CREATE UNIQUE INDEX users_username_key ON users (LOWER(username));
+
+CREATE TABLE mentions(
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    start_index INTEGER NOT NULL,
+    end_index INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, start_index),
+    CONSTRAINT fk_chirps
+        FOREIGN KEY (chirp_id)
+        REFERENCES chirps(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_users
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX mentions_user_id_idx ON mentions (user_id);
+
+-- +goose Down
+DROP TABLE mentions;
+DROP INDEX users_username_key;
+ALTER TABLE users
+DROP COLUMN username;
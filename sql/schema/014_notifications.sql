@@ -0,0 +1,59 @@
+-- +goose Up
+CREATE TABLE notifications(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('reply', 'like', 'follow', 'mention')),
+    chirp_id UUID,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    read_at TIMESTAMP,
+    CONSTRAINT fk_users
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_chirps
+        FOREIGN KEY (chirp_id)
+        REFERENCES chirps(id)
+        ON DELETE CASCADE
+);
+
+-- every new like of a chirp lands in the same unread notification until the
+-- user reads it, follows (no chirp) are grouped the same way
+CREATE UNIQUE INDEX notifications_unread_key ON notifications (user_id, type, chirp_id)
+    NULLS NOT DISTINCT
+    WHERE read_at IS NULL;
+
+CREATE INDEX notifications_user_id_idx ON notifications (user_id, updated_at DESC, id DESC);
+
+CREATE TABLE notification_actors(
+    notification_id UUID NOT NULL,
+    actor_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (notification_id, actor_id),
+    CONSTRAINT fk_notifications
+        FOREIGN KEY (notification_id)
+        REFERENCES notifications(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_users
+        FOREIGN KEY (actor_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+-- a missing row means the type is enabled
+CREATE TABLE notification_preferences(
+    user_id UUID NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('reply', 'like', 'follow', 'mention')),
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, type),
+    CONSTRAINT fk_users
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE notification_preferences;
+DROP TABLE notification_actors;
+DROP TABLE notifications;
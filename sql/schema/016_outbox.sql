@@ -0,0 +1,19 @@
+-- +goose Up
+-- domain events, written in the same transaction as the change they describe
+-- and delivered to subscribers from here by the outbox relay
+CREATE TABLE outbox_events(
+    id UUID PRIMARY KEY,
+    type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    delivered_at TIMESTAMP,
+    last_error TEXT
+);
+
+CREATE INDEX outbox_events_pending_idx ON outbox_events (next_attempt_at)
+    WHERE delivered_at IS NULL;
+
+-- +goose Down
+DROP TABLE outbox_events;
@@ -0,0 +1,52 @@
+-- +goose Up
+CREATE TABLE webhook_endpoints(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    -- failed attempts in a row; reaching the limit disables the endpoint
+    failure_count INTEGER NOT NULL DEFAULT 0,
+    disabled_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_users
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_endpoints_user_id_idx ON webhook_endpoints (user_id, created_at);
+
+CREATE TABLE webhook_deliveries(
+    id UUID PRIMARY KEY,
+    endpoint_id UUID NOT NULL,
+    event_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    -- the exact body sent, so a redelivery is identical
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_attempt_at TIMESTAMP,
+    response_status INTEGER,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    -- the outbox may hand over an event more than once, it is still sent to
+    -- each endpoint once
+    UNIQUE (endpoint_id, event_id),
+    CONSTRAINT fk_webhook_endpoints
+        FOREIGN KEY (endpoint_id)
+        REFERENCES webhook_endpoints(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at)
+    WHERE status = 'pending';
+
+CREATE INDEX webhook_deliveries_endpoint_id_idx ON webhook_deliveries (endpoint_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP TABLE webhook_deliveries;
+DROP TABLE webhook_endpoints;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- every Polka webhook handled, so a retried delivery is only applied once
+CREATE TABLE webhook_events(
+    id TEXT PRIMARY KEY,
+    source TEXT NOT NULL,
+    type TEXT NOT NULL,
+    received_at TIMESTAMP NOT NULL
+);
+
+-- the history of users' Chirpy Red status; users.is_chirpy_red is the
+-- status of the change with the latest effective_at
+CREATE TABLE red_status_changes(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    is_chirpy_red BOOLEAN NOT NULL,
+    reason TEXT NOT NULL,
+    webhook_event_id TEXT REFERENCES webhook_events(id) ON DELETE SET NULL,
+    effective_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX red_status_changes_user_idx ON red_status_changes (user_id, effective_at DESC, created_at DESC);
+
+-- +goose Down
+DROP TABLE red_status_changes;
+DROP TABLE webhook_events;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- when a user's Chirpy Red runs out by itself, NULL while it's open ended
+ALTER TABLE users ADD COLUMN red_expires_at TIMESTAMP;
+ALTER TABLE red_status_changes ADD COLUMN expires_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE red_status_changes DROP COLUMN expires_at;
+ALTER TABLE users DROP COLUMN red_expires_at;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- the bodies a chirp had before each edit; created_at is when that body was
+-- written, replaced_at when the edit replaced it
+CREATE TABLE chirp_revisions(
+    id UUID PRIMARY KEY,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    replaced_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX chirp_revisions_chirp_idx ON chirp_revisions (chirp_id, replaced_at DESC);
+
+-- +goose Down
+DROP TABLE chirp_revisions;
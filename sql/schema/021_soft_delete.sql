@@ -0,0 +1,31 @@
+-- +goose Up
+-- deleted chirps and users stay in the trash until the purge job removes
+-- them for good; every read query leaves out rows with a deleted_at
+ALTER TABLE chirps
+ADD COLUMN deleted_at TIMESTAMP;
+
+ALTER TABLE users
+ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX chirps_trash_idx ON chirps (user_id, deleted_at DESC, id DESC)
+WHERE deleted_at IS NOT NULL;
+CREATE INDEX users_deleted_at_idx ON users (deleted_at)
+WHERE deleted_at IS NOT NULL;
+
+-- a deleted rechirp doesn't stop the user rechirping the same chirp again
+DROP INDEX chirps_user_id_rechirp_of_id_key;
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_id_key ON chirps (user_id, rechirp_of_id)
+WHERE rechirp_of_id IS NOT NULL AND deleted_at IS NULL;
+
+-- +goose Down
+DELETE FROM chirps WHERE deleted_at IS NOT NULL;
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+DROP INDEX chirps_user_id_rechirp_of_id_key;
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_id_key ON chirps (user_id, rechirp_of_id)
+WHERE rechirp_of_id IS NOT NULL;
+DROP INDEX users_deleted_at_idx;
+DROP INDEX chirps_trash_idx;
+ALTER TABLE users
+DROP COLUMN deleted_at;
+ALTER TABLE chirps
+DROP COLUMN deleted_at;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- chirps being written, and chirps waiting for their publish_at. parent_id
+-- and quote_of_id aren't foreign keys: a chirp deleted in the meantime makes
+-- publishing fail, the draft isn't quietly changed.
+CREATE TABLE drafts(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    body TEXT NOT NULL,
+    parent_id UUID,
+    quote_of_id UUID,
+    publish_at TIMESTAMP,
+    -- why the scheduler couldn't publish the draft, which unscheduled it
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_users
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX drafts_user_id_idx ON drafts (user_id, created_at DESC, id DESC);
+
+CREATE INDEX drafts_publish_at_idx ON drafts (publish_at, id)
+    WHERE publish_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE drafts;
@@ -0,0 +1,33 @@
+-- +goose Up
+-- a poll is part of the chirp it was posted with, its options numbered from
+-- 0 in the order they were given
+CREATE TABLE polls(
+    chirp_id UUID PRIMARY KEY,
+    options TEXT[] NOT NULL,
+    closes_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_chirps
+        FOREIGN KEY (chirp_id)
+        REFERENCES chirps(id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE poll_votes(
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    option_index INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, user_id),
+    CONSTRAINT fk_polls
+        FOREIGN KEY (chirp_id)
+        REFERENCES polls(chirp_id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_users
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE poll_votes;
+DROP TABLE polls;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- uploaded images, kept in the blob store under keys made from id and
+-- content_type. chirp_id is set when a chirp attaches them, position is
+-- their place in it.
+CREATE TABLE media(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    chirp_id UUID,
+    position INTEGER NOT NULL DEFAULT 0,
+    content_type TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_users
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_chirps
+        FOREIGN KEY (chirp_id)
+        REFERENCES chirps(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX media_chirp_id_idx ON media (chirp_id, position)
+    WHERE chirp_id IS NOT NULL;
+
+-- +goose Down
+DROP TABLE media;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- failed attempts at publishing a scheduled draft for reasons other than the
+-- draft itself, such as the database being down. The scheduler leaves the
+-- draft alone until next_attempt_at.
+ALTER TABLE drafts
+ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN next_attempt_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE drafts
+DROP COLUMN next_attempt_at,
+DROP COLUMN attempts;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- uploads no chirp has attached yet, counted per user against their quota
+-- and deleted once they've waited too long; and every upload of a user, for
+-- when the user is purged.
+CREATE INDEX media_pending_idx ON media (user_id, created_at)
+    WHERE chirp_id IS NULL;
+
+CREATE INDEX media_user_id_idx ON media (user_id);
+
+-- +goose Down
+DROP INDEX media_user_id_idx;
+DROP INDEX media_pending_idx;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- endpoints registered by an admin belong to no user, and hear about every
+-- user's events
+ALTER TABLE webhook_endpoints
+ALTER COLUMN user_id DROP NOT NULL;
+
+CREATE INDEX webhook_endpoints_admin_idx ON webhook_endpoints (created_at)
+    WHERE user_id IS NULL;
+
+-- +goose Down
+DROP INDEX webhook_endpoints_admin_idx;
+DELETE FROM webhook_endpoints WHERE user_id IS NULL;
+ALTER TABLE webhook_endpoints
+ALTER COLUMN user_id SET NOT NULL;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- the relay compares these with NOW() and with times the server sends in
+-- UTC; without a time zone those only agree when the session's is UTC too.
+-- Everything stored so far is taken to be in UTC
+ALTER TABLE outbox_events
+ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN next_attempt_at TYPE TIMESTAMPTZ USING next_attempt_at AT TIME ZONE 'UTC',
+ALTER COLUMN delivered_at TYPE TIMESTAMPTZ USING delivered_at AT TIME ZONE 'UTC';
+
+-- +goose Down
+ALTER TABLE outbox_events
+ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN next_attempt_at TYPE TIMESTAMP USING next_attempt_at AT TIME ZONE 'UTC',
+ALTER COLUMN delivered_at TYPE TIMESTAMP USING delivered_at AT TIME ZONE 'UTC';
@@ -0,0 +1,24 @@
+-- +goose Up
+-- like the outbox's, these are compared with NOW() and with times the server
+-- sends in UTC, so they carry their time zone. Everything stored so far is
+-- taken to be in UTC
+ALTER TABLE webhook_endpoints
+ALTER COLUMN disabled_at TYPE TIMESTAMPTZ USING disabled_at AT TIME ZONE 'UTC',
+ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC';
+
+ALTER TABLE webhook_deliveries
+ALTER COLUMN next_attempt_at TYPE TIMESTAMPTZ USING next_attempt_at AT TIME ZONE 'UTC',
+ALTER COLUMN last_attempt_at TYPE TIMESTAMPTZ USING last_attempt_at AT TIME ZONE 'UTC',
+ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC';
+
+-- +goose Down
+ALTER TABLE webhook_deliveries
+ALTER COLUMN next_attempt_at TYPE TIMESTAMP USING next_attempt_at AT TIME ZONE 'UTC',
+ALTER COLUMN last_attempt_at TYPE TIMESTAMP USING last_attempt_at AT TIME ZONE 'UTC',
+ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC';
+
+ALTER TABLE webhook_endpoints
+ALTER COLUMN disabled_at TYPE TIMESTAMP USING disabled_at AT TIME ZONE 'UTC',
+ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
@@ -0,0 +1,16 @@
+-- +goose Up
+-- the scheduler compares these with times the server sends in UTC, and they
+-- are set with NOW(), so they carry their time zone. Everything stored so
+-- far is taken to be in UTC
+ALTER TABLE drafts
+ALTER COLUMN publish_at TYPE TIMESTAMPTZ USING publish_at AT TIME ZONE 'UTC',
+ALTER COLUMN next_attempt_at TYPE TIMESTAMPTZ USING next_attempt_at AT TIME ZONE 'UTC',
+ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC';
+
+-- +goose Down
+ALTER TABLE drafts
+ALTER COLUMN publish_at TYPE TIMESTAMP USING publish_at AT TIME ZONE 'UTC',
+ALTER COLUMN next_attempt_at TYPE TIMESTAMP USING next_attempt_at AT TIME ZONE 'UTC',
+ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE 'UTC';
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/broker"
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/entities"
+	"github.com/Cheemx/chirpy/internal/eventbus"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/google/uuid"
+)
+
+const (
+	// chirpStreamReplay is how many recent chirp events a reconnecting
+	// client can catch up on with Last-Event-ID.
+	chirpStreamReplay = 1000
+	// chirpStreamBuffer is how many events a stream client may fall behind
+	// by before it is disconnected.
+	chirpStreamBuffer = 64
+	// sseHeartbeat is how often an idle stream gets a comment line, so
+	// proxies don't time it out.
+	sseHeartbeat = 15 * time.Second
+)
+
+// Event types sent on GET /api/stream/chirps.
+const (
+	eventChirpCreated  = eventbus.ChirpCreated
+	eventChirpUpdated  = eventbus.ChirpUpdated
+	eventChirpDeleted  = eventbus.ChirpDeleted
+	eventChirpRestored = eventbus.ChirpRestored
+	eventReset         = "reset"
+)
+
+// chirpEvent is a change to a chirp, as relayed to cfg.chirpEvents. ID is
+// the event's ID on every instance. AuthorID, Tags and RootID are what
+// stream filters match on, Data is the JSON sent to clients.
+type chirpEvent struct {
+	ID       string
+	Type     string
+	AuthorID uuid.UUID
+	Tags     []string
+	// RootID is the chirp at the top of the chirp's thread, which is the
+	// chirp itself if it isn't a reply.
+	RootID uuid.UUID
+	Data   json.RawMessage
+}
+
+func newChirpBroker() *broker.Broker[chirpEvent] {
+	return broker.New[chirpEvent](chirpStreamReplay, chirpStreamBuffer)
+}
+
+// chirpNotice is what a chirp event carries on the event bus: only which
+// chirp changed, so it fits in a notification however big the chirp is.
+// Every instance loads the chirp itself.
+type chirpNotice struct {
+	// EventID is made from when the event was published and the chirp's
+	// ID, so it's the same on every instance and unique to the event.
+	EventID  string    `json:"event_id"`
+	ChirpID  uuid.UUID `json:"chirp_id"`
+	AuthorID uuid.UUID `json:"author_id"`
+}
+
+// publishChirp sends a chirp event to every stream on every instance. The
+// change has already been made, so a lost event is logged rather than
+// failing the request.
+func (cfg *apiConfig) publishChirp(ctx context.Context, eventType string, chirp database.Chirp) {
+	data, err := json.Marshal(chirpNotice{
+		EventID:  fmt.Sprintf("%d-%s", time.Now().UnixMicro(), chirp.ID),
+		ChirpID:  chirp.ID,
+		AuthorID: chirp.UserID,
+	})
+	if err != nil {
+		log.Printf("Error marshalling %s event: %v", eventType, err)
+		return
+	}
+	err = cfg.events.Publish(ctx, eventbus.Event{Type: eventType, Data: data})
+	if err != nil {
+		log.Printf("Error publishing %s event for Chirp %s: %v", eventType, chirp.ID, err)
+	}
+}
+
+// relayEvents hands chirp events from the bus, whichever instance they
+// were published on, to this instance's streams.
+func (cfg *apiConfig) relayEvents() {
+	cfg.events.Subscribe(func(e eventbus.Event) {
+		switch e.Type {
+		case eventbus.ChirpCreated, eventbus.ChirpUpdated, eventbus.ChirpDeleted, eventbus.ChirpRestored:
+		default:
+			return
+		}
+		var notice chirpNotice
+		if err := json.Unmarshal(e.Data, &notice); err != nil {
+			log.Printf("Error decoding %s event: %v", e.Type, err)
+			return
+		}
+		event, err := cfg.loadChirpEvent(context.Background(), e.Type, notice)
+		if errors.Is(err, sql.ErrNoRows) {
+			// deleted before it could be shown, which has an event of its own
+			return
+		}
+		if err != nil {
+			log.Printf("Error loading %s event for Chirp %s: %v", e.Type, notice.ChirpID, err)
+			return
+		}
+		cfg.chirpEvents.Publish(event)
+	})
+}
+
+// loadChirpEvent makes the event streams get from a notice. The chirp is
+// presented once, not per client, so it's presented to nobody in particular:
+// without liked_by_me or my_vote, and without the tallies of an open poll.
+// Deleted chirps are only identified, though their tags and thread still
+// come from the trash when it has them.
+func (cfg *apiConfig) loadChirpEvent(ctx context.Context, eventType string, notice chirpNotice) (chirpEvent, error) {
+	var chirp database.Chirp
+	var payload any
+	var err error
+	if eventType == eventChirpDeleted {
+		payload = struct {
+			ID     uuid.UUID `json:"id"`
+			UserID uuid.UUID `json:"user_id"`
+		}{
+			ID:     notice.ChirpID,
+			UserID: notice.AuthorID,
+		}
+		chirp, err = cfg.db.GetTrashedChirp(ctx, database.GetTrashedChirpParams{ID: notice.ChirpID, UserID: notice.AuthorID})
+		if errors.Is(err, sql.ErrNoRows) {
+			chirp = database.Chirp{ID: notice.ChirpID, UserID: notice.AuthorID}
+		} else if err != nil {
+			return chirpEvent{}, err
+		}
+	} else {
+		chirp, err = cfg.db.GetChirpByID(ctx, notice.ChirpID)
+		if err != nil {
+			return chirpEvent{}, err
+		}
+		payload, err = cfg.presentChirp(ctx, uuid.Nil, chirp)
+		if err != nil {
+			return chirpEvent{}, err
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return chirpEvent{}, err
+	}
+	rootID := chirp.ID
+	if chirp.RootID.Valid {
+		rootID = chirp.RootID.UUID
+	}
+	return chirpEvent{
+		ID:       notice.EventID,
+		Type:     eventType,
+		AuthorID: chirp.UserID,
+		Tags:     entities.UniqueTags(entities.Hashtags(chirp.Body)),
+		RootID:   rootID,
+		Data:     data,
+	}, nil
+}
+
+// writeSSE writes one server-sent event. JSON data never contains newlines,
+// so it always fits on a single data line.
+func writeSSE(w io.Writer, id string, eventType string, data []byte) error {
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, eventType, data)
+	return err
+}
+
+func (cfg *apiConfig) handleStreamChirps(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// only chirps by one author and/or using one tag
+	var authorID uuid.UUID
+	if s := query.Get("author_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, "author_id is not a valid ID"))
+			return
+		}
+		authorID = id
+	}
+	var tag string
+	if s := query.Get("tag"); s != "" {
+		name, ok := entities.NormalizeTag(s)
+		if !ok {
+			httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, "tag is not a valid hashtag"))
+			return
+		}
+		tag = name
+	}
+	filter := func(e chirpEvent) bool {
+		return (authorID == uuid.Nil || e.AuthorID == authorID) && (tag == "" || slices.Contains(e.Tags, tag))
+	}
+
+	// browsers send Last-Event-ID on their own when they reconnect. Event
+	// IDs are the same on every instance, so any instance that still has
+	// that event can resume after it; one that doesn't resets the client
+	var lastID uint64
+	found := true
+	if eventID := r.Header.Get("Last-Event-ID"); eventID != "" {
+		lastID, found = cfg.chirpEvents.Find(func(e chirpEvent) bool { return e.ID == eventID })
+	}
+
+	sub, missed, complete := cfg.chirpEvents.Subscribe(lastID, filter)
+	defer sub.Close()
+	complete = complete && found
+	// the ID to resume from is that of the last event before the live ones,
+	// none if there wasn't one
+	var startID string
+	if last, ok := sub.Last(); ok {
+		startID = last.Value.ID
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(200)
+
+	// catch up, or tell the client to reload if too much happened while it
+	// was away, then mark where the live events start so a reconnect before
+	// the first one still resumes from here
+	var err error
+	if complete {
+		for _, msg := range missed {
+			if err = writeSSE(w, msg.Value.ID, msg.Value.Type, msg.Value.Data); err != nil {
+				return
+			}
+		}
+		_, err = fmt.Fprintf(w, "id: %s\n\n", startID)
+	} else {
+		err = writeSSE(w, startID, eventReset, []byte("{}"))
+	}
+	if err != nil || rc.Flush() != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			_, err = io.WriteString(w, ": heartbeat\n\n")
+		case msg, ok := <-sub.Messages():
+			if !ok {
+				// dropped for falling behind, the client reconnects and
+				// catches up from the replay buffer
+				return
+			}
+			err = writeSSE(w, msg.Value.ID, msg.Value.Type, msg.Value.Data)
+		}
+		if err != nil || rc.Flush() != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/eventbus"
+)
+
+// connectStream opens GET /api/stream/chirps with query, resuming after
+// lastEventID unless it's empty.
+func connectStream(t *testing.T, srv *httptest.Server, query, lastEventID string) (*bufio.Reader, func()) {
+	t.Helper()
+	req, err := http.NewRequest("GET", srv.URL+"/api/stream/chirps"+query, nil)
+	if err != nil {
+		t.Fatalf("NewRequest error: %v", err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("GET stream error: %v", err)
+	}
+	if res.StatusCode != 200 || res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("GET stream: got %d %s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+	return bufio.NewReader(res.Body), func() { res.Body.Close() }
+}
+
+// nextEvent reads one event as its id and "event data", skipping comments.
+func nextEvent(t *testing.T, r *bufio.Reader) (id, event string) {
+	t.Helper()
+	fields := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" && len(fields) > 0 {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && name != "" {
+			fields[name] = strings.TrimPrefix(value, " ")
+		}
+	}
+	return fields["id"], strings.TrimSpace(fields["event"] + " " + fields["data"])
+}
+
+func TestChirpStream(t *testing.T) {
+	srv := newTestServer(t)
+	user := createTestUser(t, srv, "cheems@example.com")
+
+	connect := func(query, lastEventID string) (*bufio.Reader, func()) {
+		t.Helper()
+		return connectStream(t, srv, query, lastEventID)
+	}
+	next := func(r *bufio.Reader) string {
+		t.Helper()
+		_, event := nextEvent(t, r)
+		return event
+	}
+	post := func(body string) chirpResponse {
+		t.Helper()
+		return postChirp(t, srv, user.Token, map[string]string{"body": body}, 201)
+	}
+
+	// nothing happened yet, so there's nothing to resume from
+	stream, stop := connect("?tag=Go", "")
+	if id, got := nextEvent(t, stream); id != "" || got != "" {
+		t.Fatalf("first event = %q %q, want an empty starting id", id, got)
+	}
+	first := post("#go hi")
+	post("#rust skipped")
+	second := post("#go again")
+
+	firstID, got := nextEvent(t, stream)
+	if !strings.HasPrefix(got, "chirp.created ") || !strings.Contains(got, first.ID.String()) || !strings.HasSuffix(firstID, first.ID.String()) {
+		t.Fatalf("event %s = %q, want the first chirp created", firstID, got)
+	}
+	stop()
+
+	doJSON(t, srv, "DELETE", "/api/chirps/"+first.ID.String(), user.Token, nil)
+
+	// reconnecting replays what the filter would have sent in the meantime
+	stream, stop = connect("?tag=go", firstID)
+	defer stop()
+	if _, got := nextEvent(t, stream); !strings.HasPrefix(got, "chirp.created ") || !strings.Contains(got, second.ID.String()) {
+		t.Fatalf("replayed event = %q, want the second chirp created", got)
+	}
+	want := fmt.Sprintf(`chirp.deleted {"id":"%s","user_id":"%s"}`, first.ID, user.ID)
+	deletedID, got := nextEvent(t, stream)
+	if got != want {
+		t.Fatalf("replayed event = %q, want %q", got, want)
+	}
+	if id, got := nextEvent(t, stream); id != deletedID || got != "" {
+		t.Fatalf("after replay = %q %q, want the resume id %s", id, got, deletedID)
+	}
+
+	// an ID the server never handed out can't be resumed
+	stream, stop = connect("", "99")
+	defer stop()
+	if id, got := nextEvent(t, stream); id != deletedID || got != "reset {}" {
+		t.Fatalf("unknown Last-Event-ID = %q %q, want a reset at %s", id, got, deletedID)
+	}
+
+	if res := doJSON(t, srv, "GET", "/api/stream/chirps?author_id=nope", "", nil); res.StatusCode != 400 {
+		t.Fatalf("bad author_id: got %d want 400", res.StatusCode)
+	}
+
+	// everyone gets the same event, which doesn't carry what the author
+	// sees of their own chirp
+	stream, stop = connect("?author_id="+user.ID.String(), "")
+	defer stop()
+	next(stream)
+	polled := postChirp(t, srv, user.Token, map[string]any{
+		"body": "tabs or spaces?",
+		"poll": map[string]any{"options": []string{"tabs", "spaces"}, "closes_at": time.Now().Add(time.Hour)},
+	}, 201)
+	path := "/api/chirps/" + polled.ID.String()
+	doJSON(t, srv, "PUT", path+"/like", user.Token, nil).Body.Close()
+	doJSON(t, srv, "POST", path+"/poll/votes", user.Token, map[string]any{"option": 0}).Body.Close()
+	doJSON(t, srv, "DELETE", path, user.Token, nil).Body.Close()
+	var restored chirpResponse
+	decodeBody(t, doJSON(t, srv, "POST", path+"/restore", user.Token, nil), &restored)
+	if !restored.LikedByMe || restored.Poll.MyVote == nil {
+		t.Fatalf("restored chirp = %+v, want the author's like and vote", restored)
+	}
+	for range 2 {
+		next(stream)
+	}
+	_, data, _ := strings.Cut(next(stream), "chirp.restored ")
+	var event chirpResponse
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		t.Fatalf("restored event data %q: %v", data, err)
+	}
+	if event.ID != polled.ID || event.LikedByMe || event.LikeCount != 1 || event.Poll.MyVote != nil || event.Poll.TotalVotes != nil {
+		t.Fatalf("restored event = %+v, poll %+v; want nobody's like, vote or tallies", event, event.Poll)
+	}
+
+	// a link only counts as 23 characters, so a chirp can be far bigger than
+	// a Postgres notification; the bus only carries its ID
+	long := post("#go https://example.com/" + strings.Repeat("a", 7900))
+	got = next(stream)
+	if !strings.HasPrefix(got, "chirp.created ") || !strings.Contains(got, long.ID.String()) || len(got) < 7900 {
+		t.Fatalf("event for a long chirp = %.200q, want it created", got)
+	}
+}
+
+func TestChirpStreamAcrossInstances(t *testing.T) {
+	// two instances of the server sharing a database and an event bus, the
+	// second started after the first had already seen an event
+	store, bus := database.NewMemory(), eventbus.NewLocal()
+	one := newTestInstance(t, store, bus)
+	user := createTestUser(t, one, "cheems@example.com")
+	post := func(body string) chirpResponse {
+		t.Helper()
+		return postChirp(t, one, user.Token, map[string]string{"body": body}, 201)
+	}
+	post("before the other instance")
+	other := newTestInstance(t, store, bus)
+
+	stream, stop := connectStream(t, one, "", "")
+	nextEvent(t, stream)
+	post("first")
+	lastID, _ := nextEvent(t, stream)
+	stop()
+	second := post("second")
+
+	// the client's last event ID means the same to the other instance
+	stream, stop = connectStream(t, other, "", lastID)
+	defer stop()
+	if _, got := nextEvent(t, stream); !strings.HasPrefix(got, "chirp.created ") || !strings.Contains(got, second.ID.String()) {
+		t.Fatalf("event resumed on another instance = %q, want the second chirp", got)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/entities"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/pagination"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+	defaultTrendingLimit  = 10
+	maxTrendingLimit      = 50
+)
+
+// tagCount is one entry in the trending list.
+type tagCount struct {
+	Tag        string `json:"tag"`
+	ChirpCount int64  `json:"chirp_count"`
+}
+
+// tagChirp indexes the hashtags in a chirp. Tags it already had are left as
+// they were.
+func (cfg *apiConfig) tagChirp(ctx context.Context, chirp database.Chirp) error {
+	for _, name := range entities.UniqueTags(entities.Hashtags(chirp.Body)) {
+		tag, err := cfg.db.UpsertTag(ctx, name)
+		if err != nil {
+			return err
+		}
+		err = cfg.db.TagChirp(ctx, database.TagChirpParams{ChirpID: chirp.ID, TagID: tag.ID})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handleGetTagChirps(w http.ResponseWriter, r *http.Request) {
+	// "#Go", "go" and "GO" are all the same tag
+	tag, ok := entities.NormalizeTag(r.PathValue("tag"))
+	if !ok {
+		httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, "tag is not a valid hashtag"))
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// newest first
+	params := database.ListTagChirpsParams{Tag: tag, PageSize: page.FetchSize()}
+	params.AfterCreatedAt, params.AfterID = page.After()
+	chirps, err := cfg.db.ListTagChirps(r.Context(), params)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Creating response Body
+	res := struct {
+		Chirps     []chirpResponse `json:"chirps"`
+		NextCursor string          `json:"next_cursor,omitempty"`
+	}{}
+	chirps, res.NextCursor = pagination.Trim(page, chirps, chirpCursor)
+	if res.NextCursor != "" {
+		w.Header().Set("Link", pagination.NextLink(r.URL, res.NextCursor))
+	}
+	res.Chirps, err = cfg.presentChirps(r.Context(), cfg.viewerID(r), chirps)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
+
+func (cfg *apiConfig) handleGetTrendingTags(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// how far back to count, e.g. window=6h
+	window := defaultTrendingWindow
+	if s := query.Get("window"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 || d > maxTrendingWindow {
+			httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, "window must be a duration up to "+maxTrendingWindow.String()))
+			return
+		}
+		window = d
+	}
+
+	limit := defaultTrendingLimit
+	if s := query.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxTrendingLimit {
+			httpapi.RespondWithError(w, httpapi.BadRequest(httpapi.CodeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(maxTrendingLimit)))
+			return
+		}
+		limit = n
+	}
+
+	since := time.Now().UTC().Add(-window)
+	rows, err := cfg.db.ListTrendingTags(r.Context(), database.ListTrendingTagsParams{
+		Since:   since,
+		MaxTags: int32(limit),
+	})
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Creating response Body
+	res := struct {
+		Tags  []tagCount `json:"tags"`
+		Since time.Time  `json:"since"`
+	}{
+		Tags:  []tagCount{},
+		Since: since,
+	}
+	for _, row := range rows {
+		res.Tags = append(res.Tags, tagCount{Tag: row.Name, ChirpCount: row.ChirpCount})
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	srv := newTestServer(t)
+	user := createTestUser(t, srv, "cheems@example.com")
+
+	post := func(body string) chirpResponse {
+		t.Helper()
+		return postChirp(t, srv, user.Token, map[string]string{"body": body}, 201)
+	}
+
+	first := post("learning #Go today #golang")
+	post("more #go, #GO and #go")
+	post("#rust is fun")
+
+	page, _ := getChirpsPage(t, srv, "/api/tags/gO/chirps")
+	if len(page.Chirps) != 2 || page.Chirps[1].ID != first.ID {
+		t.Fatalf("tag page = %+v, want 2 chirps newest first", page.Chirps)
+	}
+
+	res := doJSON(t, srv, "GET", "/api/tags/trending?window=1h", "", nil)
+	var trending struct {
+		Tags []tagCount `json:"tags"`
+	}
+	decodeBody(t, res, &trending)
+	want := []tagCount{{"go", 2}, {"golang", 1}, {"rust", 1}}
+	if fmt.Sprint(trending.Tags) != fmt.Sprint(want) {
+		t.Fatalf("trending = %v, want %v", trending.Tags, want)
+	}
+
+	if res := doJSON(t, srv, "GET", "/api/tags/123/chirps", "", nil); res.StatusCode != 400 {
+		t.Fatalf("numeric tag: got %d want 400", res.StatusCode)
+	}
+	if res := doJSON(t, srv, "GET", "/api/tags/trending?window=30d", "", nil); res.StatusCode != 400 {
+		t.Fatalf("bad window: got %d want 400", res.StatusCode)
+	}
+
+	// deleted chirps drop out of their tags
+	if res := doJSON(t, srv, "DELETE", "/api/chirps/"+first.ID.String(), user.Token, nil); res.StatusCode != 204 {
+		t.Fatalf("delete chirp: got %d want 204", res.StatusCode)
+	}
+	page, _ = getChirpsPage(t, srv, "/api/tags/golang/chirps")
+	if len(page.Chirps) != 0 {
+		t.Fatalf("deleted chirp still tagged: %+v", page.Chirps)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/outbox"
+	"github.com/Cheemx/chirpy/internal/pagination"
+)
+
+// defaultTrashRetention is how long deleted chirps and accounts can be
+// restored when TRASH_RETENTION isn't set.
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// trashResponse is a chirp in its author's trash. PurgeAt is when the purge
+// job removes it for good.
+type trashResponse struct {
+	chirpResponse
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// trashRetention reads how long deleted chirps and accounts are kept from
+// TRASH_RETENTION, a duration such as "720h".
+func trashRetention() (time.Duration, error) {
+	s := os.Getenv("TRASH_RETENTION")
+	if s == "" {
+		return defaultTrashRetention, nil
+	}
+	retention, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("TRASH_RETENTION: %w", err)
+	}
+	if retention <= 0 {
+		return 0, fmt.Errorf("TRASH_RETENTION: %s is not positive", s)
+	}
+	return retention, nil
+}
+
+// trashCursor is the keyset position of a trashed chirp in deleted_at, id
+// order.
+func trashCursor(chirp database.Chirp) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: *chirp.DeletedAt, ID: chirp.ID}
+}
+
+func (cfg *apiConfig) handleGetTrash(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// most recently deleted first, leaving out whatever is due for purging
+	params := database.ListTrashParams{
+		UserID:   userID,
+		Since:    time.Now().UTC().Add(-cfg.trashRetention),
+		PageSize: page.FetchSize(),
+	}
+	params.AfterDeletedAt, params.AfterID = page.After()
+	chirps, err := cfg.db.ListTrash(r.Context(), params)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Creating response Body
+	res := struct {
+		Chirps     []trashResponse `json:"chirps"`
+		NextCursor string          `json:"next_cursor,omitempty"`
+	}{}
+	chirps, res.NextCursor = pagination.Trim(page, chirps, trashCursor)
+	if res.NextCursor != "" {
+		w.Header().Set("Link", pagination.NextLink(r.URL, res.NextCursor))
+	}
+	presented, err := cfg.presentChirps(r.Context(), userID, chirps)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	res.Chirps = make([]trashResponse, len(presented))
+	for i, chirp := range presented {
+		res.Chirps[i] = trashResponse{
+			chirpResponse: chirp,
+			PurgeAt:       chirp.DeletedAt.Add(cfg.trashRetention),
+		}
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
+
+func (cfg *apiConfig) handleRestoreChirp(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// parse the chirpID to uuid format
+	id, err := pathUUID(r, "chirpID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// only the author's own trash, and only until it's due for purging
+	chirp, err := cfg.db.GetTrashedChirp(r.Context(), database.GetTrashedChirpParams{
+		ID:     id,
+		UserID: userID,
+		Since:  time.Now().UTC().Add(-cfg.trashRetention),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpapi.RespondWithError(w, httpapi.NotFound("Chirp isn't in your trash"))
+			return
+		}
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Restoring the Chirp along with the rechirps deleted with it, and the
+	// event announcing it
+	deletedAt := *chirp.DeletedAt
+	chirp.DeletedAt = nil
+	err = cfg.db.InTx(r.Context(), func(tx database.Store) error {
+		err := tx.RestoreChirp(r.Context(), database.RestoreChirpParams{
+			ID:        chirp.ID,
+			DeletedAt: deletedAt,
+		})
+		if err != nil {
+			if isUniqueViolation(err) {
+				return httpapi.Conflict("You already rechirped this chirp")
+			}
+			return err
+		}
+		return outbox.Record(r.Context(), tx, outbox.ChirpRestored, chirp)
+	})
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	cfg.outbox.Wake()
+
+	res, err := cfg.presentChirp(r.Context(), userID, chirp)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	cfg.publishChirp(r.Context(), eventChirpRestored, chirp)
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
+
+func (cfg *apiConfig) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	// get the user from the access token
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// The account and every Chirp of it go to the trash together, rechirps
+	// of those Chirps with them, so logging in again restores them all. Its
+	// refresh tokens are revoked, so only that login can bring it back
+	err = cfg.db.InTx(r.Context(), func(tx database.Store) error {
+		user, err := tx.DeleteUser(r.Context(), userID)
+		if err != nil {
+			return lookupError(err, "User")
+		}
+		err = tx.RevokeUserRefreshTokens(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+		return tx.DeleteUserChirps(r.Context(), database.DeleteUserChirpsParams{
+			UserID:    userID,
+			DeletedAt: user.DeletedAt.Time,
+		})
+	})
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// account deleted successfully
+	w.WriteHeader(204)
+}
+
+// restoreUser brings back an account deleted within the retention window,
+// along with the chirps deleted with it, when its owner logs in again.
+func (cfg *apiConfig) restoreUser(r *http.Request, user database.User) (database.User, error) {
+	if time.Since(user.DeletedAt.Time) > cfg.trashRetention {
+		return database.User{}, httpapi.Unauthorized(errors.New("incorrect email or password"))
+	}
+	restored := user
+	err := cfg.db.InTx(r.Context(), func(tx database.Store) error {
+		var err error
+		restored, err = tx.RestoreUser(r.Context(), user.ID)
+		if err != nil {
+			return err
+		}
+		return tx.RestoreUserChirps(r.Context(), database.RestoreUserChirpsParams{
+			UserID:    user.ID,
+			DeletedAt: user.DeletedAt.Time,
+		})
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+	return restored, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestTrash(t *testing.T) {
+	srv := newTestServer(t)
+	cheems := createTestUser(t, srv, "cheems@example.com")
+	doge := createTestUser(t, srv, "doge@example.com")
+
+	trash := func(user testUser) []trashResponse {
+		t.Helper()
+		res := doJSON(t, srv, "GET", "/api/trash", user.Token, nil)
+		if res.StatusCode != 200 {
+			t.Fatalf("trash: got %d want 200", res.StatusCode)
+		}
+		var page struct {
+			Chirps []trashResponse `json:"chirps"`
+		}
+		decodeBody(t, res, &page)
+		return page.Chirps
+	}
+	status := func(method, path, token string, want int) {
+		t.Helper()
+		res := doJSON(t, srv, method, path, token, nil)
+		res.Body.Close()
+		if res.StatusCode != want {
+			t.Fatalf("%s %s: got %d want %d", method, path, res.StatusCode, want)
+		}
+	}
+
+	chirp := postChirp(t, srv, cheems.Token, map[string]string{"body": "keep me around"}, 201)
+	postChirp(t, srv, cheems.Token, map[string]string{"body": "another one"}, 201)
+	rechirp := postChirp(t, srv, doge.Token, map[string]string{"rechirp_of": chirp.ID.String()}, 201)
+	path := "/api/chirps/" + chirp.ID.String()
+
+	// deleting takes the rechirp along, and only the author sees it in
+	// their trash
+	status("DELETE", path, cheems.Token, 204)
+	status("GET", path, "", 404)
+	if page, _ := getChirpsPage(t, srv, "/api/chirps"); len(page.Chirps) != 1 {
+		t.Fatalf("chirps after delete = %+v, want 1", page.Chirps)
+	}
+	if items := trash(doge); len(items) != 0 {
+		t.Fatalf("doge's trash = %+v, want empty", items)
+	}
+	items := trash(cheems)
+	if len(items) != 1 || items[0].ID != chirp.ID || items[0].DeletedAt == nil {
+		t.Fatalf("cheems's trash = %+v, want the deleted chirp", items)
+	}
+	if want := items[0].DeletedAt.Add(defaultTrashRetention); !items[0].PurgeAt.Equal(want) {
+		t.Errorf("purge_at = %v, want %v", items[0].PurgeAt, want)
+	}
+
+	status("POST", path+"/restore", doge.Token, 404)
+	res := doJSON(t, srv, "POST", path+"/restore", cheems.Token, nil)
+	if res.StatusCode != 200 {
+		t.Fatalf("restore: got %d want 200", res.StatusCode)
+	}
+	var restored chirpResponse
+	decodeBody(t, res, &restored)
+	if restored.DeletedAt != nil || restored.RechirpCount != 1 {
+		t.Fatalf("restored chirp = %+v", restored)
+	}
+	status("POST", path+"/restore", cheems.Token, 404)
+	if page, _ := getChirpsPage(t, srv, "/api/chirps"); len(page.Chirps) != 3 {
+		t.Fatalf("chirps after restore = %+v, want 3", page.Chirps)
+	}
+
+	// a rechirp can't come back once its author has rechirped again
+	rechirpPath := "/api/chirps/" + rechirp.ID.String()
+	status("DELETE", rechirpPath, doge.Token, 204)
+	postChirp(t, srv, doge.Token, map[string]string{"rechirp_of": chirp.ID.String()}, 201)
+	res = doJSON(t, srv, "POST", rechirpPath+"/restore", doge.Token, nil)
+	var conflict struct {
+		Error string `json:"error"`
+	}
+	decodeBody(t, res, &conflict)
+	if res.StatusCode != 409 || conflict.Error != "You already rechirped this chirp" {
+		t.Fatalf("restore a rechirp made again: got %d %q, want a 409", res.StatusCode, conflict.Error)
+	}
+
+	// a deleted account takes its chirps with it until its owner logs in
+	status("DELETE", "/api/users", cheems.Token, 204)
+	if page, _ := getChirpsPage(t, srv, "/api/chirps"); len(page.Chirps) != 0 {
+		t.Fatalf("chirps after deleting the account = %+v, want none", page.Chirps)
+	}
+	// nor do its tokens work any more
+	status("POST", "/api/refresh", cheems.RefreshToken, 401)
+	status("DELETE", "/api/users", cheems.Token, 401)
+	status("GET", "/api/trash", cheems.Token, 401)
+
+	res = doJSON(t, srv, "POST", "/api/login", "", map[string]string{"email": "cheems@example.com", "password": "password"})
+	if res.StatusCode != 200 {
+		t.Fatalf("login to deleted account: got %d want 200", res.StatusCode)
+	}
+	res.Body.Close()
+	if page, _ := getChirpsPage(t, srv, "/api/chirps"); len(page.Chirps) != 3 {
+		t.Fatalf("chirps after logging in = %+v, want 3", page.Chirps)
+	}
+	// the refresh token from before stays revoked
+	status("POST", "/api/refresh", cheems.RefreshToken, 401)
+
+	// past the retention window nothing comes back
+	t.Setenv("TRASH_RETENTION", "1ns")
+	srv = newTestServer(t)
+	cheems = createTestUser(t, srv, "cheems@example.com")
+	chirp = postChirp(t, srv, cheems.Token, map[string]string{"body": "gone for good"}, 201)
+	path = "/api/chirps/" + chirp.ID.String()
+	status("DELETE", path, cheems.Token, 204)
+	if items := trash(cheems); len(items) != 0 {
+		t.Fatalf("trash past retention = %+v, want empty", items)
+	}
+	status("POST", path+"/restore", cheems.Token, 404)
+
+	status("DELETE", "/api/users", cheems.Token, 204)
+	res = doJSON(t, srv, "POST", "/api/login", "", map[string]string{"email": "cheems@example.com", "password": "password"})
+	res.Body.Close()
+	if res.StatusCode != 401 {
+		t.Fatalf("login past retention: got %d want 401", res.StatusCode)
+	}
+}
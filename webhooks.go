@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/database"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/pagination"
+	"github.com/Cheemx/chirpy/internal/validation"
+	"github.com/Cheemx/chirpy/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+// maxWebhooksPerUser caps how many endpoints one user, or the admins, can
+// register.
+const maxWebhooksPerUser = 10
+
+// webhookResponse is a registered endpoint. The secret is only sent when
+// the endpoint is created.
+type webhookResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	URL          string     `json:"url"`
+	Events       []string   `json:"events"`
+	Enabled      bool       `json:"enabled"`
+	FailureCount int32      `json:"failure_count"`
+	DisabledAt   *time.Time `json:"disabled_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Secret       string     `json:"secret,omitempty"`
+}
+
+func newWebhookResponse(endpoint database.WebhookEndpoint) webhookResponse {
+	res := webhookResponse{
+		ID:           endpoint.ID,
+		URL:          endpoint.Url,
+		Events:       endpoint.Events,
+		Enabled:      endpoint.Enabled,
+		FailureCount: endpoint.FailureCount,
+		CreatedAt:    endpoint.CreatedAt,
+		UpdatedAt:    endpoint.UpdatedAt,
+	}
+	if endpoint.DisabledAt.Valid {
+		res.DisabledAt = &endpoint.DisabledAt.Time
+	}
+	return res
+}
+
+// webhookDeliveryResponse is one entry in an endpoint's delivery log.
+type webhookDeliveryResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	EventID        uuid.UUID  `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int32      `json:"attempts"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	ResponseStatus *int32     `json:"response_status"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func newWebhookDeliveryResponse(d database.WebhookDelivery) webhookDeliveryResponse {
+	res := webhookDeliveryResponse{
+		ID:        d.ID,
+		EventID:   d.EventID,
+		EventType: d.EventType,
+		Status:    d.Status,
+		Attempts:  d.Attempts,
+		LastError: d.LastError.String,
+		CreatedAt: d.CreatedAt,
+	}
+	if d.LastAttemptAt.Valid {
+		res.LastAttemptAt = &d.LastAttemptAt.Time
+	}
+	// only pending deliveries have a next attempt
+	if d.Status == webhooks.StatusPending {
+		res.NextAttemptAt = &d.NextAttemptAt
+	}
+	if d.ResponseStatus.Valid {
+		res.ResponseStatus = &d.ResponseStatus.Int32
+	}
+	return res
+}
+
+func webhookDeliveryCursor(d database.WebhookDelivery) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: d.CreatedAt, ID: d.ID}
+}
+
+// checkWebhook validates the url and events of an endpoint. On updates nil
+// means the field isn't being changed.
+func (cfg *apiConfig) checkWebhook(errs *validation.Errors, url *string, events []string) {
+	if url != nil {
+		before := len(*errs)
+		errs.Require("url", *url)
+		errs.HTTPURL("url", *url)
+		if len(*errs) == before && cfg.webhooks.CheckURL(*url) != nil {
+			*errs = append(*errs, validation.FieldError{Field: "url", Code: validation.CodeInvalid, Message: "url must be on the public internet"})
+		}
+	}
+	if events == nil {
+		return
+	}
+	if len(events) == 0 {
+		*errs = append(*errs, validation.FieldError{Field: "events", Code: validation.CodeRequired, Message: "events is required"})
+	}
+	for _, e := range events {
+		if !slices.Contains(webhooks.Events, e) {
+			*errs = append(*errs, validation.FieldError{
+				Field:   "events",
+				Code:    validation.CodeInvalid,
+				Message: fmt.Sprintf("events must be some of %s, not %q", strings.Join(webhooks.Events, ", "), e),
+			})
+		}
+	}
+}
+
+// webhookOwner works out whose endpoints a request is about. Under /api they
+// are the user's behind the access token; under /admin, with the admin API
+// key, they are the admins', which belong to no user and hear about every
+// user's events.
+func (cfg *apiConfig) webhookOwner(r *http.Request) (uuid.NullUUID, error) {
+	if strings.HasPrefix(r.URL.Path, "/admin/") {
+		return uuid.NullUUID{}, cfg.checkAdminKey(r)
+	}
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}, nil
+}
+
+// listWebhooks returns every endpoint owner registered.
+func (cfg *apiConfig) listWebhooks(ctx context.Context, owner uuid.NullUUID) ([]database.WebhookEndpoint, error) {
+	if !owner.Valid {
+		return cfg.db.ListAdminWebhookEndpoints(ctx)
+	}
+	return cfg.db.ListWebhookEndpoints(ctx, owner.UUID)
+}
+
+// ownWebhook loads the endpoint named in the path, as long as owner owns
+// it. Other owners' endpoints are reported missing rather than forbidden, so
+// their IDs can't be probed.
+func (cfg *apiConfig) ownWebhook(r *http.Request, owner uuid.NullUUID) (database.WebhookEndpoint, error) {
+	id, err := pathUUID(r, "webhookID")
+	if err != nil {
+		return database.WebhookEndpoint{}, err
+	}
+	endpoint, err := cfg.db.GetWebhookEndpoint(r.Context(), id)
+	if err != nil {
+		return database.WebhookEndpoint{}, lookupError(err, "Webhook")
+	}
+	if endpoint.UserID != owner {
+		return database.WebhookEndpoint{}, httpapi.NotFound("Webhook not found")
+	}
+	return endpoint, nil
+}
+
+func (cfg *apiConfig) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	owner, err := cfg.webhookOwner(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	req := struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}{}
+	err = httpapi.DecodeJSON(w, r, &req)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// events are required on create, so a missing list counts as empty
+	if req.Events == nil {
+		req.Events = []string{}
+	}
+	var fieldErrs validation.Errors
+	cfg.checkWebhook(&fieldErrs, &req.URL, req.Events)
+	if len(fieldErrs) > 0 {
+		httpapi.RespondWithError(w, httpapi.ValidationFailed(fieldErrs))
+		return
+	}
+
+	existing, err := cfg.listWebhooks(r.Context(), owner)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	if len(existing) >= maxWebhooksPerUser {
+		httpapi.RespondWithError(w, httpapi.Conflict(fmt.Sprintf("You can register at most %d webhooks", maxWebhooksPerUser)))
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	endpoint, err := cfg.db.CreateWebhookEndpoint(r.Context(), database.CreateWebhookEndpointParams{
+		UserID: owner,
+		Url:    req.URL,
+		Secret: secret,
+		Events: slices.Compact(slices.Sorted(slices.Values(req.Events))),
+	})
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Responding!
+	res := newWebhookResponse(endpoint)
+	res.Secret = endpoint.Secret
+	httpapi.RespondWithJSON(w, 201, res)
+}
+
+func (cfg *apiConfig) handleGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	owner, err := cfg.webhookOwner(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	endpoints, err := cfg.listWebhooks(r.Context(), owner)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	res := make([]webhookResponse, len(endpoints))
+	for i, endpoint := range endpoints {
+		res[i] = newWebhookResponse(endpoint)
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, struct {
+		Webhooks []webhookResponse `json:"webhooks"`
+	}{Webhooks: res})
+}
+
+func (cfg *apiConfig) handleGetWebhook(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	owner, err := cfg.webhookOwner(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	endpoint, err := cfg.ownWebhook(r, owner)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, newWebhookResponse(endpoint))
+}
+
+func (cfg *apiConfig) handleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	owner, err := cfg.webhookOwner(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	endpoint, err := cfg.ownWebhook(r, owner)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// fields left out keep their current value; enabling an endpoint that
+	// was disabled after failing clears its failure count
+	req := struct {
+		URL     *string  `json:"url,omitempty"`
+		Events  []string `json:"events,omitempty"`
+		Enabled *bool    `json:"enabled,omitempty"`
+	}{}
+	err = httpapi.DecodeJSON(w, r, &req)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	var fieldErrs validation.Errors
+	cfg.checkWebhook(&fieldErrs, req.URL, req.Events)
+	if len(fieldErrs) > 0 {
+		httpapi.RespondWithError(w, httpapi.ValidationFailed(fieldErrs))
+		return
+	}
+
+	params := database.UpdateWebhookEndpointParams{
+		Url: nullString(req.URL),
+		ID:  endpoint.ID,
+	}
+	if req.Events != nil {
+		params.Events = slices.Compact(slices.Sorted(slices.Values(req.Events)))
+	}
+	if req.Enabled != nil {
+		params.Enabled = sql.NullBool{Bool: *req.Enabled, Valid: true}
+	}
+	endpoint, err = cfg.db.UpdateWebhookEndpoint(r.Context(), params)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "Webhook"))
+		return
+	}
+	if endpoint.Enabled {
+		// anything it missed while disabled can go out now
+		cfg.webhooks.Wake()
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, newWebhookResponse(endpoint))
+}
+
+func (cfg *apiConfig) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	owner, err := cfg.webhookOwner(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	endpoint, err := cfg.ownWebhook(r, owner)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// its delivery log goes with it
+	err = cfg.db.DeleteWebhookEndpoint(r.Context(), endpoint.ID)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) handleGetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	owner, err := cfg.webhookOwner(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	endpoint, err := cfg.ownWebhook(r, owner)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	// newest first
+	params := database.ListWebhookDeliveriesParams{EndpointID: endpoint.ID, PageSize: page.FetchSize()}
+	params.AfterCreatedAt, params.AfterID = page.After()
+	items, err := cfg.db.ListWebhookDeliveries(r.Context(), params)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	res := struct {
+		Deliveries []webhookDeliveryResponse `json:"deliveries"`
+		NextCursor string                    `json:"next_cursor,omitempty"`
+	}{}
+	items, res.NextCursor = pagination.Trim(page, items, webhookDeliveryCursor)
+	if res.NextCursor != "" {
+		w.Header().Set("Link", pagination.NextLink(r.URL, res.NextCursor))
+	}
+	res.Deliveries = make([]webhookDeliveryResponse, len(items))
+	for i, d := range items {
+		res.Deliveries[i] = newWebhookDeliveryResponse(d)
+	}
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 200, res)
+}
+
+func (cfg *apiConfig) handleRedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	// Authorization checkpoint
+	owner, err := cfg.webhookOwner(r)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	endpoint, err := cfg.ownWebhook(r, owner)
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+
+	deliveryID, err := pathUUID(r, "deliveryID")
+	if err != nil {
+		httpapi.RespondWithError(w, err)
+		return
+	}
+	d, err := cfg.db.GetWebhookDelivery(r.Context(), deliveryID)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "Delivery"))
+		return
+	}
+	if d.EndpointID != endpoint.ID {
+		httpapi.RespondWithError(w, httpapi.NotFound("Delivery not found"))
+		return
+	}
+
+	// queued again with a fresh set of attempts; the dispatcher sends it
+	// as soon as the endpoint is enabled
+	d, err = cfg.db.RedeliverWebhookDelivery(r.Context(), d.ID)
+	if err != nil {
+		httpapi.RespondWithError(w, lookupError(err, "Delivery"))
+		return
+	}
+	cfg.webhooks.Wake()
+
+	// Responding!
+	httpapi.RespondWithJSON(w, 202, newWebhookDeliveryResponse(d))
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/webhooks"
+)
+
+func TestWebhooks(t *testing.T) {
+	// the receiver is on loopback
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "true")
+	srv := newTestServer(t)
+	alice := createTestUser(t, srv, "alice@example.com")
+	bob := createTestUser(t, srv, "bob@example.com")
+
+	// the receiver fails the first request and accepts the rest
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 10)
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header, body: body}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(500)
+		}
+	}))
+	defer receiver.Close()
+	waitRequest := func() received {
+		t.Helper()
+		select {
+		case req := <-requests:
+			return req
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a webhook request")
+			return received{}
+		}
+	}
+	waitDelivery := func(path, status string) webhookDeliveryResponse {
+		t.Helper()
+		for range 100 {
+			var page struct {
+				Deliveries []webhookDeliveryResponse `json:"deliveries"`
+			}
+			decodeBody(t, doJSON(t, srv, "GET", path, alice.Token, nil), &page)
+			if len(page.Deliveries) == 1 && page.Deliveries[0].Status == status && page.Deliveries[0].ResponseStatus != nil {
+				return page.Deliveries[0]
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("no %s delivery at %s", status, path)
+		return webhookDeliveryResponse{}
+	}
+
+	for _, body := range []map[string]any{
+		{"url": "ftp://example.com", "events": []string{"chirp.created"}},
+		{"url": receiver.URL},
+		{"url": receiver.URL, "events": []string{"chirp.liked"}},
+	} {
+		if res := doJSON(t, srv, "POST", "/api/webhooks", alice.Token, body); res.StatusCode != 400 {
+			t.Fatalf("create %v: got %d want 400", body, res.StatusCode)
+		}
+	}
+
+	var hook webhookResponse
+	res := doJSON(t, srv, "POST", "/api/webhooks", alice.Token, map[string]any{"url": receiver.URL, "events": []string{"chirp.created"}})
+	if res.StatusCode != 201 {
+		t.Fatalf("create webhook: got %d want 201", res.StatusCode)
+	}
+	decodeBody(t, res, &hook)
+	if !strings.HasPrefix(hook.Secret, "whsec_") || !hook.Enabled {
+		t.Fatalf("created webhook = %+v, want an enabled one with a secret", hook)
+	}
+	hookPath := "/api/webhooks/" + hook.ID.String()
+
+	// the secret is never shown again, and only the owner sees the webhook
+	var got webhookResponse
+	decodeBody(t, doJSON(t, srv, "GET", hookPath, alice.Token, nil), &got)
+	if got.Secret != "" || got.URL != receiver.URL {
+		t.Fatalf("GET webhook = %+v", got)
+	}
+	if res := doJSON(t, srv, "GET", hookPath, bob.Token, nil); res.StatusCode != 404 {
+		t.Fatalf("GET someone else's webhook: got %d want 404", res.StatusCode)
+	}
+
+	// alice only hears about her own chirps, not bob's posted first
+	postChirp(t, srv, bob.Token, map[string]string{"body": "not for alice"}, 201)
+	chirp := postChirp(t, srv, alice.Token, map[string]string{"body": "hooked"}, 201)
+
+	req := waitRequest()
+	if req.header.Get(webhooks.HeaderEvent) != "chirp.created" {
+		t.Fatalf("%s = %q", webhooks.HeaderEvent, req.header.Get(webhooks.HeaderEvent))
+	}
+	if err := webhooks.Verify(hook.Secret, req.header.Get(webhooks.HeaderSignature), req.body, time.Minute, time.Now()); err != nil {
+		t.Fatalf("Verify error: %v", err)
+	}
+	var payload webhooks.Payload
+	if err := json.Unmarshal(req.body, &payload); err != nil || !strings.Contains(string(payload.Data), chirp.ID.String()) {
+		t.Fatalf("payload = %s, %v; want the chirp", req.body, err)
+	}
+
+	// the failure is in the log, and redelivering sends it again at once
+	delivery := waitDelivery(hookPath+"/deliveries", "pending")
+	if *delivery.ResponseStatus != 500 || delivery.Attempts != 1 || delivery.LastError == "" {
+		t.Fatalf("failed delivery = %+v", delivery)
+	}
+	if delivery.ID.String() != req.header.Get(webhooks.HeaderDelivery) {
+		t.Fatalf("delivery ID = %s, header %s", delivery.ID, req.header.Get(webhooks.HeaderDelivery))
+	}
+	res = doJSON(t, srv, "POST", hookPath+"/deliveries/"+delivery.ID.String()+"/redeliver", alice.Token, nil)
+	if res.StatusCode != 202 {
+		t.Fatalf("redeliver: got %d want 202", res.StatusCode)
+	}
+	res.Body.Close()
+	if again := waitRequest(); !bytes.Equal(again.body, req.body) {
+		t.Fatalf("redelivered body = %s, want %s", again.body, req.body)
+	}
+	delivery = waitDelivery(hookPath+"/deliveries", "succeeded")
+	if *delivery.ResponseStatus != 200 {
+		t.Fatalf("redelivery = %+v, want a 200", delivery)
+	}
+	if len(requests) != 0 {
+		t.Fatalf("receiver got %d more requests, want none", len(requests))
+	}
+
+	decodeBody(t, doJSON(t, srv, "PUT", hookPath, alice.Token, map[string]any{"enabled": false}), &got)
+	if got.Enabled || got.DisabledAt == nil {
+		t.Fatalf("disabled webhook = %+v", got)
+	}
+	if res := doJSON(t, srv, "DELETE", hookPath, bob.Token, nil); res.StatusCode != 404 {
+		t.Fatalf("DELETE someone else's webhook: got %d want 404", res.StatusCode)
+	}
+	if res := doJSON(t, srv, "DELETE", hookPath, alice.Token, nil); res.StatusCode != 204 {
+		t.Fatalf("DELETE webhook: got %d want 204", res.StatusCode)
+	}
+	if res := doJSON(t, srv, "GET", hookPath, alice.Token, nil); res.StatusCode != 404 {
+		t.Fatalf("GET deleted webhook: got %d want 404", res.StatusCode)
+	}
+}
+
+// Endpoints the admins register hear about every user, and Chirpy Red
+// changes reach the upgraded user's own endpoints too.
+func TestAdminWebhooks(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "true")
+	srv := newTestServer(t)
+	alice := createTestUser(t, srv, "alice@example.com")
+	bob := createTestUser(t, srv, "bob@example.com")
+
+	// each endpoint reports the events it got on its own channel
+	listen := func() (string, chan string) {
+		events := make(chan string, 10)
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			events <- r.Header.Get(webhooks.HeaderEvent)
+		}))
+		t.Cleanup(receiver.Close)
+		return receiver.URL, events
+	}
+	adminURL, adminEvents := listen()
+	bobURL, bobEvents := listen()
+
+	events := []string{"chirp.created", "user.upgraded"}
+	if res := doJSON(t, srv, "POST", "/admin/webhooks", alice.Token, map[string]any{"url": adminURL, "events": events}); res.StatusCode != 401 {
+		t.Fatalf("create admin webhook with an access token: got %d want 401", res.StatusCode)
+	}
+	var admin webhookResponse
+	res := doAdmin(t, srv, "POST", "/admin/webhooks", map[string]any{"url": adminURL, "events": events})
+	if res.StatusCode != 201 {
+		t.Fatalf("create admin webhook: got %d want 201", res.StatusCode)
+	}
+	decodeBody(t, res, &admin)
+	if res := doJSON(t, srv, "POST", "/api/webhooks", bob.Token, map[string]any{"url": bobURL, "events": events}); res.StatusCode != 201 {
+		t.Fatalf("create bob's webhook: got %d want 201", res.StatusCode)
+	}
+
+	// the admins' endpoint isn't any user's
+	if res := doJSON(t, srv, "GET", "/api/webhooks/"+admin.ID.String(), alice.Token, nil); res.StatusCode != 404 {
+		t.Fatalf("GET admin webhook as a user: got %d want 404", res.StatusCode)
+	}
+	var list struct {
+		Webhooks []webhookResponse `json:"webhooks"`
+	}
+	decodeBody(t, doAdmin(t, srv, "GET", "/admin/webhooks", nil), &list)
+	if len(list.Webhooks) != 1 || list.Webhooks[0].ID != admin.ID {
+		t.Fatalf("admin webhooks = %+v, want only the admins' one", list.Webhooks)
+	}
+
+	postChirp(t, srv, alice.Token, map[string]string{"body": "hello"}, 201)
+	res = doPolka(t, srv, "polka", map[string]any{
+		"id":    "evt_1",
+		"event": "user.upgraded",
+		"data":  map[string]string{"user_id": alice.ID.String()},
+	})
+	if res.StatusCode != 204 {
+		t.Fatalf("upgrade alice: got %d want 204", res.StatusCode)
+	}
+	res = doPolka(t, srv, "polka", map[string]any{
+		"id":    "evt_2",
+		"event": "user.upgraded",
+		"data":  map[string]string{"user_id": bob.ID.String()},
+	})
+	if res.StatusCode != 204 {
+		t.Fatalf("upgrade bob: got %d want 204", res.StatusCode)
+	}
+
+	got := map[string]int{}
+	for range 3 {
+		select {
+		case event := <-adminEvents:
+			got[event]++
+		case <-time.After(5 * time.Second):
+			t.Fatalf("admin webhook got %v, want 3 events", got)
+		}
+	}
+	if got["chirp.created"] != 1 || got["user.upgraded"] != 2 {
+		t.Fatalf("admin webhook got %v, want alice's chirp and both upgrades", got)
+	}
+	// bob hears about his own upgrade only
+	select {
+	case event := <-bobEvents:
+		if event != "user.upgraded" {
+			t.Fatalf("bob's webhook got %s, want user.upgraded", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("bob's webhook never heard about his upgrade")
+	}
+	select {
+	case event := <-bobEvents:
+		t.Fatalf("bob's webhook also got %s", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWebhookAddresses(t *testing.T) {
+	srv := newTestServer(t)
+	alice := createTestUser(t, srv, "alice@example.com")
+
+	create := func(url string, want int) {
+		t.Helper()
+		res := doJSON(t, srv, "POST", "/api/webhooks", alice.Token, map[string]any{"url": url, "events": []string{"chirp.created"}})
+		res.Body.Close()
+		if res.StatusCode != want {
+			t.Fatalf("create webhook for %s: got %d want %d", url, res.StatusCode, want)
+		}
+	}
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.0.0.5/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[fd00::1]/hook",
+	} {
+		create(url, 400)
+	}
+	create("https://example.com/hook", 201)
+
+	// nor can an endpoint be moved there
+	var hooks struct {
+		Webhooks []webhookResponse `json:"webhooks"`
+	}
+	decodeBody(t, doJSON(t, srv, "GET", "/api/webhooks", alice.Token, nil), &hooks)
+	res := doJSON(t, srv, "PUT", "/api/webhooks/"+hooks.Webhooks[0].ID.String(), alice.Token, map[string]any{"url": "http://192.168.0.1/"})
+	res.Body.Close()
+	if res.StatusCode != 400 {
+		t.Fatalf("move webhook to a private address: got %d want 400", res.StatusCode)
+	}
+}
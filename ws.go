@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/auth"
+	"github.com/Cheemx/chirpy/internal/broker"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/Cheemx/chirpy/internal/notifications"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsAuthTimeout is how long a socket opened without an Authorization
+	// header has to send an auth message.
+	wsAuthTimeout = 10 * time.Second
+	// wsPingInterval is how often the server pings, and wsPongWait how long
+	// it waits to hear anything back before giving up on the client.
+	wsPingInterval = 30 * time.Second
+	wsPongWait     = 60 * time.Second
+	wsWriteWait    = 10 * time.Second
+	// wsReauthGrace is how long a client whose token expired has to send a
+	// new one before the socket is closed.
+	wsReauthGrace      = 30 * time.Second
+	wsMaxMessageBytes  = 4096
+	wsMaxSubscriptions = 20
+	// wsEventBuffer is how many events wait for the socket across all of a
+	// client's subscriptions.
+	wsEventBuffer = 64
+)
+
+// Channels a client can subscribe to.
+const (
+	wsChannelTimeline      = "timeline"
+	wsChannelNotifications = "notifications"
+	wsChannelThreadPrefix  = "thread:"
+)
+
+// errWSClosed ends a session after the server sent a close frame.
+var errWSClosed = errors.New("websocket closed")
+
+var wsUpgrader = websocket.Upgrader{
+	// the socket is authenticated with a bearer token, never with cookies,
+	// so a page on another origin gains nothing by opening one
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsClientMessage is anything a client sends.
+type wsClientMessage struct {
+	Type    string `json:"type"`
+	Token   string `json:"token,omitempty"`
+	Channel string `json:"channel,omitempty"`
+}
+
+// wsServerMessage is anything the server sends. Errors carry the same codes
+// as REST error responses.
+type wsServerMessage struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel,omitempty"`
+	Event   string `json:"event,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// wsNotification is the data of a notification event.
+type wsNotification struct {
+	NotificationID uuid.UUID          `json:"notification_id"`
+	Type           notifications.Type `json:"type"`
+	ActorID        uuid.UUID          `json:"actor_id"`
+	ChirpID        uuid.NullUUID      `json:"chirp_id"`
+}
+
+// wsSubscription is one channel a session is subscribed to.
+type wsSubscription struct {
+	channel string
+	close   func()
+}
+
+// wsEvent is an event for sub, on its way from a forwarder to the socket.
+// closed means the broker dropped the subscription.
+type wsEvent struct {
+	sub    *wsSubscription
+	event  string
+	data   any
+	closed bool
+}
+
+// wsSession is one client's socket. Only the goroutine running run writes
+// to the socket, and only the one running read reads from it.
+type wsSession struct {
+	cfg    *apiConfig
+	conn   *websocket.Conn
+	ctx    context.Context
+	userID uuid.UUID
+	// expired is set between the token running out and the client sending
+	// a new one. Events wait in the meantime.
+	expired bool
+	// deadline fires when the client has to authenticate: first within
+	// wsAuthTimeout, then when the token expires, then wsReauthGrace
+	// after that.
+	deadline *time.Timer
+	subs     map[string]*wsSubscription
+	events   chan wsEvent
+}
+
+func (cfg *apiConfig) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	// clients that can set headers may authenticate up front, browsers send
+	// an auth message once connected
+	var userID uuid.UUID
+	var expiresAt time.Time
+	if r.Header.Get("Authorization") != "" {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			httpapi.RespondWithError(w, httpapi.Unauthorized(err))
+			return
+		}
+		user, expires, err := cfg.tokenUser(r.Context(), token)
+		if err != nil {
+			httpapi.RespondWithError(w, err)
+			return
+		}
+		userID, expiresAt = user.ID, expires
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade already responded
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	s := &wsSession{
+		cfg:      cfg,
+		conn:     conn,
+		ctx:      ctx,
+		deadline: time.NewTimer(wsAuthTimeout),
+		subs:     map[string]*wsSubscription{},
+		events:   make(chan wsEvent, wsEventBuffer),
+	}
+	defer s.deadline.Stop()
+	defer s.unsubscribeAll()
+
+	if userID != uuid.Nil {
+		if err := s.authenticated(userID, expiresAt); err != nil {
+			return
+		}
+	}
+	s.run()
+}
+
+// run handles the client's messages and delivers events until either side
+// closes the socket.
+func (s *wsSession) run() {
+	s.conn.SetReadLimit(wsMaxMessageBytes)
+	s.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	incoming := make(chan []byte)
+	go s.read(incoming)
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		// events are only delivered while the client holds a valid token
+		var events <-chan wsEvent
+		if s.userID != uuid.Nil && !s.expired {
+			events = s.events
+		}
+
+		var err error
+		select {
+		case data, ok := <-incoming:
+			if !ok {
+				return
+			}
+			err = s.handle(data)
+		case e := <-events:
+			err = s.deliver(e)
+		case <-ping.C:
+			err = s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+		case <-s.deadline.C:
+			err = s.deadlinePassed()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// read passes the client's messages to run. It stops when the socket fails
+// or is closed, or nothing arrives within wsPongWait.
+func (s *wsSession) read(incoming chan<- []byte) {
+	defer close(incoming)
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		s.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		select {
+		case incoming <- data:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// handle answers one client message. It only returns an error if the
+// session has to end.
+func (s *wsSession) handle(data []byte) error {
+	var msg wsClientMessage
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&msg); err != nil {
+		return s.sendError("", httpapi.BadRequest(httpapi.CodeInvalidJSON, "Message is not valid JSON"))
+	}
+
+	switch msg.Type {
+	case "auth":
+		user, expiresAt, err := s.cfg.tokenUser(s.ctx, msg.Token)
+		if err != nil {
+			return s.sendError("", err)
+		}
+		// subscriptions were made for one user, they can't change hands
+		if s.userID != uuid.Nil && user.ID != s.userID {
+			return s.sendError("", httpapi.Forbidden("Token is for a different user"))
+		}
+		return s.authenticated(user.ID, expiresAt)
+	case "ping":
+		return s.send(wsServerMessage{Type: "pong"})
+	case "subscribe", "unsubscribe":
+		if s.userID == uuid.Nil || s.expired {
+			return s.sendError(msg.Channel, httpapi.Unauthorized(errors.New("not authenticated")))
+		}
+		if msg.Type == "unsubscribe" {
+			if sub, ok := s.subs[msg.Channel]; ok {
+				sub.close()
+				delete(s.subs, msg.Channel)
+			}
+			return s.send(wsServerMessage{Type: "unsubscribed", Channel: msg.Channel})
+		}
+		if err := s.subscribe(msg.Channel); err != nil {
+			return s.sendError(msg.Channel, err)
+		}
+		return s.send(wsServerMessage{Type: "subscribed", Channel: msg.Channel})
+	default:
+		return s.sendError("", httpapi.BadRequest(httpapi.CodeInvalidParameter, "type must be auth, ping, subscribe or unsubscribe"))
+	}
+}
+
+// authenticated starts or renews the session for userID, until expiresAt.
+func (s *wsSession) authenticated(userID uuid.UUID, expiresAt time.Time) error {
+	s.userID = userID
+	s.expired = false
+	// a token without an expiry never needs renewing
+	if expiresAt.IsZero() {
+		s.deadline.Stop()
+	} else {
+		s.deadline.Reset(time.Until(expiresAt))
+	}
+
+	data := struct {
+		UserID    uuid.UUID  `json:"user_id"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}{UserID: userID}
+	if !expiresAt.IsZero() {
+		data.ExpiresAt = &expiresAt
+	}
+	return s.send(wsServerMessage{Type: "authenticated", Data: data})
+}
+
+// deadlinePassed runs when s.deadline fires: the client never
+// authenticated, its token just expired, or it didn't renew it in time.
+func (s *wsSession) deadlinePassed() error {
+	switch {
+	case s.userID == uuid.Nil:
+		return s.closeWith(websocket.ClosePolicyViolation, "authentication timeout")
+	case !s.expired:
+		s.expired = true
+		s.deadline.Reset(wsReauthGrace)
+		return s.send(wsServerMessage{Type: "reauth_required"})
+	default:
+		return s.closeWith(websocket.ClosePolicyViolation, "token expired")
+	}
+}
+
+// subscribe starts delivering channel's events to the client.
+func (s *wsSession) subscribe(channel string) error {
+	if _, ok := s.subs[channel]; ok {
+		return nil
+	}
+	if len(s.subs) >= wsMaxSubscriptions {
+		return httpapi.BadRequest(httpapi.CodeInvalidParameter, "Too many subscriptions")
+	}
+
+	sub := &wsSubscription{channel: channel}
+	switch {
+	case channel == wsChannelTimeline:
+		// the same chirps as GET /api/timeline. Who the user follows is
+		// read once, subscribing again picks up new follows.
+		followees, err := s.cfg.db.ListFolloweeIDs(s.ctx, s.userID)
+		if err != nil {
+			return err
+		}
+		authors := map[uuid.UUID]bool{s.userID: true}
+		for _, id := range followees {
+			authors[id] = true
+		}
+		s.forwardChirps(sub, func(e chirpEvent) bool { return authors[e.AuthorID] })
+	case channel == wsChannelNotifications:
+		events := s.cfg.notifier.Subscribe(s.userID)
+		sub.close = events.Close
+		go forward(s.ctx, s.events, sub, events, func(e notifications.Event) (string, any) {
+			data := wsNotification{NotificationID: e.NotificationID, Type: e.Type, ActorID: e.Actor}
+			if e.ChirpID != uuid.Nil {
+				data.ChirpID = uuid.NullUUID{UUID: e.ChirpID, Valid: true}
+			}
+			return "notification.created", data
+		})
+	case strings.HasPrefix(channel, wsChannelThreadPrefix):
+		// any chirp in a thread subscribes to the whole thread
+		chirpID, err := uuid.Parse(strings.TrimPrefix(channel, wsChannelThreadPrefix))
+		if err != nil {
+			return httpapi.BadRequest(httpapi.CodeInvalidParameter, "channel is not a valid thread")
+		}
+		chirp, err := s.cfg.db.GetChirpByID(s.ctx, chirpID)
+		if err != nil {
+			return lookupError(err, "Chirp")
+		}
+		rootID := chirp.ID
+		if chirp.RootID.Valid {
+			rootID = chirp.RootID.UUID
+		}
+		s.forwardChirps(sub, func(e chirpEvent) bool { return e.RootID == rootID })
+	default:
+		return httpapi.BadRequest(httpapi.CodeInvalidParameter, "channel must be timeline, notifications or thread:<chirp ID>")
+	}
+	s.subs[channel] = sub
+	return nil
+}
+
+// forwardChirps delivers the chirp events filter accepts to sub.
+func (s *wsSession) forwardChirps(sub *wsSubscription, filter func(chirpEvent) bool) {
+	events, _, _ := s.cfg.chirpEvents.Subscribe(0, filter)
+	sub.close = events.Close
+	go forward(s.ctx, s.events, sub, events, func(e chirpEvent) (string, any) {
+		return e.Type, e.Data
+	})
+}
+
+// forward relays the messages of events to out as sub's events until the
+// subscription is closed, then reports that it was.
+func forward[T any](ctx context.Context, out chan<- wsEvent, sub *wsSubscription, events *broker.Subscription[T], convert func(T) (string, any)) {
+	for msg := range events.Messages() {
+		event, data := convert(msg.Value)
+		select {
+		case out <- wsEvent{sub: sub, event: event, data: data}:
+		case <-ctx.Done():
+			return
+		}
+	}
+	select {
+	case out <- wsEvent{sub: sub, closed: true}:
+	case <-ctx.Done():
+	}
+}
+
+// deliver sends e to the client, unless it unsubscribed in the meantime.
+func (s *wsSession) deliver(e wsEvent) error {
+	if s.subs[e.sub.channel] != e.sub {
+		return nil
+	}
+	if e.closed {
+		// the client fell behind and missed events, it has to subscribe
+		// again and reload what it shows over REST
+		delete(s.subs, e.sub.channel)
+		return s.send(wsServerMessage{Type: "unsubscribed", Channel: e.sub.channel, Reason: "lagging"})
+	}
+	return s.send(wsServerMessage{Type: "event", Channel: e.sub.channel, Event: e.event, Data: e.data})
+}
+
+func (s *wsSession) unsubscribeAll() {
+	for _, sub := range s.subs {
+		sub.close()
+	}
+}
+
+func (s *wsSession) send(msg wsServerMessage) error {
+	s.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return s.conn.WriteJSON(msg)
+}
+
+// sendError reports err to the client, the same way httpapi.RespondWithError
+// would.
+func (s *wsSession) sendError(channel string, err error) error {
+	var apiErr *httpapi.Error
+	if !errors.As(err, &apiErr) {
+		apiErr = httpapi.InternalError(err)
+	}
+	log.Printf("websocket %s: %v", apiErr.Code, apiErr)
+	return s.send(wsServerMessage{Type: "error", Channel: channel, Code: apiErr.Code, Error: apiErr.Message})
+}
+
+func (s *wsSession) closeWith(code int, reason string) error {
+	msg := websocket.FormatCloseMessage(code, reason)
+	s.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteWait))
+	return errWSClosed
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cheemx/chirpy/internal/auth"
+	"github.com/Cheemx/chirpy/internal/httpapi"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocket(t *testing.T) {
+	srv := newTestServer(t)
+	alice := createTestUser(t, srv, "alice@example.com")
+	bob := createTestUser(t, srv, "bob@example.com")
+	carol := createTestUser(t, srv, "carol@example.com")
+	doJSON(t, srv, "POST", "/api/users/"+bob.ID.String()+"/follow", alice.Token, nil)
+
+	type message struct {
+		Type    string          `json:"type"`
+		Channel string          `json:"channel"`
+		Event   string          `json:"event"`
+		Code    string          `json:"code"`
+		Data    json.RawMessage `json:"data"`
+	}
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/ws"
+	dial := func(token string) *websocket.Conn {
+		t.Helper()
+		header := http.Header{}
+		if token != "" {
+			header.Set("Authorization", "Bearer "+token)
+		}
+		conn, _, err := websocket.DefaultDialer.Dial(url, header)
+		if err != nil {
+			t.Fatalf("Dial error: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+	send := func(conn *websocket.Conn, v map[string]string) {
+		t.Helper()
+		if err := conn.WriteJSON(v); err != nil {
+			t.Fatalf("WriteJSON error: %v", err)
+		}
+	}
+	next := func(conn *websocket.Conn) message {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var msg message
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("ReadJSON error: %v", err)
+		}
+		return msg
+	}
+	expect := func(conn *websocket.Conn, msgType, channel string) message {
+		t.Helper()
+		msg := next(conn)
+		if msg.Type != msgType || msg.Channel != channel {
+			t.Fatalf("got %+v, want %s on %q", msg, msgType, channel)
+		}
+		return msg
+	}
+	post := func(user testUser, body string, inReplyTo uuid.UUID) chirpResponse {
+		t.Helper()
+		req := map[string]string{"body": body}
+		if inReplyTo != uuid.Nil {
+			req["in_reply_to"] = inReplyTo.String()
+		}
+		return postChirp(t, srv, user.Token, req, 201)
+	}
+
+	// browsers authenticate with a message after connecting
+	conn := dial("")
+	send(conn, map[string]string{"type": "subscribe", "channel": "timeline"})
+	if msg := expect(conn, "error", "timeline"); msg.Code != httpapi.CodeUnauthorized {
+		t.Fatalf("subscribe before auth = %+v, want unauthorized", msg)
+	}
+	send(conn, map[string]string{"type": "auth", "token": alice.Token})
+	expect(conn, "authenticated", "")
+	send(conn, map[string]string{"type": "subscribe", "channel": "timeline"})
+	expect(conn, "subscribed", "timeline")
+	send(conn, map[string]string{"type": "subscribe", "channel": "notifications"})
+	expect(conn, "subscribed", "notifications")
+
+	root := post(alice, "root", uuid.Nil)
+	if msg := expect(conn, "event", "timeline"); msg.Event != eventChirpCreated || !strings.Contains(string(msg.Data), root.ID.String()) {
+		t.Fatalf("timeline event = %+v, want the root chirp", msg)
+	}
+	send(conn, map[string]string{"type": "subscribe", "channel": "thread:" + root.ID.String()})
+	expect(conn, "subscribed", "thread:"+root.ID.String())
+
+	// carol isn't followed, bob's reply shows up on all three channels
+	post(carol, "not on the timeline", uuid.Nil)
+	reply := post(bob, "a reply", root.ID)
+	got := map[string]string{}
+	for range 3 {
+		msg := next(conn)
+		got[msg.Channel] = msg.Event
+		// the notification is about alice's chirp and who replied to it
+		mentioned := reply.ID
+		if msg.Channel == "notifications" {
+			mentioned = bob.ID
+		}
+		if !strings.Contains(string(msg.Data), mentioned.String()) {
+			t.Fatalf("event %+v doesn't mention %s", msg, mentioned)
+		}
+	}
+	want := map[string]string{
+		"timeline":                   eventChirpCreated,
+		"thread:" + root.ID.String(): eventChirpCreated,
+		"notifications":              "notification.created",
+	}
+	if !maps.Equal(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+
+	send(conn, map[string]string{"type": "ping"})
+	expect(conn, "pong", "")
+	missing := "thread:" + uuid.NewString()
+	send(conn, map[string]string{"type": "subscribe", "channel": missing})
+	if msg := expect(conn, "error", missing); msg.Code != httpapi.CodeNotFound {
+		t.Fatalf("unknown thread = %+v, want not found", msg)
+	}
+
+	// a socket whose token runs out has to authenticate again as the same user
+	token, err := auth.MakeJWT(alice.ID, "Cheems", 2*time.Second)
+	if err != nil {
+		t.Fatalf("MakeJWT error: %v", err)
+	}
+	conn = dial(token)
+	expect(conn, "authenticated", "")
+	expect(conn, "reauth_required", "")
+	send(conn, map[string]string{"type": "subscribe", "channel": "timeline"})
+	if msg := expect(conn, "error", "timeline"); msg.Code != httpapi.CodeUnauthorized {
+		t.Fatalf("subscribe after expiry = %+v, want unauthorized", msg)
+	}
+	send(conn, map[string]string{"type": "auth", "token": bob.Token})
+	if msg := expect(conn, "error", ""); msg.Code != httpapi.CodeForbidden {
+		t.Fatalf("auth as another user = %+v, want forbidden", msg)
+	}
+	send(conn, map[string]string{"type": "auth", "token": alice.Token})
+	expect(conn, "authenticated", "")
+	send(conn, map[string]string{"type": "subscribe", "channel": "timeline"})
+	expect(conn, "subscribed", "timeline")
+
+	if _, res, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer nope"}}); err == nil || res.StatusCode != 401 {
+		t.Fatalf("bad token: got %v, want 401", err)
+	}
+
+	// a deleted account's token can neither connect nor authenticate again
+	conn = dial(carol.Token)
+	expect(conn, "authenticated", "")
+	doJSON(t, srv, "DELETE", "/api/users", carol.Token, nil).Body.Close()
+	send(conn, map[string]string{"type": "auth", "token": carol.Token})
+	if msg := expect(conn, "error", ""); msg.Code != httpapi.CodeUnauthorized {
+		t.Fatalf("auth after deleting the account = %+v, want unauthorized", msg)
+	}
+	if _, res, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer " + carol.Token}}); err == nil || res.StatusCode != 401 {
+		t.Fatalf("deleted account's token: got %v, want 401", err)
+	}
+}